@@ -17,16 +17,26 @@
 package chain
 
 import (
+	"path/filepath"
+
+	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/types"
 )
 
+const (
+	DefaultDBType = db.GoLevelDBBackend
+)
+
 type BaseConfig struct {
 	Address      types.BtpAddress `json:"address"`
 	Endpoint     string           `json:"endpoint"`
+	Endpoints    []string         `json:"endpoints,omitempty"`
 	KeyStore     string           `json:"key_store"`
 	Type         string           `json:"type"`
 	KeyStorePass string           `json:"key_password,omitempty"`
 	KeySecret    string           `json:"key_secret,omitempty"`
+	DBType       string           `json:"db_type,omitempty"`
+	DBDir        string           `json:"db_dir,omitempty"`
 
 	Options map[string]interface{} `json:"options,omitempty"`
 }
@@ -38,3 +48,36 @@ func (b BaseConfig) GetAddress() types.BtpAddress {
 func (b BaseConfig) GetType() string {
 	return b.Type
 }
+
+// GetEndpoints returns Endpoint followed by Endpoints for failover.
+func (b BaseConfig) GetEndpoints() []string {
+	eps := make([]string, 0, len(b.Endpoints)+1)
+	if b.Endpoint != "" {
+		eps = append(eps, b.Endpoint)
+	}
+	return append(eps, b.Endpoints...)
+}
+
+// DatabaseDir returns the directory of the database of the receiver, named
+// by the network address. DBDir is relative to baseDir if it's not absolute.
+func (b BaseConfig) DatabaseDir(baseDir string) string {
+	dir := baseDir
+	if b.DBDir != "" {
+		if filepath.IsAbs(b.DBDir) {
+			dir = b.DBDir
+		} else {
+			dir = filepath.Join(baseDir, b.DBDir)
+		}
+	}
+	return filepath.Join(dir, b.Address.NetworkAddress())
+}
+
+// OpenDatabase opens the database of the receiver in DatabaseDir.
+func (b BaseConfig) OpenDatabase(baseDir string) (db.Database, error) {
+	dbType := b.DBType
+	if dbType == "" {
+		dbType = string(DefaultDBType)
+	}
+	dir, name := filepath.Split(b.DatabaseDir(baseDir))
+	return db.Open(dir, dbType, name)
+}
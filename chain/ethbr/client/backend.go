@@ -0,0 +1,119 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// backend implements bind.ContractBackend with failover between endpoints.
+type backend struct {
+	c *Client
+}
+
+func (b *backend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = b.c.do(func(cn *conn) error {
+		code, err = cn.eth.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+	return
+}
+
+func (b *backend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) (ret []byte, err error) {
+	err = b.c.do(func(cn *conn) error {
+		ret, err = cn.eth.CallContract(ctx, call, blockNumber)
+		return err
+	})
+	return
+}
+
+func (b *backend) HeaderByNumber(ctx context.Context, number *big.Int) (bh *types.Header, err error) {
+	err = b.c.do(func(cn *conn) error {
+		bh, err = cn.eth.HeaderByNumber(ctx, number)
+		return err
+	})
+	return
+}
+
+func (b *backend) PendingCodeAt(ctx context.Context, account common.Address) (code []byte, err error) {
+	err = b.c.do(func(cn *conn) error {
+		code, err = cn.eth.PendingCodeAt(ctx, account)
+		return err
+	})
+	return
+}
+
+func (b *backend) PendingNonceAt(ctx context.Context, account common.Address) (nonce uint64, err error) {
+	err = b.c.do(func(cn *conn) error {
+		nonce, err = cn.eth.PendingNonceAt(ctx, account)
+		return err
+	})
+	return
+}
+
+func (b *backend) SuggestGasPrice(ctx context.Context) (price *big.Int, err error) {
+	err = b.c.do(func(cn *conn) error {
+		price, err = cn.eth.SuggestGasPrice(ctx)
+		return err
+	})
+	return
+}
+
+func (b *backend) SuggestGasTipCap(ctx context.Context) (tip *big.Int, err error) {
+	err = b.c.do(func(cn *conn) error {
+		tip, err = cn.eth.SuggestGasTipCap(ctx)
+		return err
+	})
+	return
+}
+
+func (b *backend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
+	err = b.c.do(func(cn *conn) error {
+		gas, err = cn.eth.EstimateGas(ctx, call)
+		return err
+	})
+	return
+}
+
+func (b *backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return b.c.do(func(cn *conn) error {
+		return cn.eth.SendTransaction(ctx, tx)
+	})
+}
+
+func (b *backend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = b.c.do(func(cn *conn) error {
+		logs, err = cn.eth.FilterLogs(ctx, query)
+		return err
+	})
+	return
+}
+
+// SubscribeFilterLogs subscribes with the best endpoint, it doesn't fail over
+// after subscribed.
+func (b *backend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (s ethereum.Subscription, err error) {
+	err = b.c.do(func(cn *conn) error {
+		s, err = cn.eth.SubscribeFilterLogs(ctx, query, ch)
+		return err
+	})
+	return
+}
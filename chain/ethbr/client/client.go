@@ -19,8 +19,11 @@ package client
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/json"
 	"math/big"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -28,9 +31,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/icon-project/btp2/common/cache"
+	"github.com/icon-project/btp2/common/capture"
+	"github.com/icon-project/btp2/common/endpoint"
+	"github.com/icon-project/btp2/common/jsonrpc"
 	"github.com/icon-project/btp2/common/log"
 )
 
@@ -41,6 +50,9 @@ const (
 	ChainID                                    = 56
 	DefaultGasLimit                            = 8000000
 	DefaultGasPrice                            = 5000000000
+	// DefaultFinalityDepth is the number of blocks on the last block,
+	// below which blocks are regarded as finalized.
+	DefaultFinalityDepth = 15
 )
 
 var (
@@ -50,12 +62,96 @@ var (
 )
 
 type Client struct {
-	uri       string
-	log       log.Logger
-	ethClient *ethclient.Client
-	rpcClient *rpc.Client
-	chainID   *big.Int
-	stop      <-chan bool
+	log     log.Logger
+	pool    *endpoint.Pool
+	mtx     sync.Mutex
+	conns   map[string]*conn
+	opt     jsonrpc.Options
+	rec     *capture.Recorder
+	chainID *big.Int
+	stop    <-chan bool
+	cache   *cache.Cache
+	// finalityDepth and the last block bound headers to be cached
+	finalityDepth int64
+	last          int64
+}
+
+// kinds of cached data
+const (
+	cacheKindHeader = 'h'
+	cacheKindLogs   = 'l'
+)
+
+type conn struct {
+	rpc *rpc.Client
+	eth *ethclient.Client
+}
+
+// callbackError is the error returned by the callback of monitoring,
+// which stops monitoring instead of failover.
+type callbackError struct {
+	error
+}
+
+func isEndpointFailure(err error) bool {
+	if err == ethereum.NotFound {
+		return false
+	}
+	if _, ok := err.(callbackError); ok {
+		return false
+	}
+	_, ok := err.(rpc.Error)
+	return !ok
+}
+
+// connOf returns the connection to the endpoint, it dials on the first use.
+func (c *Client) connOf(url string) (*conn, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if cn, ok := c.conns[url]; ok {
+		return cn, nil
+	}
+	var rpcClient *rpc.Client
+	var err error
+	if strings.HasPrefix(url, "http") {
+		var tr *jsonrpc.Transport
+		if tr, err = jsonrpc.NewTransport(nil, &c.opt); err != nil {
+			return nil, err
+		}
+		rpcClient, err = rpc.DialHTTPWithClient(url, &http.Client{Transport: tr})
+	} else {
+		opts := make([]rpc.ClientOption, 0, 1)
+		if c.rec != nil {
+			opts = append(opts, rpc.WithWebsocketDialer(*capture.NewDialer(c.rec, url)))
+		}
+		rpcClient, err = rpc.DialOptions(context.Background(), url, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{
+		rpc: rpcClient,
+		eth: ethclient.NewClient(rpcClient),
+	}
+	c.conns[url] = cn
+	return cn, nil
+}
+
+// do calls f with the best endpoint, and fails over to others on failures
+// of the endpoint.
+func (c *Client) do(f func(cn *conn) error) error {
+	return c.pool.Do(func(url string) error {
+		cn, err := c.connOf(url)
+		if err != nil {
+			return err
+		}
+		return f(cn)
+	})
+}
+
+// Endpoints returns the health of endpoints.
+func (c *Client) Endpoints() []*endpoint.Status {
+	return c.pool.Statuses()
 }
 
 func toBlockNumArg(number *big.Int) string {
@@ -80,8 +176,15 @@ func newTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit u
 	})
 }
 
+// GetEthClient returns the client for the best endpoint.
+// Use GetBackend for failover.
 func (c *Client) GetEthClient() *ethclient.Client {
-	return c.ethClient
+	cn, err := c.connOf(c.pool.Best())
+	if err != nil {
+		c.log.Warnf("fail to connect err:%+v", err)
+		return nil
+	}
+	return cn.eth
 }
 
 func (c *Client) NewTransactOpts(k *ecdsa.PrivateKey) (*bind.TransactOpts, error) {
@@ -89,7 +192,7 @@ func (c *Client) NewTransactOpts(k *ecdsa.PrivateKey) (*bind.TransactOpts, error
 	if err != nil {
 		return nil, err
 	}
-	txo.GasPrice, _ = c.ethClient.SuggestGasPrice(context.Background())
+	txo.GasPrice, _ = c.GetBackend().SuggestGasPrice(context.Background())
 	txo.GasLimit = uint64(DefaultGasLimit)
 	return txo, nil
 }
@@ -108,7 +211,9 @@ func (c *Client) SendTransaction(tx *types.Transaction) error {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
 
-	err := c.ethClient.SendTransaction(ctx, tx)
+	err := c.do(func(cn *conn) error {
+		return cn.eth.SendTransaction(ctx, tx)
+	})
 
 	if err != nil {
 		c.log.Errorf("could not send tx: %v", err)
@@ -121,7 +226,11 @@ func (c *Client) SendTransaction(tx *types.Transaction) error {
 func (c *Client) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	tr, err := c.ethClient.TransactionReceipt(ctx, hash)
+	var tr *types.Receipt
+	err := c.do(func(cn *conn) (err error) {
+		tr, err = cn.eth.TransactionReceipt(ctx, hash)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -131,7 +240,12 @@ func (c *Client) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error)
 func (c *Client) GetTransaction(hash common.Hash) (*types.Transaction, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	tx, pending, err := c.ethClient.TransactionByHash(ctx, hash)
+	var tx *types.Transaction
+	var pending bool
+	err := c.do(func(cn *conn) (err error) {
+		tx, pending, err = cn.eth.TransactionByHash(ctx, hash)
+		return
+	})
 	if err != nil {
 		return nil, pending, err
 	}
@@ -141,20 +255,144 @@ func (c *Client) GetTransaction(hash common.Hash) (*types.Transaction, bool, err
 func (c *Client) GetBlockByHeight(height *big.Int) (*types.Block, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	return c.ethClient.BlockByNumber(ctx, height)
+	var blk *types.Block
+	err := c.do(func(cn *conn) (err error) {
+		blk, err = cn.eth.BlockByNumber(ctx, height)
+		return
+	})
+	return blk, err
 }
 
 func (c *Client) GetHeaderByHeight(height *big.Int) (*types.Header, error) {
+	if height != nil {
+		if bh := c.cachedHeader(height.Int64()); bh != nil {
+			return bh, nil
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	return c.ethClient.HeaderByNumber(ctx, height)
+	var bh *types.Header
+	err := c.do(func(cn *conn) (err error) {
+		bh, err = cn.eth.HeaderByNumber(ctx, height)
+		return
+	})
+	if err == nil {
+		c.cacheHeader(bh)
+	}
+	return bh, err
+}
+
+// SetCache sets the cache for headers and logs. Headers are cached only if
+// they are finalityDepth blocks below the last block, because they are
+// looked up by height. Cached data of blocks replaced by reorganization
+// are removed on monitoring.
+func (c *Client) SetCache(ch *cache.Cache, finalityDepth int64) {
+	if finalityDepth <= 0 {
+		finalityDepth = DefaultFinalityDepth
+	}
+	c.cache = ch
+	c.finalityDepth = finalityDepth
+}
+
+// setLast updates the height of the last block known by the client.
+func (c *Client) setLast(height int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if height > c.last {
+		c.last = height
+	}
+}
+
+// isFinalized returns whether the block at the height is finalized, it
+// expects the last block is updated before.
+func (c *Client) isFinalized(height int64) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return height <= c.last-c.finalityDepth
+}
+
+func (c *Client) cachedHeader(height int64) *types.Header {
+	bs := c.cache.Get(cache.Key(height, cacheKindHeader))
+	if bs == nil {
+		return nil
+	}
+	bh := &types.Header{}
+	if err := rlp.DecodeBytes(bs, bh); err != nil {
+		return nil
+	}
+	return bh
+}
+
+// cacheHeader stores the header, and removes cached data of the height
+// and its parent if they are replaced.
+func (c *Client) cacheHeader(bh *types.Header) {
+	if c.cache == nil {
+		return
+	}
+	height := bh.Number.Int64()
+	if old := c.cachedHeader(height); old != nil && old.Hash() != bh.Hash() {
+		c.log.Infof("reorganization at %d (%s -> %s)", height, old.Hash(), bh.Hash())
+		c.cache.RemoveFrom(height)
+	}
+	if parent := c.cachedHeader(height - 1); parent != nil && parent.Hash() != bh.ParentHash {
+		c.log.Infof("reorganization at %d (%s -> %s)", height-1, parent.Hash(), bh.ParentHash)
+		c.cache.RemoveFrom(height - 1)
+	}
+	if !c.isFinalized(height) {
+		return
+	}
+	bs, err := rlp.EncodeToBytes(bh)
+	if err != nil {
+		return
+	}
+	c.cache.Put(cache.Key(height, cacheKindHeader), bs)
+}
+
+func logsCacheKey(bh *types.Header, fq *ethereum.FilterQuery) []byte {
+	q := make([][]byte, 0)
+	for _, addr := range fq.Addresses {
+		q = append(q, addr.Bytes())
+	}
+	for i, topics := range fq.Topics {
+		q = append(q, []byte{byte(i)})
+		for _, topic := range topics {
+			q = append(q, topic.Bytes())
+		}
+	}
+	hash := bh.Hash()
+	return cache.Key(bh.Number.Int64(), cacheKindLogs, hash.Bytes(), crypto.Keccak256(q...))
+}
+
+// getLogs returns logs of the block for the query.
+func (c *Client) getLogs(bh *types.Header, fq *ethereum.FilterQuery) ([]types.Log, error) {
+	key := logsCacheKey(bh, fq)
+	if bs := c.cache.Get(key); bs != nil {
+		var logs []types.Log
+		if err := json.Unmarshal(bs, &logs); err == nil {
+			return logs, nil
+		}
+	}
+	q := *fq
+	hash := bh.Hash()
+	q.BlockHash = &hash
+	logs, err := c.FilterLogs(q)
+	if err != nil {
+		return nil, err
+	}
+	if bs, err := json.Marshal(logs); err == nil {
+		c.cache.Put(key, bs)
+	}
+	return logs, nil
 }
 
 func (c *Client) GetProof(height *big.Int, addr common.Address) (StorageProof, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
 	var proof StorageProof
-	if err := c.rpcClient.CallContext(ctx, &proof, "eth_getProof", addr, nil, toBlockNumArg(height)); err != nil {
+	err := c.do(func(cn *conn) error {
+		return cn.rpc.CallContext(ctx, &proof, "eth_getProof", addr, nil, toBlockNumArg(height))
+	})
+	if err != nil {
 		return proof, err
 	}
 	return proof, nil
@@ -165,7 +403,11 @@ func (c *Client) GetBlockReceipts(block *types.Block) ([]*types.Receipt, error)
 	defer cancel()
 	var receipts []*types.Receipt
 	for _, tx := range block.Transactions() {
-		receipt, err := c.ethClient.TransactionReceipt(ctx, tx.Hash())
+		var receipt *types.Receipt
+		err := c.do(func(cn *conn) (err error) {
+			receipt, err = cn.eth.TransactionReceipt(ctx, tx.Hash())
+			return
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -177,7 +419,11 @@ func (c *Client) GetBlockReceipts(block *types.Block) ([]*types.Receipt, error)
 func (c *Client) FilterLogs(fq ethereum.FilterQuery) ([]types.Log, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	logs, err := c.ethClient.FilterLogs(ctx, fq)
+	var logs []types.Log
+	err := c.do(func(cn *conn) (err error) {
+		logs, err = cn.eth.FilterLogs(ctx, fq)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -187,46 +433,31 @@ func (c *Client) FilterLogs(fq ethereum.FilterQuery) ([]types.Log, error) {
 func (c *Client) GetChainID() (*big.Int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	return c.ethClient.ChainID(ctx)
+	var id *big.Int
+	err := c.do(func(cn *conn) (err error) {
+		id, err = cn.eth.ChainID(ctx)
+		return
+	})
+	return id, err
 }
 
 func (c *Client) GetBlockNumber() (uint64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
-	return c.ethClient.BlockNumber(ctx)
+	var n uint64
+	err := c.do(func(cn *conn) (err error) {
+		n, err = cn.eth.BlockNumber(ctx)
+		return
+	})
+	if err == nil {
+		c.setLast(int64(n))
+	}
+	return n, err
 }
 
 // Poll deprecated
 func (c *Client) Poll(cb func(bh *types.Header) error, errCb func(int64, error)) error {
-	n, err := c.GetBlockNumber()
-	if err != nil {
-		return err
-	}
-	current := new(big.Int).SetUint64(n)
-	for {
-		select {
-		case <-c.stop:
-			return nil
-		default:
-			var bh *types.Header
-			if bh, err = c.GetHeaderByHeight(current); err != nil {
-				if ethereum.NotFound == err {
-					c.log.Trace("Block not ready, will retry ", current)
-				} else {
-					c.log.Error("Unable to get block ", current, err)
-				}
-				<-time.After(BlockRetryInterval)
-				continue
-			}
-
-			if err = cb(bh); err != nil {
-				c.log.Errorf("Poll callback return err:%+v", err)
-				errCb(bh.Number.Int64()-1, err)
-			}
-
-			current.Add(current, big.NewInt(1))
-		}
-	}
+	return c.monitor(nil, true, cb, errCb)
 }
 
 func (c *Client) MonitorBlock(br *BlockRequest, cb func(b *BlockNotification) error, errCb func(int64, error)) error {
@@ -238,89 +469,161 @@ func (c *Client) MonitorBlock(br *BlockRequest, cb func(b *BlockNotification) er
 		}
 		if br.FilterQuery != nil {
 			var err error
-			fq := *br.FilterQuery
-			fq.BlockHash = &bn.Hash
-			if bn.Logs, err = c.FilterLogs(fq); err != nil {
+			if bn.Logs, err = c.getLogs(bh, br.FilterQuery); err != nil {
 				c.log.Info("Unable to get logs ", err)
 				return err
 			}
 		}
 		return cb(bn)
 	}
-	var (
-		h   *big.Int
-		one = big.NewInt(1)
-	)
-	return c.Monitor(func(bh *types.Header) error {
-		if h == nil {
-			h = new(big.Int).Set(br.Height)
-			for ; h.Cmp(bh.Number) < 0; h = h.Add(h, one) {
-				for {
-					tbh, err := c.GetHeaderByHeight(h)
-					if err != nil {
-						c.log.Debugf("failure GetHeaderByHeight(%v) err:%+v", h, err)
-						continue
-					} else {
-						err = onBlockHeader(tbh)
-						if err != nil {
-							return err
-						}
-						break
-					}
-				}
-			}
-		}
-		return onBlockHeader(bh)
-	}, errCb)
+	return c.monitor(br.Height, false, onBlockHeader, errCb)
 }
 
 func (c *Client) Monitor(cb func(bh *types.Header) error, errCb func(int64, error)) error {
-	if strings.HasPrefix(c.uri, "http") {
-		return c.Poll(cb, errCb)
-	}
-	var (
-		s   ethereum.Subscription
-		err error
-		ch  = make(chan *types.Header)
-	)
-	if s, err = c.ethClient.SubscribeNewHead(context.Background(), ch); err != nil {
-		if rpc.ErrNotificationsUnsupported == err {
-			c.log.Infoln("%v, try polling", err)
-			return c.Poll(cb, errCb)
+	return c.monitor(nil, false, cb, errCb)
+}
+
+// monitor calls cb with headers from the height, or from the last block if
+// height is nil. On failures of the endpoint, it fails over to other one,
+// and resumes from the next of the last processed block.
+func (c *Client) monitor(height *big.Int, poll bool, cb func(bh *types.Header) error,
+	errCb func(int64, error)) error {
+	var next *big.Int
+	if height != nil {
+		next = new(big.Int).Set(height)
+	}
+	onHeader := func(bh *types.Header) error {
+		if err := cb(bh); err != nil {
+			c.log.Errorf("MonitorBlock callback return err:%+v", err)
+			return callbackError{err}
 		}
-		return err
+		c.log.Debugf("MonitorBlock %v", bh.Number.Int64())
+		next = new(big.Int).Add(bh.Number, big.NewInt(1))
+		return nil
 	}
 	for {
+		url := c.pool.Best()
+		err := c.monitorEndpoint(url, &next, poll, onHeader)
+		if cbErr, ok := err.(callbackError); ok {
+			errCb(next.Int64()-1, cbErr.error)
+			return cbErr.error
+		}
+		if err == nil {
+			return nil
+		}
+		c.pool.Report(url, 0, err)
+		c.log.Warnf("fail to monitor %s from %v, retry err:%+v", url, next, err)
 		select {
-		case err = <-s.Err():
+		case <-c.stop:
+			return nil
+		case <-time.After(BlockRetryInterval):
+		}
+	}
+}
+
+func (c *Client) monitorEndpoint(url string, next **big.Int, poll bool, cb func(bh *types.Header) error) error {
+	cn, err := c.connOf(url)
+	if err != nil {
+		return err
+	}
+	if *next == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		n, err := cn.eth.BlockNumber(ctx)
+		cancel()
+		if err != nil {
 			return err
-		case bh := <-ch:
-			if err = cb(bh); err != nil {
-				c.log.Errorf("MonitorBlock callback return err:%+v", err)
+		}
+		*next = new(big.Int).SetUint64(n)
+		c.setLast(int64(n))
+	}
+	// headers from next to the height
+	catchUp := func(height *big.Int) error {
+		for (*next).Cmp(height) <= 0 {
+			bh := c.cachedHeader((*next).Int64())
+			if bh == nil {
+				var err error
+				ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+				bh, err = cn.eth.HeaderByNumber(ctx, *next)
+				cancel()
+				if err != nil {
+					return err
+				}
+				c.cacheHeader(bh)
+			}
+			if err := cb(bh); err != nil {
 				return err
 			}
-			c.log.Debugf("MonitorBlock %v", bh.Number.Int64())
+		}
+		return nil
+	}
+
+	if !poll && !strings.HasPrefix(url, "http") {
+		ch := make(chan *types.Header)
+		s, err := cn.eth.SubscribeNewHead(context.Background(), ch)
+		if err == nil {
+			defer s.Unsubscribe()
+			for {
+				select {
+				case <-c.stop:
+					return nil
+				case err = <-s.Err():
+					return err
+				case bh := <-ch:
+					c.setLast(bh.Number.Int64())
+					c.cacheHeader(bh)
+					if err = catchUp(bh.Number); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if rpc.ErrNotificationsUnsupported != err {
+			return err
+		}
+		c.log.Infof("%v, try polling", err)
+	}
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		n, err := cn.eth.BlockNumber(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		c.setLast(int64(n))
+		if err = catchUp(new(big.Int).SetUint64(n)); err != nil {
+			return err
+		}
+		select {
+		case <-c.stop:
+			return nil
+		case <-time.After(BlockRetryInterval):
 		}
 	}
 }
 
 func (c *Client) CloseMonitor() {
-	c.log.Debugf("CloseMonitor %s", c.rpcClient)
-	c.ethClient.Close()
-	c.rpcClient.Close()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for url, cn := range c.conns {
+		c.log.Debugf("CloseMonitor %s", url)
+		cn.eth.Close()
+		delete(c.conns, url)
+	}
 }
 
 func (c *Client) CloseAllMonitor() {
-	// TODO: do we need to multiple connections?
 	c.CloseMonitor()
 }
 
+// GetBackend returns the backend for contract bindings, which fails over
+// between endpoints.
 func (c *Client) GetBackend() bind.ContractBackend {
-	return c.ethClient
+	return &backend{c}
 }
 
 func (c *Client) GetRevertMessage(hash common.Hash) (string, error) {
-	tx, _, err := c.ethClient.TransactionByHash(context.Background(), hash)
+	tx, _, err := c.GetTransaction(hash)
 	if err != nil {
 		return "", err
 	}
@@ -339,27 +642,46 @@ func (c *Client) GetRevertMessage(hash common.Hash) (string, error) {
 		Data:     tx.Data(),
 	}
 
-	_, err = c.ethClient.CallContract(context.Background(), msg, nil)
+	_, err = c.GetBackend().CallContract(context.Background(), msg, nil)
 	return err.Error(), nil
 
 }
 
-func NewClient(uri string, l log.Logger) *Client {
-	//TODO options {MaxRetrySendTx, MaxRetryGetResult, MaxIdleConnsPerHost, Debug, Dump} }
-	rpcClient, err := rpc.Dial(uri)
-	if err != nil {
-		l.Fatal("Error creating client", err)
-	}
+// NewClient returns the client for endpoints, calls are routed to
+// the best endpoint by health checks with the last block. Each HTTP
+// endpoint has its own rate limit and retries by opt, and messages of all
+// endpoints are recorded with the capture of opt.
+func NewClient(uris []string, opt *jsonrpc.Options, l log.Logger) (*Client, error) {
 	c := &Client{
-		uri:       uri,
-		rpcClient: rpcClient,
-		ethClient: ethclient.NewClient(rpcClient),
-		log:       l,
+		conns: make(map[string]*conn),
+		log:   l,
 	}
-	c.chainID, _ = c.GetChainID()
-	l.Tracef("Client Connected Chain ID: ", c.chainID)
+	if opt != nil {
+		c.opt = *opt
+	}
+	if c.opt.Capture != nil && c.opt.Capture.Filename != "" {
+		var err error
+		if c.rec, err = capture.OpenRecorder(c.opt.Capture); err != nil {
+			return nil, err
+		}
+	}
+	pool, err := endpoint.NewPool(uris, func(url string) (int64, error) {
+		cn, err := c.connOf(url)
+		if err != nil {
+			return 0, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		defer cancel()
+		n, err := cn.eth.BlockNumber(ctx)
+		return int64(n), err
+	}, isEndpointFailure, l)
 	if err != nil {
-		c.log.Error("Error creating tendermintLightclient system contract", err)
+		return nil, err
 	}
-	return c
+	c.pool = pool
+	if c.chainID, err = c.GetChainID(); err != nil {
+		c.log.Warnf("fail to get chain ID err:%+v", err)
+	}
+	l.Tracef("Client Connected Chain ID: ", c.chainID)
+	return c, nil
 }
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/cache"
+	"github.com/icon-project/btp2/common/capture"
+	"github.com/icon-project/btp2/common/jsonrpc"
+	"github.com/icon-project/btp2/common/log"
+)
+
+func testHeader(height int64, parent *types.Header, extra string) *types.Header {
+	bh := &types.Header{
+		Number:     big.NewInt(height),
+		Difficulty: big.NewInt(1),
+		Extra:      []byte(extra),
+	}
+	if parent != nil {
+		bh.ParentHash = parent.Hash()
+	}
+	return bh
+}
+
+func TestClient_CacheHeader(t *testing.T) {
+	c := &Client{log: log.New()}
+	c.SetCache(cache.New(0, nil), 2)
+	headers := make([]*types.Header, 11)
+	for h := range headers {
+		var parent *types.Header
+		if h > 0 {
+			parent = headers[h-1]
+		}
+		headers[h] = testHeader(int64(h), parent, "")
+	}
+	c.setLast(10)
+	for _, bh := range headers {
+		c.cacheHeader(bh)
+	}
+	// headers in the finality depth aren't cached
+	for h, bh := range headers {
+		if h <= 8 {
+			assert.Equal(t, bh.Hash(), c.cachedHeader(int64(h)).Hash(), "height:%d", h)
+		} else {
+			assert.Nil(t, c.cachedHeader(int64(h)), "height:%d", h)
+		}
+	}
+
+	// replaced header removes cached headers from its height
+	c.setLast(12)
+	replaced := testHeader(8, headers[7], "replaced")
+	c.cacheHeader(replaced)
+	assert.Equal(t, replaced.Hash(), c.cachedHeader(8).Hash())
+	assert.Equal(t, headers[7].Hash(), c.cachedHeader(7).Hash())
+
+	// header whose parent is replaced removes the cached parent
+	child := testHeader(9, headers[8], "")
+	c.cacheHeader(child)
+	assert.Nil(t, c.cachedHeader(8))
+	assert.Equal(t, child.Hash(), c.cachedHeader(9).Hash())
+}
+
+type testEthService struct{}
+
+func (s *testEthService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (s *testEthService) BlockNumber() hexutil.Uint64 {
+	return 10
+}
+
+func TestClient_CaptureWebsocket(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("eth", &testEthService{}))
+	defer server.Stop()
+	srv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "capture.jsonl")
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	c, err := NewClient([]string{url}, &jsonrpc.Options{Capture: &capture.Options{
+		WriterConfig: log.WriterConfig{Filename: file},
+	}}, log.New())
+	assert.NoError(t, err)
+	defer c.CloseMonitor()
+	n, err := c.GetBlockNumber()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), n)
+
+	bs, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	recs, err := capture.ReadRecords(bytes.NewReader(bs))
+	assert.NoError(t, err)
+	methods := make(map[string]bool)
+	for _, rec := range recs {
+		assert.Equal(t, capture.KindWS, rec.Kind)
+		if strings.Contains(string(rec.Request), "eth_blockNumber") {
+			methods["request"] = true
+		}
+		if strings.Contains(string(rec.Response), "0xa") {
+			methods["response"] = true
+		}
+	}
+	assert.True(t, methods["request"], "no request")
+	assert.True(t, methods["response"], "no response")
+
+	// capture file which can't be opened
+	_, err = NewClient([]string{url}, &jsonrpc.Options{Capture: &capture.Options{
+		WriterConfig: log.WriterConfig{Filename: filepath.Join(file, "capture.jsonl")},
+	}}, log.New())
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/link"
 	"github.com/icon-project/btp2/common/log"
 	"github.com/icon-project/btp2/common/types"
@@ -15,11 +16,13 @@ import (
 const TYPE = "eth-bridge"
 
 func RegisterEthBridge() {
+	db.RegisterSchema(TYPE, schemaMigrations...)
 	link.RegisterFactory(&link.Factory{
 		Type:             TYPE,
 		ParseChainConfig: ParseChainConfig,
 		NewReceiver:      NewReceiver,
 		NewSender:        NewSender,
+		OpenStore:        OpenStore,
 	})
 }
 
@@ -36,8 +39,25 @@ func ParseChainConfig(raw json.RawMessage) (link.ChainConfig, error) {
 
 func NewReceiver(srcCfg link.ChainConfig, dstAddr types.BtpAddress, baseDir string, l log.Logger) (link.Receiver, error) {
 	src := srcCfg.(chain.BaseConfig)
+	database, err := src.OpenDatabase(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return newEthBridge(srcCfg, dstAddr, src.GetEndpoints(), l, database, src.Options)
+}
 
-	return newEthBridge(srcCfg, dstAddr, src.Endpoint, l, baseDir, src.Options)
+func OpenStore(srcCfg link.ChainConfig, baseDir string, l log.Logger) (link.Store, error) {
+	src := srcCfg.(chain.BaseConfig)
+	database, err := src.OpenDatabase(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newStore(database, l)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	return s, nil
 }
 
 func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, baseDir string, l log.Logger) (types.Sender, error) {
@@ -47,7 +67,7 @@ func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, baseDir string
 		return nil, err
 	}
 
-	return newSender(srcAddr, dst, w, dst.Endpoint, dst.Options, l), nil
+	return newSender(srcAddr, dst, w, dst.GetEndpoints(), dst.Options, l)
 }
 
 func newWallet(passwd, secret string, keyStorePath string) (types.Wallet, error) {
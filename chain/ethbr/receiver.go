@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"math/big"
-	"path/filepath"
 	"sort"
 	"unsafe"
 
@@ -16,23 +15,19 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/icon-project/btp2/chain/ethbr/binding"
 	"github.com/icon-project/btp2/chain/ethbr/client"
+	"github.com/icon-project/btp2/common/cache"
 	"github.com/icon-project/btp2/common/codec"
 	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/jsonrpc"
 	"github.com/icon-project/btp2/common/link"
 	"github.com/icon-project/btp2/common/log"
 	btpTypes "github.com/icon-project/btp2/common/types"
 )
 
-const (
-	ReceiveBlockPrefix = "H|"
-)
-
 type receiveStatus struct {
 	height   int64
 	startSeq int64
@@ -48,6 +43,16 @@ func (r *receiveStatus) Seq() int64 {
 	return r.lastSeq
 }
 
+func (r *receiveStatus) Size() int64 {
+	var size int64
+	for _, rp := range r.rps {
+		for _, evt := range rp.Events {
+			size += int64(len(evt.Next) + len(evt.Message))
+		}
+	}
+	return size
+}
+
 func (r *receiveStatus) StartSeq() int64 {
 	return r.startSeq
 }
@@ -66,37 +71,46 @@ func newReceiveStatus(height, startSeq, lastSeq int64, rps []*client.ReceiptProo
 }
 
 const (
-	DefaultDBType  = db.GoLevelDBBackend
 	EventSignature = "Message(string,uint256,bytes)"
 )
 
 type ethbr struct {
+	*store
 	l             log.Logger
 	src           link.ChainConfig
 	dst           btpTypes.BtpAddress
 	c             *client.Client
+	cache         *cache.Cache
 	nid           int64
 	rsc           chan interface{}
-	rss           []*receiveStatus
-	seq           int64
+	rss           *link.ReceiveStatusList
+	seq           int64 // owned by the monitor goroutine
 	startHeight   int64
 	receiveHeight int64
-	db            *leveldb.DB
 	opt           struct {
 		StartHeight int64
-	}
-}
-
-func newEthBridge(src link.ChainConfig, dst btpTypes.BtpAddress, endpoint string,
-	l log.Logger, baseDir string, opt map[string]interface{}) (*ethbr, error) {
+		// Cache keeps headers and logs from the endpoint, in the database
+		// if Cache.Persist is set.
+		Cache cache.Options
+		// FinalityDepth is the number of blocks on the last block, below
+		// which headers are cached.
+		FinalityDepth int64
+		// Window pauses monitoring while statuses pending for
+		// finalization exceed MaxCount or MaxBytes.
+		Window link.WindowConfig
+		// retries and the rate limit of JSON-RPC requests
+		jsonrpc.Options
+	}
+}
+
+func newEthBridge(src link.ChainConfig, dst btpTypes.BtpAddress, endpoints []string,
+	l log.Logger, database db.Database, opt map[string]interface{}) (*ethbr, error) {
 	c := &ethbr{
 		src: src,
 		dst: dst,
 		l:   l,
 		rsc: make(chan interface{}),
-		rss: make([]*receiveStatus, 0),
 	}
-	c.c = client.NewClient(endpoint, l)
 	b, err := json.Marshal(opt)
 	if err != nil {
 		l.Panicf("fail to marshal opt:%#v err:%+v", opt, err)
@@ -105,99 +119,21 @@ func newEthBridge(src link.ChainConfig, dst btpTypes.BtpAddress, endpoint string
 	if err = json.Unmarshal(b, &c.opt); err != nil {
 		l.Panicf("fail to unmarshal opt:%#v err:%+v", opt, err)
 	}
+	c.rss = link.NewReceiveStatusListWithWindow(&c.opt.Window)
 
-	err = c.prepareDatabase(baseDir)
-	if err != nil {
+	if c.c, err = client.NewClient(endpoints, &c.opt.Options, l); err != nil {
+		database.Close()
 		return nil, err
 	}
-	return c, nil
-}
-
-func (e *ethbr) getFirstHeightForReceiveBlock() int64 {
-	iter := e.db.NewIterator(util.BytesPrefix([]byte(ReceiveBlockPrefix)), nil)
-	if iter.First() {
-		return new(big.Int).SetBytes(iter.Key()[len([]byte(ReceiveBlockPrefix)):]).Int64()
-	}
-	return 0
-}
-
-func (e *ethbr) addBTPBlockDatabase(height int64, data []byte) error {
-	h := big.NewInt(height)
-	key := append([]byte(ReceiveBlockPrefix), h.Bytes()...)
-	return e.db.Put(key, data, nil)
-}
-
-func (e *ethbr) removeAllReceiveBlock() error {
-	e.l.Debugf("removeAllReceiveBlock")
-	iter := e.db.NewIterator(util.BytesPrefix([]byte(ReceiveBlockPrefix)), nil)
-	if iter.Next() {
-		e.l.Debugf("Delete height stored in database (height : %d)",
-			new(big.Int).SetBytes(iter.Key()[len([]byte(ReceiveBlockPrefix)):]).Int64())
-		if err := e.db.Delete(iter.Key(), nil); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (e *ethbr) removeReceiveBlockByHeight(height int64) error {
-
-	h := big.NewInt(height)
-	key := append([]byte(ReceiveBlockPrefix), h.Bytes()...)
-	iter := e.db.NewIterator(util.BytesPrefix([]byte(ReceiveBlockPrefix)), nil)
-	if iter.Seek(key) {
-		for iter.Prev() {
-			e.l.Debugf("Delete height stored in database (height : %d)",
-				new(big.Int).SetBytes(iter.Key()[len([]byte(ReceiveBlockPrefix)):]).Int64())
-			if err := e.db.Delete(iter.Key(), nil); err != nil {
-				return err
-			}
-		}
-	}
-	return e.db.Delete(key, nil)
-}
-
-func (e *ethbr) setLastReceiveHeight(height int64) error {
-	bytesArray := big.NewInt(height).Bytes()
-	return e.db.Put([]byte("LastReceiveHeight"), bytesArray, nil)
-}
-
-func (e *ethbr) getLastReceiveHeight() (int64, error) {
-	if has, err := e.db.Has([]byte("LastReceiveHeight"), nil); err != nil {
-		return 0, err
-	} else if has {
-		bytesArray, err := e.db.Get([]byte("LastReceiveHeight"), nil)
-		if err != nil {
-			return 0, err
-		}
-		return new(big.Int).SetBytes(bytesArray).Int64(), nil
-	}
-	return 0, nil
-}
-
-func (e *ethbr) deleteAllDatabase() error {
-	e.l.Debugf("deleteAllDatabase")
-	iter := e.db.NewIterator(nil, nil)
-	for iter.Next() {
-		e.db.Delete(iter.Key(), nil)
-	}
-	return nil
-}
-
-func (e *ethbr) prepareDatabase(baseDir string) error {
-	var err error
-	dbDir := filepath.Join(baseDir, e.src.GetAddress().NetworkAddress())
-	e.l.Debugln("open database", dbDir)
-	e.db, err = leveldb.OpenFile(dbDir, nil)
+	c.store, err = newStore(database, l)
 	if err != nil {
-		return errors.Wrap(err, "fail to open database")
+		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			e.db.Close()
-		}
-	}()
-	return nil
+	if c.cache, err = cache.NewWithOptions(&c.opt.Cache, database, CacheBucket); err != nil {
+		return nil, err
+	}
+	c.c.SetCache(c.cache, c.opt.FinalityDepth)
+	return c, nil
 }
 
 func (e *ethbr) Start(bls *btpTypes.BMCLinkStatus) (<-chan interface{}, error) {
@@ -211,11 +147,12 @@ func (e *ethbr) Start(bls *btpTypes.BMCLinkStatus) (<-chan interface{}, error) {
 }
 
 func (e *ethbr) Stop() {
+	e.rss.Close()
 	close(e.rsc)
 }
 
 func (e *ethbr) GetStatus() (link.ReceiveStatus, error) {
-	return e.rss[len(e.rss)-1], nil
+	return e.rss.Status()
 }
 
 func (e *ethbr) BuildBlockUpdate(bls *btpTypes.BMCLinkStatus, limit int64) ([]link.BlockUpdate, error) {
@@ -313,32 +250,24 @@ func (e *ethbr) FinalizedStatus(blsc <-chan *btpTypes.BMCLinkStatus) {
 		for {
 			select {
 			case bls := <-blsc:
-				e.removeReceiveBlockByHeight(bls.Verifier.Height)
+				e.removeReceiveBlock(bls.Verifier.Height)
 				e.clearReceiveStatus(bls)
+				e.cache.RemoveBelow(bls.Verifier.Height)
 			}
 		}
 	}()
 }
 
-func (e *ethbr) nextReceiveStatus(bls *btpTypes.BMCLinkStatus) *receiveStatus {
-	for i, rs := range e.rss {
-		if bls.Verifier.Height <= rs.Height() {
-			if bls.Verifier.Height == rs.Height() {
-				return e.rss[i+1]
-			}
-			return e.rss[i]
-		}
-	}
-	return nil
+// nextReceiveStatus returns the first status after the verifier height.
+func (e *ethbr) nextReceiveStatus(bls *btpTypes.BMCLinkStatus) link.ReceiveStatus {
+	return e.rss.Find(func(rs link.ReceiveStatus) bool {
+		return bls.Verifier.Height < rs.Height()
+	})
 }
 
 func (e *ethbr) clearReceiveStatus(bls *btpTypes.BMCLinkStatus) {
-	for i, rs := range e.rss {
-		if rs.Height() <= bls.Verifier.Height && rs.Seq() <= bls.RxSeq {
-			e.l.Debugf("clear receive data (height:%d, seq:%d) ", bls.Verifier.Height, bls.RxSeq)
-			e.rss = e.rss[i+1:]
-			return
-		}
+	if removed := e.rss.RemoveDelivered(bls.Verifier.Height, bls.RxSeq); len(removed) > 0 {
+		e.l.Debugf("clear receive data (height:%d, seq:%d) ", bls.Verifier.Height, bls.RxSeq)
 	}
 }
 
@@ -352,7 +281,7 @@ func (e *ethbr) monitoring(bls *btpTypes.BMCLinkStatus) error {
 	}
 
 	if bls.RxSeq < 1 {
-		if err := e.deleteAllDatabase(); err != nil {
+		if err := e.resetLastReceiveHeight(); err != nil {
 			return err
 		}
 	}
@@ -362,23 +291,20 @@ func (e *ethbr) monitoring(bls *btpTypes.BMCLinkStatus) error {
 		return err
 	}
 
-	fb := e.getFirstHeightForReceiveBlock()
 	if bls.Verifier.Height > lastHeight {
-		if err := e.deleteAllDatabase(); err != nil {
+		if err := e.resetLastReceiveHeight(); err != nil {
 			return err
 		}
 		height = bls.Verifier.Height
-	} else {
-		if fb != 0 && bls.Verifier.Height > fb {
-			height = bls.Verifier.Height
-		} else if fb > bls.Verifier.Height {
-			height = fb - 1
-		} else {
-			height = lastHeight
-		}
-		if err := e.removeAllReceiveBlock(); err != nil {
+	} else if err = e.store.Verify(bls); err != nil {
+		e.l.Warnf("resume from verifier height:%d instead of LastReceiveHeight:%d err:%+v",
+			bls.Verifier.Height, lastHeight, err)
+		if err = e.resetLastReceiveHeight(); err != nil {
 			return err
 		}
+		height = bls.Verifier.Height
+	} else {
+		height = lastHeight
 	}
 
 	e.l.Debugf("ReceiveLoop height:%d seq:%d filterQuery[Address:%s,Topic:%s]",
@@ -400,11 +326,6 @@ func (e *ethbr) monitoring(bls *btpTypes.BMCLinkStatus) error {
 	return e.c.MonitorBlock(br,
 		func(v *client.BlockNotification) error {
 			e.receiveHeight = v.Height.Int64()
-			if v.Height.Int64()%500 == 0 {
-				if err := e.setLastReceiveHeight(v.Height.Int64()); err != nil {
-					return err
-				}
-			}
 			if len(v.Logs) > 0 {
 				var startSeq int64
 				var lastSeq int64
@@ -457,14 +378,25 @@ func (e *ethbr) monitoring(bls *btpTypes.BMCLinkStatus) error {
 						return err
 					}
 
-					e.rss = append(e.rss, rs)
+					if e.rss.Full(rs) {
+						e.l.Infof("pause monitoring until finalization (count:%d, bytes:%d)",
+							e.rss.Len(), e.rss.Bytes())
+						e.rsc <- e.rss.WindowFull()
+					}
+					e.rss.Append(rs)
 					e.l.Debugf("monitor info : Height:%d  RpsCnt:%d LastSeq:%d ",
 						v.Height.Int64(), len(rps), e.seq)
 
 					e.rsc <- rs
 				}
 			}
-
+			// saved after the messages of the block are received, so the
+			// block is not monitored again on restart
+			if v.Height.Int64()%500 == 0 {
+				if err := e.setLastReceiveHeight(v.Height.Int64(), e.seq); err != nil {
+					return err
+				}
+			}
 			return nil
 		}, errCb)
 }
@@ -503,19 +435,11 @@ func (e *ethbr) newBlockUpdate(v *client.BlockNotification) (*client.BlockUpdate
 }
 
 func (e *ethbr) getReceiveStatusForSequence(seq int64) *receiveStatus {
-	for _, rs := range e.rss {
-		if rs.startSeq <= seq && seq <= rs.lastSeq {
-			return rs
-		}
-	}
-	return nil
-}
-
-func (e *ethbr) getReceiveStatusForHeight(height int64) *receiveStatus {
-	for _, rs := range e.rss {
-		if rs.Height() == height {
-			return rs
-		}
+	if rs := e.rss.Find(func(rs link.ReceiveStatus) bool {
+		r := rs.(*receiveStatus)
+		return r.startSeq <= seq && seq <= r.lastSeq
+	}); rs != nil {
+		return rs.(*receiveStatus)
 	}
 	return nil
 }
@@ -0,0 +1,226 @@
+package ethbr
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/chain/ethbr/binding"
+	"github.com/icon-project/btp2/chain/ethbr/client"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	btpTypes "github.com/icon-project/btp2/common/types"
+)
+
+func TestMain(m *testing.M) {
+	RegisterEthBridge()
+	client.BlockRetryInterval = 10 * time.Millisecond
+	os.Exit(m.Run())
+}
+
+const testStartHeight = 10
+
+var (
+	testSrc = btpTypes.BtpAddress("btp://0x1.eth/0x0000000000000000000000000000000000000001")
+	testDst = btpTypes.BtpAddress("btp://0x2.icon/cx0000000000000000000000000000000000000002")
+)
+
+// testChain serves blocks with Message events of BMC as an ethereum node.
+type testChain struct {
+	t       *testing.T
+	mtx     sync.Mutex
+	seq     int64
+	headers []*types.Header
+	logs    map[common.Hash][]*types.Log
+}
+
+func newTestChain(t *testing.T) *testChain {
+	c := &testChain{t: t, logs: make(map[common.Hash][]*types.Log)}
+	for h := 0; h <= testStartHeight; h++ {
+		c.addBlock(0)
+	}
+	return c
+}
+
+// addBlock adds the block with n messages to the destination, and returns
+// the height.
+func (c *testChain) addBlock(n int) int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	bh := &types.Header{
+		Number:     big.NewInt(int64(len(c.headers))),
+		Difficulty: big.NewInt(1),
+		Extra:      make([]byte, 97),
+	}
+	if len(c.headers) > 0 {
+		bh.ParentHash = c.headers[len(c.headers)-1].Hash()
+	}
+	bmcABI, err := abi.JSON(strings.NewReader(binding.BMCABI))
+	assert.NoError(c.t, err)
+	hash := bh.Hash()
+	logs := make([]*types.Log, 0, n)
+	for i := 0; i < n; i++ {
+		c.seq++
+		data, err := bmcABI.Events["Message"].Inputs.NonIndexed().Pack([]byte("message"))
+		assert.NoError(c.t, err)
+		logs = append(logs, &types.Log{
+			Address: common.HexToAddress(testSrc.ContractAddress()),
+			Topics: []common.Hash{
+				crypto.Keccak256Hash([]byte(EventSignature)),
+				crypto.Keccak256Hash([]byte(testDst.String())),
+				common.BigToHash(big.NewInt(c.seq)),
+			},
+			Data:        data,
+			BlockNumber: bh.Number.Uint64(),
+			BlockHash:   hash,
+			Index:       uint(i),
+		})
+	}
+	c.headers = append(c.headers, bh)
+	c.logs[hash] = logs
+	return bh.Number.Int64()
+}
+
+func (c *testChain) handle(method string, params []json.RawMessage) (interface{}, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	last := c.headers[len(c.headers)-1]
+	switch method {
+	case "eth_chainId":
+		return hexutil.Uint64(1), nil
+	case "eth_blockNumber":
+		return hexutil.Uint64(last.Number.Uint64()), nil
+	case "eth_getBlockByNumber":
+		var num string
+		if err := json.Unmarshal(params[0], &num); err != nil {
+			return nil, err
+		}
+		if num == "latest" {
+			return last, nil
+		}
+		height, err := hexutil.DecodeUint64(num)
+		if err != nil {
+			return nil, err
+		}
+		if height >= uint64(len(c.headers)) {
+			return nil, nil
+		}
+		return c.headers[height], nil
+	case "eth_getLogs":
+		q := &struct {
+			BlockHash common.Hash `json:"blockHash"`
+		}{}
+		if err := json.Unmarshal(params[0], q); err != nil {
+			return nil, err
+		}
+		return c.logs[q.BlockHash], nil
+	default:
+		return nil, nil
+	}
+}
+
+func (c *testChain) serve() *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if result, err := c.handle(req.Method, req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": -32602, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	c.t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestEthBridge_Race relays blocks like the link, while the monitor
+// receives following blocks and finalized statuses are handled.
+func TestEthBridge_Race(t *testing.T) {
+	const blocks = 20
+	c := newTestChain(t)
+	srv := c.serve()
+	cfg := chain.BaseConfig{Address: testSrc, Endpoint: srv.URL, Type: TYPE}
+	e, err := newEthBridge(cfg, testDst, []string{srv.URL}, log.New(), db.NewMapDB(), nil)
+	assert.NoError(t, err)
+	defer e.Stop()
+
+	bls := &btpTypes.BMCLinkStatus{}
+	bls.Verifier.Height = testStartHeight
+	rsc, err := e.Start(bls)
+	assert.NoError(t, err)
+	blsc := make(chan *btpTypes.BMCLinkStatus)
+	e.FinalizedStatus(blsc)
+
+	go func() {
+		for i := 0; i < blocks; i++ {
+			c.addBlock(1)
+		}
+	}()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if rs, err := e.GetStatus(); err == nil {
+				e.GetHeightForSeq(rs.Seq())
+			}
+		}
+	}()
+
+	for received := 0; received < blocks; received++ {
+		var rs link.ReceiveStatus
+		select {
+		case v := <-rsc:
+			var ok bool
+			if rs, ok = v.(link.ReceiveStatus); !ok {
+				assert.FailNow(t, "unexpected notification", "%+v", v)
+			}
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timeout", "received %d of %d", received, blocks)
+		}
+		bus, err := e.BuildBlockUpdate(bls, 1024*1024)
+		assert.NoError(t, err)
+		assert.Len(t, bus, 1)
+		assert.Equal(t, rs.Height(), bus[0].TargetHeight())
+		assert.NoError(t, bus[0].UpdateBMCLinkStatus(bls))
+		mp, err := e.BuildMessageProof(bls, 1024*1024)
+		assert.NoError(t, err)
+		if assert.NotNil(t, mp) {
+			assert.Equal(t, rs.Seq(), mp.LastSeqNum())
+			assert.NoError(t, mp.UpdateBMCLinkStatus(bls))
+		}
+
+		finalized := *bls
+		blsc <- &finalized
+	}
+	assert.Equal(t, int64(blocks), bls.RxSeq)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/common/jsonrpc"
 	"github.com/icon-project/btp2/common/link"
 	btpTypes "github.com/icon-project/btp2/common/types"
 
@@ -98,6 +99,8 @@ type sender struct {
 	w       btpTypes.Wallet
 	l       log.Logger
 	opt     struct {
+		// retries and the rate limit of JSON-RPC requests
+		jsonrpc.Options
 	}
 	bmc                *binding.BMC
 	rr                 chan *btpTypes.RelayResult
@@ -105,7 +108,7 @@ type sender struct {
 	queue              *queue
 }
 
-func newSender(srcAddr btpTypes.BtpAddress, dstCfg link.ChainConfig, w btpTypes.Wallet, endpoint string, opt map[string]interface{}, l log.Logger) btpTypes.Sender {
+func newSender(srcAddr btpTypes.BtpAddress, dstCfg link.ChainConfig, w btpTypes.Wallet, endpoints []string, opt map[string]interface{}, l log.Logger) (btpTypes.Sender, error) {
 	s := &sender{
 		srcAddr: srcAddr,
 		dstCfg:  dstCfg.(chain.BaseConfig),
@@ -123,11 +126,13 @@ func newSender(srcAddr btpTypes.BtpAddress, dstCfg link.ChainConfig, w btpTypes.
 		l.Panicf("fail to unmarshal opt:%#v err:%+v", opt, err)
 	}
 
-	s.c = client.NewClient(endpoint, l)
+	if s.c, err = client.NewClient(endpoints, &s.opt.Options, l); err != nil {
+		return nil, err
+	}
 
-	s.bmc, _ = binding.NewBMC(client.HexToAddress(s.dstCfg.Address.ContractAddress()), s.c.GetEthClient())
+	s.bmc, _ = binding.NewBMC(client.HexToAddress(s.dstCfg.Address.ContractAddress()), s.c.GetBackend())
 
-	return s
+	return s, nil
 }
 
 func (s *sender) Start() (<-chan *btpTypes.RelayResult, error) {
@@ -0,0 +1,203 @@
+package ethbr
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+const (
+	ReceiveBlockBucket db.BucketID = "H|"
+	PropertyBucket     db.BucketID = "P|"
+	CacheBucket        db.BucketID = "K|"
+	// LegacyPropertyBucket is for the keys stored without bucket before
+	// schema version 1.
+	LegacyPropertyBucket db.BucketID = ""
+	KeyLastReceiveHeight             = "LastReceiveHeight"
+	KeyLastReceiveSeq                = "LastReceiveSeq"
+)
+
+var schemaMigrations = []*db.Migration{
+	{
+		Version:     1,
+		Description: "move LastReceiveHeight to the property bucket",
+		Migrate:     migrateLastReceiveHeight,
+	},
+}
+
+func migrateLastReceiveHeight(database db.Database, batch db.Batch) error {
+	bk, err := database.GetBucket(LegacyPropertyBucket)
+	if err != nil {
+		return err
+	}
+	bs, err := bk.Get([]byte(KeyLastReceiveHeight))
+	if err != nil || bs == nil {
+		return err
+	}
+	batch.Set(PropertyBucket, []byte(KeyLastReceiveHeight), bs)
+	batch.Delete(LegacyPropertyBucket, []byte(KeyLastReceiveHeight))
+	return nil
+}
+
+// store is the database of the receiver.
+type store struct {
+	db     db.Database
+	blocks db.Bucket
+	props  db.Bucket
+}
+
+// newStore migrates the database to the latest schema, and returns the
+// store on it.
+func newStore(database db.Database, l log.Logger) (*store, error) {
+	version, applied, err := db.Migrate(database, TYPE, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range applied {
+		l.Infof("migrate database from version %d to %d (%s)", version, m.Version, m.Description)
+		version = m.Version
+	}
+	s := &store{db: database}
+	if s.blocks, err = database.GetBucket(ReceiveBlockBucket); err != nil {
+		return nil, err
+	}
+	if s.props, err = database.GetBucket(PropertyBucket); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *store) removeReceiveBlock(height int64) error {
+	return s.blocks.Delete(big.NewInt(height).Bytes())
+}
+
+// setLastReceiveHeight saves the height with the last sequence of the
+// messages received until the height.
+func (s *store) setLastReceiveHeight(height, seq int64) error {
+	batch := s.db.NewBatch()
+	batch.Set(PropertyBucket, []byte(KeyLastReceiveHeight), big.NewInt(height).Bytes())
+	batch.Set(PropertyBucket, []byte(KeyLastReceiveSeq), big.NewInt(seq).Bytes())
+	return batch.Write()
+}
+
+func (s *store) getLastReceiveHeight() (int64, error) {
+	bs, err := s.props.Get([]byte(KeyLastReceiveHeight))
+	if err != nil {
+		return 0, err
+	}
+	return new(big.Int).SetBytes(bs).Int64(), nil
+}
+
+// getLastReceiveSeq returns the sequence saved with LastReceiveHeight, and
+// false if it's not saved.
+func (s *store) getLastReceiveSeq() (int64, bool, error) {
+	bs, err := s.props.Get([]byte(KeyLastReceiveSeq))
+	if err != nil || bs == nil {
+		return 0, false, err
+	}
+	return new(big.Int).SetBytes(bs).Int64(), true, nil
+}
+
+func (s *store) resetLastReceiveHeight() error {
+	batch := s.db.NewBatch()
+	batch.Delete(PropertyBucket, []byte(KeyLastReceiveHeight))
+	batch.Delete(PropertyBucket, []byte(KeyLastReceiveSeq))
+	return batch.Write()
+}
+
+func (s *store) LastReceiveHeight() (int64, error) {
+	return s.getLastReceiveHeight()
+}
+
+func (s *store) Entries() ([]*link.StoreEntry, error) {
+	entries := make([]*link.StoreEntry, 0)
+	it := s.blocks.NewIterator(nil)
+	defer it.Release()
+	for it.Next() {
+		entries = append(entries, &link.StoreEntry{
+			Kind:   link.StoreEntryHeader,
+			Height: new(big.Int).SetBytes(it.Key()).Int64(),
+			Value:  common.HexBytes(append([]byte{}, it.Value()...)),
+		})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Height < entries[j].Height
+	})
+	return entries, nil
+}
+
+// Reset removes the blocks after the height, and lowers LastReceiveHeight
+// to the height. The sequence of LastReceiveHeight is removed, because it's
+// unknown for the height.
+func (s *store) Reset(height int64) error {
+	if height < 0 {
+		return errors.IllegalArgumentError.Errorf("invalid height %d", height)
+	}
+	batch := s.db.NewBatch()
+	it := s.blocks.NewIterator(nil)
+	for it.Next() {
+		if new(big.Int).SetBytes(it.Key()).Int64() > height {
+			batch.Delete(ReceiveBlockBucket, it.Key())
+		}
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		return err
+	}
+	lastHeight, err := s.getLastReceiveHeight()
+	if err != nil {
+		return err
+	}
+	if lastHeight > height {
+		batch.Set(PropertyBucket, []byte(KeyLastReceiveHeight), big.NewInt(height).Bytes())
+		batch.Delete(PropertyBucket, []byte(KeyLastReceiveSeq))
+	}
+	return batch.Write()
+}
+
+// Verify checks whether the receiver can resume from LastReceiveHeight for
+// the status. Messages between the status and LastReceiveHeight would be
+// skipped, if LastReceiveHeight is over the verifier height and messages
+// received until it are not delivered.
+func (s *store) Verify(bls *types.BMCLinkStatus) error {
+	lastHeight, err := s.getLastReceiveHeight()
+	if err != nil {
+		return err
+	}
+	if lastHeight <= bls.Verifier.Height {
+		return nil
+	}
+	seq, ok, err := s.getLastReceiveSeq()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.InvalidStateError.Errorf(
+			"unknown sequence of LastReceiveHeight %d over verifier height %d",
+			lastHeight, bls.Verifier.Height)
+	}
+	if seq > bls.RxSeq {
+		return errors.InvalidStateError.Errorf(
+			"LastReceiveHeight %d with seq %d over verifier height %d with rxSeq %d",
+			lastHeight, seq, bls.Verifier.Height, bls.RxSeq)
+	}
+	return nil
+}
+
+func (s *store) Compact() error {
+	return db.Compact(s.db)
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
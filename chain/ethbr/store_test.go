@@ -0,0 +1,131 @@
+package ethbr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/intconv"
+	"github.com/icon-project/btp2/common/link/linktest"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+func newTestStore(t *testing.T) *store {
+	s, err := newStore(db.NewMapDB(), log.New())
+	assert.NoError(t, err)
+	return s
+}
+
+func linkStatus(height, rxSeq int64) *types.BMCLinkStatus {
+	bls := &types.BMCLinkStatus{RxSeq: rxSeq}
+	bls.Verifier.Height = height
+	return bls
+}
+
+func TestStore_Verify(t *testing.T) {
+	s := newTestStore(t)
+	assert.NoError(t, s.Verify(linkStatus(10, 0)))
+
+	assert.NoError(t, s.setLastReceiveHeight(500, 3))
+	for _, c := range []struct {
+		name   string
+		bls    *types.BMCLinkStatus
+		failed bool
+	}{
+		{"verifier over LastReceiveHeight", linkStatus(600, 2), false},
+		{"verifier at LastReceiveHeight", linkStatus(500, 2), false},
+		{"messages are delivered", linkStatus(400, 3), false},
+		{"messages are not delivered", linkStatus(400, 2), true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := s.Verify(c.bls)
+			if c.failed {
+				assert.True(t, errors.InvalidStateError.Equals(err), "err:%+v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// the sequence is unknown for the height lowered by Reset
+	assert.NoError(t, s.Reset(450))
+	height, err := s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(450), height)
+	assert.True(t, errors.InvalidStateError.Equals(s.Verify(linkStatus(400, 3))))
+	assert.NoError(t, s.Verify(linkStatus(450, 0)))
+
+	assert.NoError(t, s.resetLastReceiveHeight())
+	assert.NoError(t, s.Verify(linkStatus(0, 0)))
+}
+
+func TestStore_MigrateLastReceiveHeight(t *testing.T) {
+	database := linktest.NewDatabase(t, t.TempDir())
+	legacy, err := database.GetBucket(LegacyPropertyBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Set([]byte(KeyLastReceiveHeight), big.NewInt(100).Bytes()))
+
+	s, err := newStore(database, log.New())
+	assert.NoError(t, err)
+	version, err := db.GetSchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	height, err := s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), height)
+	assert.Nil(t, linktest.BucketKeys(t, database, LegacyPropertyBucket))
+	assert.Equal(t, [][]byte{[]byte(KeyLastReceiveHeight)}, linktest.BucketKeys(t, database, PropertyBucket))
+
+	// the sequence is unknown for the migrated height
+	_, ok, err := s.getLastReceiveSeq()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStore_NewerSchema(t *testing.T) {
+	database := linktest.NewDatabase(t, t.TempDir())
+	bk, err := database.GetBucket(db.SchemaBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, bk.Set([]byte("Version"), intconv.Int64ToBytes(2)))
+	_, err = newStore(database, log.New())
+	assert.True(t, errors.UnsupportedError.Equals(err), "err:%+v", err)
+}
+
+func TestStore_Reset(t *testing.T) {
+	s := newTestStore(t)
+	for _, h := range []int64{10, 11, 12} {
+		assert.NoError(t, s.blocks.Set(big.NewInt(h).Bytes(), []byte("header")))
+	}
+	assert.NoError(t, s.setLastReceiveHeight(12, 3))
+	assert.True(t, errors.IllegalArgumentError.Equals(s.Reset(-1)))
+
+	assert.NoError(t, s.Reset(11))
+	entries, err := s.Entries()
+	assert.NoError(t, err)
+	var heights []int64
+	for _, e := range entries {
+		heights = append(heights, e.Height)
+	}
+	assert.Equal(t, []int64{10, 11}, heights)
+	height, err := s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), height)
+	_, ok, err := s.getLastReceiveSeq()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// LastReceiveHeight is not raised, and the sequence is kept
+	assert.NoError(t, s.setLastReceiveHeight(11, 2))
+	assert.NoError(t, s.Reset(20))
+	height, err = s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), height)
+	seq, ok, err := s.getLastReceiveSeq()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), seq)
+}
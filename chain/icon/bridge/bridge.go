@@ -2,6 +2,7 @@ package bridge
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"unsafe"
 
@@ -10,7 +11,9 @@ import (
 
 	"github.com/icon-project/btp2/chain/icon/client"
 	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/errors"
 	"github.com/icon-project/btp2/common/intconv"
+	"github.com/icon-project/btp2/common/jsonrpc"
 	"github.com/icon-project/btp2/common/link"
 	"github.com/icon-project/btp2/common/log"
 	"github.com/icon-project/btp2/common/types"
@@ -30,6 +33,14 @@ func (r *receiveStatus) Seq() int64 {
 	return r.seq
 }
 
+func (r *receiveStatus) Size() int64 {
+	var size int64
+	for _, evt := range r.rp.Events {
+		size += int64(len(evt.Next) + len(evt.Message))
+	}
+	return size
+}
+
 func (r *receiveStatus) ReceiptProof() *ReceiptProof {
 	return r.rp
 }
@@ -39,11 +50,22 @@ type bridge struct {
 	src         link.ChainConfig
 	dst         types.BtpAddress
 	c           *client.Client
+	sub         *client.Subscription
 	nid         int64
 	rsc         chan interface{}
-	rss         []*receiveStatus
-	rs          *receiveStatus
+	rss         *link.ReceiveStatusList
+	rs          *receiveStatus // owned by the monitor goroutine
 	startHeight int64
+	opt         struct {
+		// Polling gets BTP blocks by HTTP JSON-RPC instead of websocket,
+		// for endpoints which don't support websocket.
+		Polling bool
+		// Window pauses monitoring while statuses pending for
+		// finalization exceed MaxCount or MaxBytes.
+		Window link.WindowConfig
+		// retries and the rate limit of JSON-RPC requests
+		jsonrpc.Options
+	}
 }
 
 func newReceiveStatus(height, rxSeq int64, sn int64, msgs []string, next types.BtpAddress) (*receiveStatus, error) {
@@ -73,16 +95,27 @@ func newReceiveStatus(height, rxSeq int64, sn int64, msgs []string, next types.B
 	}, nil
 }
 
-func newBridge(src link.ChainConfig, dst types.BtpAddress, endpoint string, baseDir string, l log.Logger) (*bridge, error) {
+func newBridge(src link.ChainConfig, dst types.BtpAddress, endpoints []string, baseDir string,
+	l log.Logger, opt map[string]interface{}) (*bridge, error) {
 	c := &bridge{
 		src: src,
 		dst: dst,
 		l:   l,
 		rsc: make(chan interface{}),
-		rss: make([]*receiveStatus, 0),
 		rs:  &receiveStatus{},
 	}
-	c.c = client.NewClient(endpoint, l)
+	b, err := json.Marshal(opt)
+	if err != nil {
+		return nil, errors.IllegalArgumentError.Wrapf(err, "fail to marshal opt:%#v", opt)
+	}
+	if err = json.Unmarshal(b, &c.opt); err != nil {
+		return nil, errors.IllegalArgumentError.Wrapf(err, "fail to unmarshal opt:%#v", opt)
+	}
+	c.rss = link.NewReceiveStatusListWithWindow(&c.opt.Window)
+	if c.c, err = client.NewClient(endpoints, &c.opt.Options, l); err != nil {
+		return nil, err
+	}
+	c.sub = client.NewSubscription(c.c, &client.SubscriptionOptions{Polling: c.opt.Polling})
 	return c, nil
 }
 
@@ -109,6 +142,10 @@ func (b *bridge) Start(bls *types.BMCLinkStatus) (<-chan interface{}, error) {
 
 	go func() {
 		err := b.monitoring(bls)
+		if err == nil {
+			// stopped
+			return
+		}
 		b.l.Debugf("Unknown monitoring error occurred  (err : %v)", err)
 		b.rsc <- err
 	}()
@@ -117,11 +154,13 @@ func (b *bridge) Start(bls *types.BMCLinkStatus) (<-chan interface{}, error) {
 }
 
 func (b *bridge) Stop() {
+	b.rss.Close()
+	b.sub.Close()
 	close(b.rsc)
 }
 
 func (b *bridge) GetStatus() (link.ReceiveStatus, error) {
-	return b.rss[len(b.rss)-1], nil
+	return b.rss.Status()
 }
 
 func (b *bridge) GetHeightForSeq(seq int64) int64 {
@@ -137,6 +176,9 @@ func (b *bridge) BuildBlockUpdate(bls *types.BMCLinkStatus, limit int64) ([]link
 	b.l.Debugf("Build BlockUpdate (height=%d, rxSeq=%d)", bls.Verifier.Height, bls.RxSeq)
 	bus := make([]link.BlockUpdate, 0)
 	rs := b.nextReceiveStatus(bls)
+	if rs == nil {
+		return nil, errors.IllegalArgumentError.New("No blockUpdate available to create.")
+	}
 	bu := newBlockUpdate(bls, rs.Height())
 	bus = append(bus, bu)
 	return bus, nil
@@ -212,29 +254,14 @@ func (b *bridge) monitoring(bls *types.BMCLinkStatus) error {
 		ProofFlag: client.NewHexInt(0),
 	}
 
-	onErr := func(conn *websocket.Conn, err error) {
-		b.l.Debugf("onError %s err:%+v", conn.LocalAddr().String(), err)
-		b.c.CloseMonitor(conn)
-		//Restart Monitoring
-		ls := &types.BMCLinkStatus{}
-		ls.RxSeq = b.rs.Seq()
-		ls.Verifier.Height = b.rs.Height()
-		b.l.Debugf("Restart Monitoring")
-		b.monitoring(ls)
-	}
 	onConn := func(conn *websocket.Conn) {
 		b.l.Debugf("ReceiveLoop monitorBTP2Block height:%d seq:%d networkId:%d connected %s",
-			bls.Verifier.Height, bls.RxSeq, b.nid, conn.LocalAddr().String())
+			b.rs.Height(), b.rs.Seq(), b.nid, conn.LocalAddr().String())
 	}
-
-	err := b.monitorBTP2Block(req, bls, onConn, onErr)
-	if err != nil {
-		return err
-	}
-	return nil
+	return b.monitorBTP2Block(req, bls, onConn)
 }
 
-func (b *bridge) monitorBTP2Block(req *client.BTPRequest, bls *types.BMCLinkStatus, scb func(conn *websocket.Conn), errCb func(*websocket.Conn, error)) error {
+func (b *bridge) monitorBTP2Block(req *client.BTPRequest, bls *types.BMCLinkStatus, scb func(conn *websocket.Conn)) error {
 	offset, err := b.c.GetBTPLinkOffset(b.src.GetAddress(), b.dst)
 	if err != nil {
 		return err
@@ -247,7 +274,7 @@ func (b *bridge) monitorBTP2Block(req *client.BTPRequest, bls *types.BMCLinkStat
 		b.rs.seq = bls.RxSeq
 	}
 
-	return b.c.MonitorBTP(req, func(conn *websocket.Conn, v *client.BTPNotification) error {
+	return b.sub.MonitorBTP(req, func(v *client.BTPNotification) error {
 		h, err := base64.StdEncoding.DecodeString(v.Header)
 		if err != nil {
 			return err
@@ -270,42 +297,38 @@ func (b *bridge) monitorBTP2Block(req *client.BTPRequest, bls *types.BMCLinkStat
 				return err
 			}
 			b.rs = rs
-			b.rss = append(b.rss, rs)
+			if b.rss.Full(rs) {
+				b.l.Infof("pause monitoring until finalization (count:%d, bytes:%d)",
+					b.rss.Len(), b.rss.Bytes())
+				b.rsc <- b.rss.WindowFull()
+			}
+			b.rss.Append(rs)
 			b.l.Debugf("monitor info : Height:%d  UpdateNumber:%d  MessageCnt:%d ", bh.MainHeight, bh.UpdateNumber, len(msgs))
 
 			b.rsc <- rs
 		}
 		return nil
-	}, scb, errCb)
+	}, scb)
 }
 
-func (b *bridge) nextReceiveStatus(bls *types.BMCLinkStatus) *receiveStatus {
-	for i, rs := range b.rss {
-		if bls.Verifier.Height <= rs.Height() {
-			if bls.Verifier.Height == rs.Height() {
-				return b.rss[i+1]
-			}
-			return b.rss[i]
-		}
-	}
-	return nil
+// nextReceiveStatus returns the first status after the verifier height.
+func (b *bridge) nextReceiveStatus(bls *types.BMCLinkStatus) link.ReceiveStatus {
+	return b.rss.Find(func(rs link.ReceiveStatus) bool {
+		return bls.Verifier.Height < rs.Height()
+	})
 }
 
 func (b *bridge) clearReceiveStatus(bls *types.BMCLinkStatus) {
-	for i, rs := range b.rss {
-		if rs.Height() <= bls.Verifier.Height && rs.Seq() <= bls.RxSeq {
-			b.l.Debugf("clear receive data (height:%d, seq:%d) ", bls.Verifier.Height, bls.RxSeq)
-			b.rss = b.rss[i+1:]
-			return
-		}
+	if removed := b.rss.RemoveDelivered(bls.Verifier.Height, bls.RxSeq); len(removed) > 0 {
+		b.l.Debugf("clear receive data (height:%d, seq:%d) ", bls.Verifier.Height, bls.RxSeq)
 	}
 }
 
 func (b *bridge) getReceiveStatusForSequence(seq int64) *receiveStatus {
-	for _, rs := range b.rss {
-		if rs.Seq() <= seq && seq <= rs.Seq() {
-			return rs
-		}
+	if rs := b.rss.Find(func(rs link.ReceiveStatus) bool {
+		return rs.Seq() == seq
+	}); rs != nil {
+		return rs.(*receiveStatus)
 	}
 	return nil
 }
@@ -0,0 +1,190 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/jsonrpc"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/link/linktest"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+const (
+	testNetworkID   = 1
+	testStartHeight = 10
+)
+
+var (
+	testSrc = types.BtpAddress("btp://0x1.icon/cx0000000000000000000000000000000000000001")
+	testDst = types.BtpAddress("btp://0x2.icon/cx0000000000000000000000000000000000000002")
+)
+
+// testChain serves BTP blocks as an ICON node, proofs are not served
+// because the bridge doesn't relay them.
+type testChain struct {
+	mtx      sync.Mutex
+	seq      int64
+	last     int64
+	headers  map[int64][]byte
+	messages map[int64][]string
+}
+
+func newTestChain() *testChain {
+	c := &testChain{
+		headers:  make(map[int64][]byte),
+		messages: make(map[int64][]string),
+	}
+	c.addBlock(0)
+	return c
+}
+
+// addBlock adds the BTP block with n messages, and returns the height.
+func (c *testChain) addBlock(n int) int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	height := int64(testStartHeight)
+	if c.last != 0 {
+		height = c.last + 1
+	}
+	bh := &client.BTPBlockHeader{
+		MainHeight:   height,
+		NetworkID:    testNetworkID,
+		UpdateNumber: c.seq << 1,
+		MessageCount: int64(n),
+	}
+	msgs := make([]string, n)
+	for i := range msgs {
+		msgs[i] = base64.StdEncoding.EncodeToString([]byte("message"))
+	}
+	c.headers[height] = codec.RLP.MustMarshalToBytes(bh)
+	c.messages[height] = msgs
+	c.seq += int64(n)
+	c.last = height
+	return height
+}
+
+func (c *testChain) handle(method string, params json.RawMessage) (interface{}, *jsonrpc.Error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	switch method {
+	case "icx_getLastBlock":
+		// BTP blocks are polled until the block before the last one
+		return &client.Block{Height: c.last + 1}, nil
+	case "icx_call":
+		p := &client.CallParam{}
+		if err := json.Unmarshal(params, p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		switch p.Data.(map[string]interface{})["method"] {
+		case "getBTPLinkNetworkId":
+			return client.NewHexInt(testNetworkID), nil
+		default:
+			return client.NewHexInt(0), nil
+		}
+	case "btp_getNetworkInfo":
+		return &client.BTPNetworkInfo{
+			StartHeight: client.NewHexInt(testStartHeight - 1),
+			NetworkID:   client.NewHexInt(testNetworkID),
+		}, nil
+	case "btp_getHeader", "btp_getMessages":
+		p := &client.BTPBlockParam{}
+		if err := json.Unmarshal(params, p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		height, _ := p.Height.Value()
+		if _, ok := c.headers[height]; !ok {
+			return nil, &jsonrpc.Error{Code: client.JsonrpcErrorCodeNotFound, Message: "NotFound"}
+		}
+		if method == "btp_getHeader" {
+			return base64.StdEncoding.EncodeToString(c.headers[height]), nil
+		}
+		return c.messages[height], nil
+	default:
+		return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeMethodNotFound, Message: method}
+	}
+}
+
+func TestBridge_InvalidOption(t *testing.T) {
+	cfg := chain.BaseConfig{Address: testSrc, Type: TYPE}
+	_, err := newBridge(cfg, testDst, []string{"http://localhost"}, "", log.New(),
+		map[string]interface{}{"polling": "yes"})
+	assert.True(t, errors.IllegalArgumentError.Equals(err), "err:%+v", err)
+}
+
+// TestBridge_Race relays blocks like the link, while the monitor receives
+// following blocks and finalized statuses are handled.
+func TestBridge_Race(t *testing.T) {
+	const blocks = 20
+	c := newTestChain()
+	srv := linktest.ServeJSONRPC(t, c.handle)
+	cfg := chain.BaseConfig{Address: testSrc, Endpoint: srv.URL, Type: TYPE}
+	b, err := newBridge(cfg, testDst, []string{srv.URL}, "", log.New(),
+		map[string]interface{}{"polling": true})
+	assert.NoError(t, err)
+	defer b.Stop()
+
+	bls := &types.BMCLinkStatus{}
+	bls.Verifier.Height = testStartHeight
+	rsc, err := b.Start(bls)
+	assert.NoError(t, err)
+	blsc := make(chan *types.BMCLinkStatus)
+	b.FinalizedStatus(blsc)
+
+	go func() {
+		for i := 0; i < blocks; i++ {
+			c.addBlock(1)
+		}
+	}()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if rs, err := b.GetStatus(); err == nil {
+				b.GetHeightForSeq(rs.Seq())
+			}
+		}
+	}()
+
+	for received := 0; received < blocks; received++ {
+		var rs link.ReceiveStatus
+		select {
+		case v := <-rsc:
+			var ok bool
+			if rs, ok = v.(link.ReceiveStatus); !ok {
+				assert.FailNow(t, "unexpected notification", "%+v", v)
+			}
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timeout", "received %d of %d", received, blocks)
+		}
+		bus, err := b.BuildBlockUpdate(bls, 1024*1024)
+		assert.NoError(t, err)
+		assert.Len(t, bus, 1)
+		assert.Equal(t, rs.Height(), bus[0].TargetHeight())
+		assert.NoError(t, bus[0].UpdateBMCLinkStatus(bls))
+		mp, err := b.BuildMessageProof(bls, 1024*1024)
+		assert.NoError(t, err)
+		if assert.NotNil(t, mp) {
+			assert.NoError(t, mp.UpdateBMCLinkStatus(bls))
+		}
+
+		finalized := *bls
+		blsc <- &finalized
+	}
+	assert.Equal(t, int64(blocks), bls.RxSeq)
+}
@@ -38,7 +38,7 @@ func ParseChainConfig(raw json.RawMessage) (link.ChainConfig, error) {
 func NewReceiver(srcCfg link.ChainConfig, dstAddr types.BtpAddress, baseDir string, l log.Logger) (link.Receiver, error) {
 	src := srcCfg.(chain.BaseConfig)
 
-	return newBridge(src, dstAddr, src.Endpoint, baseDir, l)
+	return newBridge(src, dstAddr, src.GetEndpoints(), baseDir, l, src.Options)
 }
 
 func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, baseDir string, l log.Logger) (types.Sender, error) {
@@ -48,7 +48,7 @@ func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, baseDir string
 		return nil, err
 	}
 
-	return icon.NewSender(srcAddr, dst, w, dst.Endpoint, dst.Options, l), nil
+	return icon.NewSender(srcAddr, dst, w, dst.GetEndpoints(), dst.Options, l)
 }
 
 func newWallet(passwd, secret string, keyStorePath string) (types.Wallet, error) {
@@ -0,0 +1,127 @@
+package btp2
+
+import (
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/mta"
+	"github.com/icon-project/btp2/common/types"
+)
+
+const (
+	AccumulatorStateKey = "State"
+)
+
+// accumulatorIndex locates the header of the main height in the accumulator.
+type accumulatorIndex struct {
+	Height     int64
+	HeaderHash []byte
+}
+
+// prepareAccumulator recovers the accumulator of headers. The empty one
+// starts from the accumulator height of the BMV, so that headers have the
+// same heights in both accumulators.
+func (b *btp2) prepareAccumulator(bls *types.BMCLinkStatus) error {
+	vs := &client.VerifierStatus{}
+	if _, err := codec.RLP.UnmarshalFromBytes(bls.Verifier.Extra, vs); err != nil {
+		return err
+	}
+	acc, err := mta.RecoverExtAccumulator([]byte(AccumulatorStateKey), b.accBucket, vs.AccumulatorHeight)
+	if err != nil {
+		return err
+	}
+	b.acc = acc
+	return nil
+}
+
+func (b *btp2) getAccumulatorIndex(height int64) (*accumulatorIndex, error) {
+	bs, err := b.accIndex.Get(heightToKey(height))
+	if err != nil || bs == nil {
+		return nil, err
+	}
+	idx := &accumulatorIndex{}
+	if _, err = codec.RLP.UnmarshalFromBytes(bs, idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// accumulate adds the header to the accumulator, the header which is
+// already accumulated is ignored for restarting the monitor.
+func (b *btp2) accumulate(height int64, header []byte) error {
+	b.accMtx.Lock()
+	defer b.accMtx.Unlock()
+	if idx, err := b.getAccumulatorIndex(height); err != nil {
+		return err
+	} else if idx != nil {
+		return nil
+	}
+	b.acc.AddData(header)
+	if err := b.acc.Flush(); err != nil {
+		return err
+	}
+	idx := &accumulatorIndex{
+		Height:     b.acc.Height(),
+		HeaderHash: crypto.SHA3Sum256(header),
+	}
+	return b.accIndex.Set(heightToKey(height), codec.RLP.MustMarshalToBytes(idx))
+}
+
+func (b *btp2) BuildBlockProof(bls *types.BMCLinkStatus, height int64) (link.BlockProof, error) {
+	if height < 1 || height >= bls.Verifier.Height {
+		// messages at the verifier height are proven by the last block update
+		return nil, nil
+	}
+	vs := &client.VerifierStatus{}
+	if _, err := codec.RLP.UnmarshalFromBytes(bls.Verifier.Extra, vs); err != nil {
+		return nil, err
+	}
+	if vs.AccumulatorHeight == 0 {
+		// the BMV doesn't verify block proofs
+		return nil, nil
+	}
+	b.accMtx.Lock()
+	defer b.accMtx.Unlock()
+	if b.acc == nil {
+		return nil, errors.InvalidStateError.New("accumulator is not prepared")
+	}
+	if vs.AccumulatorOffset != b.acc.Offset() {
+		// headers before the accumulator are unknown, so the witness for
+		// the BMV couldn't be built. the link keeps running without the
+		// block proof until the BMV is updated.
+		b.l.Warnf("fail to build block proof, accumulator offset mismatch (height:%d, offset:%d, bmv offset:%d)",
+			height, b.acc.Offset(), vs.AccumulatorOffset)
+		return nil, nil
+	}
+	idx, err := b.getAccumulatorIndex(height)
+	if err != nil {
+		return nil, err
+	}
+	if idx == nil {
+		return nil, errors.NotFoundError.Errorf("no accumulated header for height:%d", height)
+	}
+	if idx.Height > vs.AccumulatorHeight {
+		return nil, errors.InvalidStateError.Errorf(
+			"header is not accumulated by the BMV (height:%d, acc height:%d, bmv acc height:%d)",
+			height, idx.Height, vs.AccumulatorHeight)
+	}
+	header, err := db.DoGet(b.accBucket, idx.HeaderHash)
+	if err != nil {
+		return nil, err
+	}
+	accHeight, w, err := b.acc.WitnessForAt(idx.Height, vs.AccumulatorHeight, vs.AccumulatorOffset)
+	if err != nil {
+		return nil, err
+	}
+	bp := &client.BlockProof{
+		Header: header,
+		BlockWitness: &client.BlockWitness{
+			Height:  accHeight,
+			Witness: mta.WitnessesToHashes(w),
+		},
+	}
+	return NewBlockProof(height, bp), nil
+}
@@ -0,0 +1,133 @@
+package btp2
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/mta"
+	"github.com/icon-project/btp2/common/types"
+)
+
+func accumulatorLinkStatus(height, accHeight, accOffset int64) *types.BMCLinkStatus {
+	bls := &types.BMCLinkStatus{}
+	bls.Verifier.Height = height
+	bls.Verifier.Extra = codec.RLP.MustMarshalToBytes(&client.VerifierStatus{
+		AccumulatorHeight: accHeight,
+		AccumulatorOffset: accOffset,
+	})
+	return bls
+}
+
+func newTestAccumulatorBTP2(t *testing.T, bls *types.BMCLinkStatus) *btp2 {
+	s, err := newStore(db.NewMapDB(), log.New())
+	assert.NoError(t, err)
+	b := &btp2{store: s, l: log.New()}
+	assert.NoError(t, b.prepareAccumulator(bls))
+	return b
+}
+
+func TestBTP2_BuildBlockProof(t *testing.T) {
+	const (
+		offset = 100
+		start  = 11
+		end    = 20
+		// the BMV accumulated headers until main height bmvHeight
+		bmvHeight = 17
+	)
+	b := newTestAccumulatorBTP2(t, accumulatorLinkStatus(start-1, offset, offset))
+	assert.Equal(t, int64(offset), b.acc.Offset())
+
+	// accumulator of the BMV, which started with the same offset
+	bmvDB := db.NewMapDB()
+	bk, err := bmvDB.GetBucket(db.BucketID("bmv"))
+	assert.NoError(t, err)
+	bmvAcc := mta.NewExtAccumulator([]byte(AccumulatorStateKey), bk, offset)
+	var status *mta.Status
+	headers := make(map[int64][]byte)
+	for h := int64(start); h <= end; h++ {
+		headers[h] = []byte(fmt.Sprintf("header of %d", h))
+		assert.NoError(t, b.accumulate(h, headers[h]))
+		bmvAcc.AddData(headers[h])
+		if h == bmvHeight {
+			status = bmvAcc.Status()
+		}
+	}
+	assert.Equal(t, int64(offset+bmvHeight-start+1), status.Height)
+
+	bls := accumulatorLinkStatus(bmvHeight, status.Height, status.Offset)
+	for h := int64(start); h < bmvHeight; h++ {
+		bp, err := b.BuildBlockProof(bls, h)
+		assert.NoError(t, err)
+		if !assert.NotNil(t, bp) {
+			continue
+		}
+		assert.Equal(t, h, bp.ProofHeight())
+
+		p := &client.BlockProof{}
+		_, err = codec.RLP.UnmarshalFromBytes(bp.(*blockProof).payload, p)
+		assert.NoError(t, err)
+		assert.Equal(t, headers[h], p.Header)
+		assert.Equal(t, status.Height, p.BlockWitness.Height)
+		accHeight := offset + h - start + 1
+		assert.NoError(t, status.VerifyAt(accHeight, p.BlockWitness.Height,
+			p.BlockWitness.Witness, crypto.SHA3Sum256(p.Header)), "height:%d", h)
+	}
+
+	// messages at the verifier height are proven by the block update
+	bp, err := b.BuildBlockProof(bls, bmvHeight)
+	assert.NoError(t, err)
+	assert.Nil(t, bp)
+
+	// header which is not accumulated by the BMV yet
+	bls = accumulatorLinkStatus(end, status.Height, status.Offset)
+	_, err = b.BuildBlockProof(bls, bmvHeight+1)
+	assert.True(t, errors.InvalidStateError.Equals(err))
+
+	// BMV which started with the other offset
+	bls = accumulatorLinkStatus(bmvHeight, status.Height, status.Offset+1)
+	bp, err = b.BuildBlockProof(bls, start)
+	assert.NoError(t, err)
+	assert.Nil(t, bp)
+
+	// BMV which doesn't verify block proofs
+	bls = accumulatorLinkStatus(bmvHeight, 0, 0)
+	bp, err = b.BuildBlockProof(bls, start)
+	assert.NoError(t, err)
+	assert.Nil(t, bp)
+}
+
+func TestBTP2_PrepareAccumulator(t *testing.T) {
+	b := newTestAccumulatorBTP2(t, accumulatorLinkStatus(10, 100, 100))
+	assert.NoError(t, b.accumulate(11, []byte("header of 11")))
+
+	// recovered accumulator keeps its offset
+	b2 := &btp2{store: b.store, l: b.l}
+	assert.NoError(t, b2.prepareAccumulator(accumulatorLinkStatus(11, 101, 100)))
+	assert.Equal(t, int64(100), b2.acc.Offset())
+	assert.Equal(t, int64(101), b2.acc.Height())
+}
+
+func TestBTP2_BuildBlockProofWithLowerOffset(t *testing.T) {
+	// the BMV accumulated headers before the accumulator of the relay
+	b := newTestAccumulatorBTP2(t, accumulatorLinkStatus(10, 105, 100))
+	assert.Equal(t, int64(105), b.acc.Offset())
+	for h := int64(11); h <= 15; h++ {
+		assert.NoError(t, b.accumulate(h, []byte(fmt.Sprintf("header of %d", h))))
+	}
+
+	// the link keeps running without block proofs
+	bls := accumulatorLinkStatus(15, 110, 100)
+	for h := int64(11); h < 15; h++ {
+		bp, err := b.BuildBlockProof(bls, h)
+		assert.NoError(t, err)
+		assert.Nil(t, bp)
+	}
+}
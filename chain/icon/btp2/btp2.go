@@ -1,33 +1,38 @@
 package btp2
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"math/big"
-	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/gorilla/websocket"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/cache"
 	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/errors"
 	"github.com/icon-project/btp2/common/intconv"
+	"github.com/icon-project/btp2/common/jsonrpc"
 	"github.com/icon-project/btp2/common/link"
 	"github.com/icon-project/btp2/common/log"
 	"github.com/icon-project/btp2/common/mbt"
+	"github.com/icon-project/btp2/common/mta"
+	"github.com/icon-project/btp2/common/ntm"
 	"github.com/icon-project/btp2/common/types"
 )
 
 const (
 	DefaultProgressInterval = 50
-	ReceiveBlockPrefix      = "H|"
 )
 
 type receiveStatus struct {
-	height int64
-	seq    int64
+	height   int64
+	seq      int64
+	msgCount int64
 }
 
 func (r *receiveStatus) Height() int64 {
@@ -38,129 +43,92 @@ func (r *receiveStatus) Seq() int64 {
 	return r.seq
 }
 
-func newReceiveStatus(height, seq int64) (*receiveStatus, error) {
+func (r *receiveStatus) MessageCount() int64 {
+	return r.msgCount
+}
+
+func newReceiveStatus(height, seq, msgCount int64) (*receiveStatus, error) {
 	return &receiveStatus{
-		height: height,
-		seq:    seq,
+		height:   height,
+		seq:      seq,
+		msgCount: msgCount,
 	}, nil
-
 }
 
 type btp2 struct {
+	*store
 	l           log.Logger
 	src         link.ChainConfig
 	dst         types.BtpAddress
 	c           *client.Client
-	db          *leveldb.DB
+	sub         *client.Subscription
+	cache       *cache.Cache
 	nid         int64
 	rsc         chan interface{}
-	rss         []*receiveStatus
-	seq         int64
+	rss         *link.ReceiveStatusList
+	seq         int64              // owned by the monitor goroutine
+	skipped     link.ReceiveStatus // owned by the monitor goroutine
+	seqOffset   int64
 	startHeight int64
-}
-
-func newBTP2(src link.ChainConfig, dst types.BtpAddress, endpoint string, baseDir string, l log.Logger) (*btp2, error) {
+	ntid        int64
+	nt          *ntm.NetworkType
+	v           *verifier
+	accMtx      sync.Mutex // guards acc against the monitor and the link
+	acc         *mta.ExtAccumulator
+	opt         struct {
+		// SkipEmptyBlock doesn't notify BTP blocks without messages and
+		// proof context change, so they are relayed with the next block.
+		SkipEmptyBlock bool `json:"skip_empty_block"`
+		// Polling gets BTP blocks by HTTP JSON-RPC instead of websocket,
+		// for endpoints which don't support websocket.
+		Polling bool
+		// Cache keeps BTP blocks from the endpoint, in the database if
+		// Cache.Persist is set.
+		Cache cache.Options
+		// Window pauses monitoring while statuses pending for
+		// finalization exceed MaxCount or MaxBytes.
+		Window link.WindowConfig
+		// retries and the rate limit of JSON-RPC requests
+		jsonrpc.Options
+	}
+}
+
+func newBTP2(src link.ChainConfig, dst types.BtpAddress, endpoints []string, database db.Database,
+	l log.Logger, opt map[string]interface{}) (_ *btp2, err error) {
 	c := &btp2{
 		src: src,
 		dst: dst,
 		l:   l,
 		rsc: make(chan interface{}),
-		rss: make([]*receiveStatus, 0),
-	}
-	c.c = client.NewClient(endpoint, l)
-	err := c.prepareDatabase(baseDir)
-	if err != nil {
-		return nil, err
-	}
-	return c, nil
-}
-
-func (b *btp2) getFirstHeightForReceiveBlock() int64 {
-	iter := b.db.NewIterator(util.BytesPrefix([]byte(ReceiveBlockPrefix)), nil)
-	if iter.First() {
-		return new(big.Int).SetBytes(iter.Key()[len([]byte(ReceiveBlockPrefix)):]).Int64()
-	}
-	return 0
-}
-
-func (b *btp2) addReceiveBlock(height int64, data []byte) error {
-	h := big.NewInt(height)
-	key := append([]byte(ReceiveBlockPrefix), h.Bytes()...)
-	return b.db.Put(key, data, nil)
-}
-
-func (b *btp2) removeAllReceiveBlock() error {
-	b.l.Debugf("removeAllReceiveBlock")
-	iter := b.db.NewIterator(util.BytesPrefix([]byte(ReceiveBlockPrefix)), nil)
-	if iter.Next() {
-		b.l.Debugf("Delete height stored in database (height : %d)",
-			new(big.Int).SetBytes(iter.Key()[len([]byte(ReceiveBlockPrefix)):]).Int64())
-		if err := b.db.Delete(iter.Key(), nil); err != nil {
-			return err
-		}
 	}
-	return nil
-}
-
-func (b *btp2) removeReceiveBlockByHeight(height int64) error {
-	b.l.Debugf("removeReceiveBlockByHeight (height : %d) ", height)
-	h := big.NewInt(height)
-	key := append([]byte(ReceiveBlockPrefix), h.Bytes()...)
-	iter := b.db.NewIterator(util.BytesPrefix([]byte(ReceiveBlockPrefix)), nil)
-	if iter.Seek(key) {
-		for iter.Prev() {
-			b.l.Debugf("Delete height stored in database (height : %d)",
-				new(big.Int).SetBytes(iter.Key()[len([]byte(ReceiveBlockPrefix)):]).Int64())
-			if err := b.db.Delete(iter.Key(), nil); err != nil {
-				return err
+	defer func() {
+		if err != nil {
+			if c.c != nil {
+				c.c.Close()
 			}
+			database.Close()
 		}
+	}()
+	b, err := json.Marshal(opt)
+	if err != nil {
+		return nil, errors.IllegalArgumentError.Wrapf(err, "fail to marshal opt:%#v", opt)
 	}
-	return b.db.Delete(key, nil)
-	return nil
-}
-
-func (b *btp2) setLastReceiveHeight(height int64) error {
-	bytesArray := big.NewInt(height).Bytes()
-	return b.db.Put([]byte("LastReceiveHeight"), bytesArray, nil)
-}
-
-func (b *btp2) getLastReceiveHeight() (int64, error) {
-	if has, err := b.db.Has([]byte("LastReceiveHeight"), nil); err != nil {
-		return 0, err
-	} else if has {
-		bytesArray, err := b.db.Get([]byte("LastReceiveHeight"), nil)
-		if err != nil {
-			return 0, err
-		}
-		return new(big.Int).SetBytes(bytesArray).Int64(), nil
+	if err = json.Unmarshal(b, &c.opt); err != nil {
+		return nil, errors.IllegalArgumentError.Wrapf(err, "fail to unmarshal opt:%#v", opt)
 	}
-	return 0, nil
-}
-
-func (b *btp2) deleteAllDatabase() error {
-	b.l.Debugf("deleteAllDatabase")
-	iter := b.db.NewIterator(nil, nil)
-	for iter.Next() {
-		b.db.Delete(iter.Key(), nil)
+	c.rss = link.NewReceiveStatusListWithWindow(&c.opt.Window)
+	if c.c, err = client.NewClient(endpoints, &c.opt.Options, l); err != nil {
+		return nil, err
 	}
-	return nil
-}
-
-func (b *btp2) prepareDatabase(baseDir string) error {
-	var err error
-	dbDir := filepath.Join(baseDir, b.src.GetAddress().NetworkAddress())
-	b.l.Debugln("open database", dbDir)
-	b.db, err = leveldb.OpenFile(dbDir, nil)
-	if err != nil {
-		return errors.Wrap(err, "fail to open database")
+	c.sub = client.NewSubscription(c.c, &client.SubscriptionOptions{Polling: c.opt.Polling})
+	if c.store, err = newStore(database, l); err != nil {
+		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			b.db.Close()
-		}
-	}()
-	return nil
+	if c.cache, err = cache.NewWithOptions(&c.opt.Cache, database, CacheBucket); err != nil {
+		return nil, err
+	}
+	c.c.SetCache(c.cache)
+	return c, nil
 }
 
 func (b *btp2) getNetworkId() error {
@@ -208,8 +176,25 @@ func (b *btp2) Start(bls *types.BMCLinkStatus) (<-chan interface{}, error) {
 		return nil, err
 	}
 
+	// states are prepared before goroutines of the monitor and
+	// FinalizedStatus, and calls of the link for received statuses
+	vs := &client.VerifierStatus{}
+	if _, err := codec.RLP.UnmarshalFromBytes(bls.Verifier.Extra, vs); err != nil {
+		return nil, err
+	}
+	b.seqOffset = vs.SequenceOffset
+
+	height, err := b.prepareMonitoring(bls)
+	if err != nil {
+		return nil, err
+	}
+
 	go func() {
-		err := b.monitoring(bls)
+		err := b.monitoring(height)
+		if err == nil {
+			// stopped
+			return
+		}
 		b.l.Debugf("Unknown monitoring error occurred  (err : %v)", err)
 		b.rsc <- err
 	}()
@@ -218,17 +203,19 @@ func (b *btp2) Start(bls *types.BMCLinkStatus) (<-chan interface{}, error) {
 }
 
 func (b *btp2) Stop() {
+	b.rss.Close()
+	b.sub.Close()
 	close(b.rsc)
 }
 
 func (b *btp2) GetStatus() (link.ReceiveStatus, error) {
-	return b.rss[len(b.rss)-1], nil
+	return b.rss.Status()
 }
 
 func (b *btp2) GetHeightForSeq(seq int64) int64 {
 	rs := b.getReceiveStatusForSequence(seq)
 	if rs != nil {
-		return rs.height
+		return rs.Height()
 	} else {
 		return 0
 	}
@@ -237,37 +224,70 @@ func (b *btp2) GetHeightForSeq(seq int64) int64 {
 func (b *btp2) BuildBlockUpdate(bls *types.BMCLinkStatus, limit int64) ([]link.BlockUpdate, error) {
 	b.l.Debugf("Build BlockUpdate (height:%d, rxSeq:%d)", bls.Verifier.Height, bls.RxSeq)
 	bus := make([]link.BlockUpdate, 0)
-	rs := b.nextReceiveStatus(bls)
-	if rs == nil {
+	rss := b.rss.After(bls.Verifier.Height)
+	if len(rss) == 0 {
 		return nil, errors.IllegalArgumentError.New("No blockUpdate available to create.")
 	}
 
-	h, p, err := b.getBtpHeader(rs.Height())
-	if err != nil {
-		return nil, err
-	}
-	bh := &client.BTPBlockHeader{}
-	if _, err := codec.RLP.UnmarshalFromBytes(h, bh); err != nil {
-		return nil, err
-	}
-	bbu := &client.BTPBlockUpdate{BTPBlockHeader: h, BTPBlockProof: p}
+	cur := &types.BMCLinkStatus{}
+	*cur = *bls
+	var size int64
+	for _, rs := range rss {
+		bh, bbu, err := b.getBlockUpdate(rs.Height())
+		if err != nil {
+			return nil, err
+		}
+		buSize := int64(len(codec.RLP.MustMarshalToBytes(bbu)))
+		if limit < size+buSize {
+			break
+		}
+		size += buSize
 
-	if limit < int64(len(codec.RLP.MustMarshalToBytes(bbu))) {
-		return bus, nil
-	}
+		bus = append(bus, NewBlockUpdate(cur, bh.MainHeight, bbu))
+		cur.Verifier.Height = bh.MainHeight
 
-	bu := NewBlockUpdate(bls, bh.MainHeight, bbu)
-	bus = append(bus, bu)
+		if bh.MessageCount > 0 {
+			mt, err := b.getMessage(bh.MainHeight)
+			if err != nil {
+				return nil, err
+			}
+			size += int64(len(codec.RLP.MustMarshalToBytes(mt.ProofOfAll())))
+			if limit < size {
+				// remaining messages are delivered by following relay messages
+				break
+			}
+		}
+	}
 	return bus, nil
 }
 
-func (b *btp2) BuildBlockProof(bls *types.BMCLinkStatus, height int64) (link.BlockProof, error) {
-	return nil, nil
+func (b *btp2) getBlockUpdate(height int64) (*client.BTPBlockHeader, *client.BTPBlockUpdate, error) {
+	h, p, err := b.getBtpHeader(height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rh, err := b.getReceiveBlock(height); err != nil {
+		return nil, nil, err
+	} else if rh != nil && !bytes.Equal(rh, h) {
+		return nil, nil, errors.InvalidStateError.Errorf("header mismatch with received one (height:%d)", height)
+	}
+	bh := &client.BTPBlockHeader{}
+	if _, err := b.nt.HeaderCodec.UnmarshalFromBytes(h, bh); err != nil {
+		return nil, nil, err
+	}
+	v, err := b.verifier()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = v.VerifyProof(bh, p); err != nil {
+		return nil, nil, err
+	}
+	return bh, &client.BTPBlockUpdate{BTPBlockHeader: h, BTPBlockProof: p}, nil
 }
 
 func (b *btp2) BuildMessageProof(bls *types.BMCLinkStatus, limit int64) (link.MessageProof, error) {
 	b.l.Debugf("Build BuildMessageProof (height:%d, rxSeq:%d)", bls.Verifier.Height, bls.RxSeq)
-	rs := b.getReceiveStatusForHeight(bls.Verifier.Height)
+	rs := b.rss.ForHeight(bls.Verifier.Height)
 
 	if rs == nil {
 		return nil, nil
@@ -277,34 +297,54 @@ func (b *btp2) BuildMessageProof(bls *types.BMCLinkStatus, limit int64) (link.Me
 	if err != nil {
 		return nil, err
 	}
+	bh, err := b.getHeader(bls.Verifier.Height)
+	if err != nil {
+		return nil, err
+	}
+	v, err := b.verifier()
+	if err != nil {
+		return nil, err
+	}
+	if err = v.VerifyMessages(bh, mbt); err != nil {
+		return nil, err
+	}
 
 	messageCnt := int64(mbt.Len())
 	offset := bls.RxSeq - (rs.Seq() - messageCnt)
-	if (bls.RxSeq - rs.seq) == 0 {
+	if (bls.RxSeq - rs.Seq()) == 0 {
 		return nil, nil
 	}
-	if messageCnt > 0 {
-		for i := offset + 1; i < messageCnt; i++ {
-			p, err := mbt.Proof(int(offset+1), int(i))
-			if err != nil {
-				return nil, err
-			}
-
-			if limit < int64(len(codec.RLP.MustMarshalToBytes(p))) {
-				mp := NewMessageProof(bls, bls.RxSeq+i, *p)
-				return mp, nil
-			}
-		}
+	end, err := proofEndInLimit(mbt, int(offset+1), limit)
+	if err != nil {
+		return nil, err
 	}
-
-	p, err := mbt.Proof(int(offset+1), int(messageCnt))
+	p, err := mbt.Proof(int(offset+1), end)
 	if err != nil {
 		return nil, err
 	}
-	mp := NewMessageProof(bls, bls.RxSeq+messageCnt, *p)
+	mp := NewMessageProof(bls, bls.RxSeq+int64(end)-offset, *p)
 	return mp, nil
 }
 
+// proofEndInLimit returns the largest end of the proof from begin, whose
+// length doesn't exceed the limit. The proof has one message at least.
+func proofEndInLimit(mt *mbt.MerkleBinaryTree, begin int, limit int64) (int, error) {
+	var err error
+	n := sort.Search(mt.Len()-begin, func(i int) bool {
+		l, pErr := mt.ProofLength(begin, begin+i+1)
+		if pErr != nil {
+			err = pErr
+			return true
+		}
+		return int64(l) > limit
+	})
+	if err != nil {
+		return 0, err
+	}
+	// n is the number of messages after begin in the limit
+	return begin + n, nil
+}
+
 func (b *btp2) BuildRelayMessage(rmis []link.RelayMessageItem) ([]byte, error) {
 	bm := &BTPRelayMessage{
 		Messages: make([]*TypePrefixedMessage, 0),
@@ -333,33 +373,55 @@ func (b *btp2) FinalizedStatus(blsc <-chan *types.BMCLinkStatus) {
 		for {
 			select {
 			case bls := <-blsc:
-				b.removeReceiveBlockByHeight(bls.Verifier.Height)
-				b.clearReceiveStatus(bls)
+				if rss := b.clearReceiveStatus(bls); len(rss) > 0 {
+					if err := b.removeReceiveData(rss); err != nil {
+						b.l.Warnf("fail to remove receive data (height:%d, err:%+v)", bls.Verifier.Height, err)
+					}
+				}
+				if v, err := b.verifier(); err == nil {
+					v.Finalize(bls.Verifier.Height)
+				}
+				b.cache.RemoveBelow(b.lowestRequiredHeight(bls))
 			}
 		}
 	}()
 }
 
-func (b *btp2) nextReceiveStatus(bls *types.BMCLinkStatus) *receiveStatus {
-	for i, rs := range b.rss {
-		if bls.Verifier.Height <= rs.Height() {
-			if bls.Verifier.Height == rs.Height() {
-				return b.rss[i+1]
-			}
-			return b.rss[i]
-		}
+// lowestRequiredHeight returns the lowest height of BTP blocks which may
+// be required for relay messages.
+func (b *btp2) lowestRequiredHeight(bls *types.BMCLinkStatus) int64 {
+	height := bls.Verifier.Height
+	if rs := b.rss.First(); rs != nil && rs.Height() < height {
+		height = rs.Height()
 	}
-	return nil
+	return height
 }
 
-func (b *btp2) clearReceiveStatus(bls *types.BMCLinkStatus) {
-	for i, rs := range b.rss {
-		if rs.Height() <= bls.Verifier.Height && rs.Seq() <= bls.RxSeq {
-			b.l.Debugf("clear receive data (height:%d, seq:%d) ", bls.Verifier.Height, bls.RxSeq)
-			b.rss = b.rss[i+1:]
-			return
+// clearReceiveStatus removes the statuses delivered to the destination,
+// and returns the removed ones.
+func (b *btp2) clearReceiveStatus(bls *types.BMCLinkStatus) []link.ReceiveStatus {
+	removed := b.rss.RemoveDelivered(bls.Verifier.Height, bls.RxSeq+b.seqOffset)
+	if len(removed) > 0 {
+		b.l.Debugf("clear receive data (height:%d, seq:%d) ", bls.Verifier.Height, bls.RxSeq)
+	}
+	return removed
+}
+
+func (b *btp2) getHeader(height int64) (*client.BTPBlockHeader, error) {
+	h, err := b.getReceiveBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		if h, _, err = b.getBtpHeader(height); err != nil {
+			return nil, err
 		}
 	}
+	bh := &client.BTPBlockHeader{}
+	if _, err = b.nt.HeaderCodec.UnmarshalFromBytes(h, bh); err != nil {
+		return nil, err
+	}
+	return bh, nil
 }
 
 func (b *btp2) getMessage(height int64) (*mbt.MerkleBinaryTree, error) {
@@ -376,46 +438,42 @@ func (b *btp2) getMessage(height int64) (*mbt.MerkleBinaryTree, error) {
 		result = append(result, m)
 	}
 
-	mt, err := mbt.NewMerkleBinaryTree(mbt.HashFuncByUID("eth"), result)
+	mt, err := mbt.NewMerkleBinaryTree(b.nt.HashFunc, result)
 	if err != nil {
 		return nil, err
 	}
 	return mt, nil
 }
 
-func (b *btp2) monitoring(bls *types.BMCLinkStatus) error {
-	var height int64
-
+// prepareMonitoring restores receive statuses, the accumulator and the
+// verifier, and returns the height to monitor after.
+func (b *btp2) prepareMonitoring(bls *types.BMCLinkStatus) (int64, error) {
 	if bls.Verifier.Height < 1 {
-		return fmt.Errorf("cannot catchup from zero height")
+		return 0, fmt.Errorf("cannot catchup from zero height")
 	}
 
-	if bls.RxSeq < 1 {
-		if err := b.deleteAllDatabase(); err != nil {
-			return err
-		}
+	if bls.RxSeq != 0 {
+		b.seq = bls.RxSeq + b.seqOffset
 	}
 
-	lastHeight, err := b.getLastReceiveHeight()
+	height, err := b.restoreReceiveStatus(bls)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	fb := b.getFirstHeightForReceiveBlock()
-	if bls.Verifier.Height > lastHeight {
-		b.deleteAllDatabase()
-		height = bls.Verifier.Height
-	} else {
-		if fb != 0 && bls.Verifier.Height > fb {
-			height = bls.Verifier.Height
-		} else if fb > bls.Verifier.Height {
-			height = fb - 1
-		} else {
-			height = lastHeight
-		}
-		if err := b.removeAllReceiveBlock(); err != nil {
-			return err
-		}
+	if err = b.prepareAccumulator(bls); err != nil {
+		return 0, err
+	}
+
+	if err = b.prepareVerifier(bls); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+func (b *btp2) monitoring(height int64) error {
+	for _, rs := range b.rss.All() {
+		b.rsc <- rs
 	}
 
 	req := &client.BTPRequest{
@@ -425,102 +483,109 @@ func (b *btp2) monitoring(bls *types.BMCLinkStatus) error {
 		ProgressInterval: client.NewHexInt(int64(DefaultProgressInterval)),
 	}
 
-	onErr := func(conn *websocket.Conn, err error) {
-		b.l.Debugf("onError %s err:%+v", conn.LocalAddr().String(), err)
-		b.c.CloseMonitor(conn)
-	}
 	onConn := func(conn *websocket.Conn) {
-		b.l.Debugf("ReceiveLoop monitorBTP2Block height:%d seq:%d networkId:%d connected %s",
-			height, bls.RxSeq, b.nid, conn.LocalAddr().String())
+		b.l.Debugf("ReceiveLoop monitorBTP2Block seq:%d networkId:%d connected %s",
+			b.seq, b.nid, conn.LocalAddr().String())
 	}
+	return b.monitorBTP2Block(req, onConn)
+}
+
+func (b *btp2) monitorBTP2Block(req *client.BTPRequest, scb func(conn *websocket.Conn)) error {
+	return b.sub.MonitorBTP(req, b.handleBTPNotification, scb)
+}
 
-	err = b.monitorBTP2Block(req, bls, onConn, onErr)
+func (b *btp2) handleBTPNotification(v *client.BTPNotification) error {
+	h, err := base64.StdEncoding.DecodeString(v.Header)
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func (b *btp2) monitorBTP2Block(req *client.BTPRequest, bls *types.BMCLinkStatus, scb func(conn *websocket.Conn), errCb func(*websocket.Conn, error)) error {
-	vs := &client.VerifierStatus{}
-	_, err := codec.RLP.UnmarshalFromBytes(bls.Verifier.Extra, vs)
+	if v.Progress.Value != 0 {
+		if err := b.setLastReceiveHeight(v.Progress.Value); err != nil {
+			return err
+		}
+	}
+
+	if len(v.Header) == 0 {
+		return nil
+	}
+
+	bh := &client.BTPBlockHeader{}
+	if _, err = b.nt.HeaderCodec.UnmarshalFromBytes(h, bh); err != nil {
+		return err
+	}
+
+	bv, err := b.verifier()
 	if err != nil {
 		return err
 	}
+	if err = bv.VerifyHeader(bh); err != nil {
+		return err
+	}
 
-	if bls.RxSeq != 0 {
-		b.seq = bls.RxSeq + vs.SequenceOffset
+	if bh.MainHeight == b.startHeight {
+		return nil
 	}
 
-	return b.c.MonitorBTP(req, func(conn *websocket.Conn, v *client.BTPNotification) error {
-		h, err := base64.StdEncoding.DecodeString(v.Header)
+	if bh.MessageCount != 0 {
+		mt, err := b.getMessage(bh.MainHeight)
 		if err != nil {
 			return err
 		}
-
-		if v.Progress.Value != 0 {
-			if err := b.setLastReceiveHeight(v.Progress.Value); err != nil {
-				return err
-			}
-		}
-
-		if len(v.Header) == 0 {
-			return nil
-		}
-
-		bh := &client.BTPBlockHeader{}
-		if _, err = codec.RLP.UnmarshalFromBytes(h, bh); err != nil {
+		if err = bv.VerifyMessages(bh, mt); err != nil {
 			return err
 		}
 
-		if bh.MainHeight != b.startHeight {
-
-			if bh.MessageCount != 0 {
-				msgs, err := b.c.GetBTPMessage(bh.MainHeight, b.nid)
-				if err != nil {
-					return err
-				}
+		messageSN := bh.UpdateNumber >> 1
+		if messageSN != b.seq {
+			return fmt.Errorf("invalid seq (UpdateNumber:%d, Seq:%d)", bh.UpdateNumber, b.seq)
+		}
+		b.seq += int64(mt.Len())
+	}
 
-				messageSN := bh.UpdateNumber >> 1
-				if messageSN != b.seq {
-					return fmt.Errorf("invalid seq (UpdateNumber:%d, Seq:%d)", bh.UpdateNumber, b.seq)
-				}
-				b.seq += int64(len(msgs))
-			}
+	if err := b.addReceiveBlock(bh.MainHeight, h); err != nil {
+		return err
+	}
 
-			if err := b.addReceiveBlock(bh.MainHeight, h); err != nil {
-				return err
-			}
+	if err := b.accumulate(bh.MainHeight, h); err != nil {
+		return err
+	}
 
-			rs, err := newReceiveStatus(bh.MainHeight, b.seq)
-			if err != nil {
-				return err
-			}
-			b.rss = append(b.rss, rs)
-			b.l.Debugf("monitor info : Height:%d  UpdateNumber:%d  MessageCnt:%d  Seq:%d ", bh.MainHeight, bh.UpdateNumber, bh.MessageCount, b.seq)
-			b.rsc <- rs
+	rs, err := newReceiveStatus(bh.MainHeight, b.seq, bh.MessageCount)
+	if err != nil {
+		return err
+	}
+	if err = b.addReceiveStatus(rs); err != nil {
+		return err
+	}
+	if b.rss.Full(rs) {
+		b.l.Infof("pause monitoring until finalization (count:%d)", b.rss.Len())
+		// the link should know the last status to relay block updates for it
+		if b.skipped != nil {
+			b.rsc <- b.skipped
+			b.skipped = nil
 		}
+		b.rsc <- b.rss.WindowFull()
+	}
+	b.rss.Append(rs)
+	b.l.Debugf("monitor info : Height:%d  UpdateNumber:%d  MessageCnt:%d  Seq:%d ", bh.MainHeight, bh.UpdateNumber, bh.MessageCount, b.seq)
 
+	if b.opt.SkipEmptyBlock && bh.MessageCount == 0 && bh.UpdateNumber&1 == 0 {
+		// the header is relayed with the following block by BuildBlockUpdate,
+		// because the BMV requires all headers for PrevNetworkSectionHash
+		b.l.Debugf("skip relay of empty block (Height:%d UpdateNumber:%d)", bh.MainHeight, bh.UpdateNumber)
+		b.skipped = rs
 		return nil
-	}, scb, errCb)
-}
-
-func (b *btp2) getReceiveStatusForSequence(seq int64) *receiveStatus {
-	for _, rs := range b.rss {
-		if rs.Seq() <= seq && seq <= rs.Seq() {
-			return rs
-		}
 	}
+	b.skipped = nil
+	b.rsc <- rs
 	return nil
 }
 
-func (b *btp2) getReceiveStatusForHeight(height int64) *receiveStatus {
-	for _, rs := range b.rss {
-		if rs.Height() == height {
-			return rs
-		}
-	}
-	return nil
+func (b *btp2) getReceiveStatusForSequence(seq int64) link.ReceiveStatus {
+	return b.rss.Find(func(rs link.ReceiveStatus) bool {
+		return rs.Seq() == seq
+	})
 }
 
 func (b *btp2) setStartHeight() error {
@@ -530,6 +595,65 @@ func (b *btp2) setStartHeight() error {
 		return err
 	}
 	sh, err := ni.StartHeight.Value()
+	if err != nil {
+		return err
+	}
 	b.startHeight = sh + 1
+	if b.ntid, err = ni.NetworkTypeID.Value(); err != nil {
+		return err
+	}
+	if b.nt, err = ntm.ByName(ni.NetworkTypeName); err != nil {
+		return err
+	}
+	if b.nt.ProofFormat != ntm.ProofFormatSecp256k1 {
+		return errors.UnsupportedError.Errorf("UnsupportedProofFormat(type=%s,format=%s)",
+			b.nt.Name, b.nt.ProofFormat)
+	}
+	return nil
+}
+
+// verifier returns the verifier prepared by Start, and InvalidStateError
+// if the receiver is not started.
+func (b *btp2) verifier() (*verifier, error) {
+	if b.v == nil {
+		return nil, errors.InvalidStateError.New("NotStarted")
+	}
+	return b.v, nil
+}
+
+// prepareVerifier sets up the verifier with the proof context for the
+// headers after the verifier height of the destination. Headers of the
+// restored receive statuses are verified again to track proof contexts
+// changed by them, so that their proofs could be verified for relay.
+func (b *btp2) prepareVerifier(bls *types.BMCLinkStatus) error {
+	p := &client.BTPNetworkTypeInfoParam{
+		Height: client.NewHexInt(bls.Verifier.Height),
+		Id:     client.NewHexInt(b.ntid),
+	}
+	nti, err := b.c.GetBTPNetworkTypeInfo(p)
+	if err != nil {
+		return err
+	}
+	pc, err := nti.NextProofContext.Value()
+	if err != nil {
+		return err
+	}
+	v, err := newVerifier(b.src.GetAddress().NetworkAddress(), b.nt, b.ntid, bls.Verifier.Height, pc)
+	if err != nil {
+		return err
+	}
+	for _, rs := range b.rss.All() {
+		if rs.Height() <= bls.Verifier.Height {
+			continue
+		}
+		bh, err := b.getHeader(rs.Height())
+		if err != nil {
+			return err
+		}
+		if err = v.VerifyHeader(bh); err != nil {
+			return err
+		}
+	}
+	b.v = v
 	return nil
 }
@@ -0,0 +1,480 @@
+package btp2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/intconv"
+	"github.com/icon-project/btp2/common/jsonrpc"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/link/linktest"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/mbt"
+	"github.com/icon-project/btp2/common/ntm"
+	"github.com/icon-project/btp2/common/types"
+)
+
+func TestMain(m *testing.M) {
+	RegisterIconBtp2()
+	os.Exit(m.Run())
+}
+
+const (
+	testNetworkID     = 1
+	testNetworkTypeID = 1
+	// testStartHeight is the height of the first BTP block, which
+	// initializes the BMV.
+	testStartHeight = 10
+)
+
+var (
+	testSrc = types.BtpAddress("btp://0x1.icon/cx0000000000000000000000000000000000000001")
+	testDst = types.BtpAddress("btp://0x2.icon/cx0000000000000000000000000000000000000002")
+)
+
+func newTestValidators(nt *ntm.NetworkType, n int) ([]*crypto.PrivateKey, []byte) {
+	keys := make([]*crypto.PrivateKey, n)
+	pc := &secp256k1ProofContext{}
+	for i := range keys {
+		priv, pub := crypto.GenerateKeyPair()
+		keys[i] = priv
+		pc.Validators = append(pc.Validators, nt.HashFunc(pub.SerializeUncompressed()[1:])[12:])
+	}
+	return keys, codec.RLP.MustMarshalToBytes(pc)
+}
+
+type proofContextChange struct {
+	height int64
+	pc     []byte
+}
+
+// testChain produces BTP blocks signed by the validators, and serves them
+// as an ICON node.
+type testChain struct {
+	t    *testing.T
+	mtx  sync.Mutex
+	nt   *ntm.NetworkType
+	v    *verifier
+	keys []*crypto.PrivateKey
+	pc   []byte
+	// proof contexts changed at the heights, in order
+	pcs        []*proofContextChange
+	seq        int64
+	prevNSHash []byte
+	last       int64
+	headers    map[int64][]byte
+	proofs     map[int64][]byte
+	messages   map[int64][][]byte
+}
+
+func newTestChain(t *testing.T, validators int) *testChain {
+	nt, err := ntm.ByName("icon")
+	assert.NoError(t, err)
+	c := &testChain{
+		t:        t,
+		nt:       nt,
+		last:     testStartHeight,
+		headers:  make(map[int64][]byte),
+		proofs:   make(map[int64][]byte),
+		messages: make(map[int64][][]byte),
+	}
+	c.keys, c.pc = newTestValidators(nt, validators)
+	c.pcs = []*proofContextChange{{0, c.pc}}
+	c.v, err = newVerifier(testSrc.NetworkAddress(), nt, testNetworkTypeID, 0, c.pc)
+	assert.NoError(t, err)
+	return c
+}
+
+func testMessages(n int) [][]byte {
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = []byte(fmt.Sprintf("message-%d", i))
+	}
+	return msgs
+}
+
+// header returns the header following the last one. nextPC is the proof
+// context changed by the header, nil for no change.
+func (c *testChain) header(msgs [][]byte, nextPC []byte) *client.BTPBlockHeader {
+	bh := &client.BTPBlockHeader{
+		MainHeight:             c.last + 1,
+		NextProofContextHash:   c.nt.HashFunc(c.pc),
+		NetworkID:              testNetworkID,
+		UpdateNumber:           c.seq << 1,
+		PrevNetworkSectionHash: c.prevNSHash,
+		MessageCount:           int64(len(msgs)),
+	}
+	if nextPC != nil {
+		bh.UpdateNumber |= 1
+		bh.NextProofContext = nextPC
+		bh.NextProofContextHash = c.nt.HashFunc(nextPC)
+	}
+	if len(msgs) > 0 {
+		mt, err := mbt.NewMerkleBinaryTree(c.nt.HashFunc, msgs)
+		assert.NoError(c.t, err)
+		bh.MessagesRoot = mt.Root()
+	}
+	return bh
+}
+
+// sign returns the proof of the header signed by the first signers of keys.
+func (c *testChain) sign(bh *client.BTPBlockHeader, keys []*crypto.PrivateKey, signers int) []byte {
+	nsRoot, err := c.v.networkSectionsRoot(bh)
+	assert.NoError(c.t, err)
+	ntsdHash := c.v.hash(&networkTypeSectionDecision{
+		SrcNetworkID:  c.v.srcNetworkID,
+		NetworkTypeID: testNetworkTypeID,
+		Height:        bh.MainHeight,
+		Round:         bh.Round,
+		NetworkTypeSectionHash: c.v.hash(&networkTypeSection{
+			NextProofContextHash: bh.NextProofContextHash,
+			NetworkSectionsRoot:  nsRoot,
+		}),
+	})
+	p := &secp256k1Proof{Signatures: make([][]byte, len(keys))}
+	for i := 0; i < signers; i++ {
+		sig, err := crypto.NewSignature(ntsdHash, keys[i])
+		assert.NoError(c.t, err)
+		p.Signatures[i], err = sig.SerializeRSV()
+		assert.NoError(c.t, err)
+	}
+	return codec.RLP.MustMarshalToBytes(p)
+}
+
+// addBlock adds the BTP block with the messages, and returns the height.
+func (c *testChain) addBlock(msgs ...[]byte) int64 {
+	return c.addBlockWithProofContext(nil, nil, msgs...)
+}
+
+// addBlockWithProofContext adds the BTP block changing validators to keys
+// of nextPC if nextPC is not nil.
+func (c *testChain) addBlockWithProofContext(keys []*crypto.PrivateKey, nextPC []byte,
+	msgs ...[]byte) int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	bh := c.header(msgs, nextPC)
+	c.headers[bh.MainHeight] = codec.RLP.MustMarshalToBytes(bh)
+	c.proofs[bh.MainHeight] = c.sign(bh, c.keys, len(c.keys))
+	c.messages[bh.MainHeight] = msgs
+	c.seq += int64(len(msgs))
+	c.prevNSHash = c.v.networkSectionHash(bh)
+	c.last = bh.MainHeight
+	if nextPC != nil {
+		c.keys, c.pc = keys, nextPC
+		c.pcs = append(c.pcs, &proofContextChange{bh.MainHeight, nextPC})
+	}
+	return bh.MainHeight
+}
+
+func (c *testChain) handle(method string, params json.RawMessage) (interface{}, *jsonrpc.Error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	switch method {
+	case "icx_getLastBlock":
+		// the proof of the last BTP block is finalized by the next block
+		return &client.Block{Height: c.last + 1}, nil
+	case "icx_call":
+		return client.NewHexInt(testNetworkID), nil
+	case "btp_getNetworkInfo":
+		return &client.BTPNetworkInfo{
+			StartHeight:     client.NewHexInt(testStartHeight - 1),
+			NetworkTypeID:   client.NewHexInt(testNetworkTypeID),
+			NetworkID:       client.NewHexInt(testNetworkID),
+			NetworkTypeName: c.nt.Name,
+		}, nil
+	case "btp_getNetworkTypeInfo":
+		p := &client.BTPNetworkTypeInfoParam{}
+		if err := json.Unmarshal(params, p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		// the proof context for the headers after the height
+		pc := c.pc
+		if height, err := p.Height.Value(); err == nil {
+			for _, pcc := range c.pcs {
+				if pcc.height <= height {
+					pc = pcc.pc
+				}
+			}
+		}
+		return &client.BTPNetworkTypeInfo{
+			NetworkTypeName:  c.nt.Name,
+			NetworkTypeID:    client.NewHexInt(testNetworkTypeID),
+			NextProofContext: client.NewHexBytes(pc),
+		}, nil
+	case "btp_getHeader", "btp_getProof", "btp_getMessages":
+		p := &client.BTPBlockParam{}
+		if err := json.Unmarshal(params, p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		height, _ := p.Height.Value()
+		if _, ok := c.headers[height]; !ok {
+			return nil, &jsonrpc.Error{Code: client.JsonrpcErrorCodeNotFound, Message: "NotFound"}
+		}
+		switch method {
+		case "btp_getHeader":
+			return base64.StdEncoding.EncodeToString(c.headers[height]), nil
+		case "btp_getProof":
+			return base64.StdEncoding.EncodeToString(c.proofs[height]), nil
+		default:
+			msgs := make([]string, 0)
+			for _, m := range c.messages[height] {
+				msgs = append(msgs, base64.StdEncoding.EncodeToString(m))
+			}
+			return msgs, nil
+		}
+	default:
+		return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeMethodNotFound, Message: method}
+	}
+}
+
+// newTestBTP2 returns the receiver polling the chain with the database.
+func newTestBTP2(t *testing.T, c *testChain, database db.Database, opt map[string]interface{}) *btp2 {
+	srv := linktest.ServeJSONRPC(t, c.handle)
+	if opt == nil {
+		opt = make(map[string]interface{})
+	}
+	opt["polling"] = true
+	cfg := chain.BaseConfig{Address: testSrc, Endpoint: srv.URL, Type: TYPE}
+	b, err := newBTP2(cfg, testDst, []string{srv.URL}, database, log.New(), opt)
+	assert.NoError(t, err)
+	return b
+}
+
+func testLinkStatus(height, rxSeq int64) *types.BMCLinkStatus {
+	bls := &types.BMCLinkStatus{RxSeq: rxSeq}
+	bls.Verifier.Height = height
+	bls.Verifier.Extra = codec.RLP.MustMarshalToBytes(&client.VerifierStatus{})
+	return bls
+}
+
+// receiveStatuses starts the receiver, and returns the statuses of n
+// blocks from the channel.
+func receiveStatuses(t *testing.T, b *btp2, bls *types.BMCLinkStatus, n int) []link.ReceiveStatus {
+	rsc, err := b.Start(bls)
+	assert.NoError(t, err)
+	rss := make([]link.ReceiveStatus, 0, n)
+	for len(rss) < n {
+		select {
+		case v := <-rsc:
+			rs, ok := v.(link.ReceiveStatus)
+			if !ok {
+				assert.FailNow(t, "unexpected notification", "%+v", v)
+			}
+			rss = append(rss, rs)
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timeout", "received %d of %d", len(rss), n)
+		}
+	}
+	return rss
+}
+
+func decodeMessageProof(t *testing.T, mp link.MessageProof) [][]byte {
+	p := &mbt.MerkleBinaryTreeProof{}
+	_, err := codec.RLP.UnmarshalFromBytes(mp.(*MessageProof).Payload(), p)
+	assert.NoError(t, err)
+	return p.Contents
+}
+
+func TestBTP2_BuildMessageProof(t *testing.T) {
+	c := newTestChain(t, 4)
+	msgs := testMessages(10)
+	height := c.addBlock(msgs...)
+	b := newTestBTP2(t, c, db.NewMapDB(), nil)
+	defer b.Stop()
+	receiveStatuses(t, b, testLinkStatus(testStartHeight, 0), 1)
+
+	// all messages in the limit
+	bls := testLinkStatus(height, 0)
+	mp, err := b.BuildMessageProof(bls, 1024*1024)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(msgs)), mp.LastSeqNum())
+	assert.Equal(t, msgs, decodeMessageProof(t, mp))
+
+	// messages split by the limit
+	mt, err := mbt.NewMerkleBinaryTree(c.nt.HashFunc, msgs)
+	assert.NoError(t, err)
+	limit, err := mt.ProofLength(1, 3)
+	assert.NoError(t, err)
+	proven := make([][]byte, 0)
+	for {
+		mp, err = b.BuildMessageProof(bls, int64(limit))
+		assert.NoError(t, err)
+		if mp == nil {
+			break
+		}
+		contents := decodeMessageProof(t, mp)
+		assert.NotEmpty(t, contents)
+		assert.Equal(t, mp.LastSeqNum()-bls.RxSeq, int64(len(contents)))
+		proven = append(proven, contents...)
+		assert.NoError(t, mp.UpdateBMCLinkStatus(bls))
+	}
+	assert.Equal(t, msgs, proven)
+	assert.Equal(t, int64(len(msgs)), bls.RxSeq)
+}
+
+func TestBTP2_SkipEmptyBlock(t *testing.T) {
+	c := newTestChain(t, 4)
+	first := c.addBlock(testMessages(2)...)
+	c.addBlock()
+	c.addBlock()
+	last := c.addBlock(testMessages(1)...)
+	b := newTestBTP2(t, c, db.NewMapDB(), map[string]interface{}{"skip_empty_block": true})
+	defer b.Stop()
+
+	rss := receiveStatuses(t, b, testLinkStatus(testStartHeight, 0), 2)
+	assert.Equal(t, first, rss[0].Height())
+	assert.Equal(t, last, rss[1].Height())
+	assert.Equal(t, int64(3), rss[1].Seq())
+
+	// empty blocks are kept for the block updates
+	assert.Equal(t, 4, b.rss.Len())
+	for h := first; h <= last; h++ {
+		bs, err := b.getReceiveBlock(h)
+		assert.NoError(t, err)
+		assert.Equal(t, c.headers[h], bs)
+		idx, err := b.getAccumulatorIndex(h)
+		assert.NoError(t, err)
+		assert.NotNil(t, idx)
+	}
+	bus, err := b.BuildBlockUpdate(testLinkStatus(first, 2), 1024*1024)
+	assert.NoError(t, err)
+	assert.Len(t, bus, 3)
+	for i, bu := range bus {
+		assert.Equal(t, first+int64(i)+1, bu.TargetHeight())
+	}
+}
+
+// TestBTP2_Restart resends the receive statuses persisted before restart,
+// which are verified with the proof contexts from the verifier height.
+func TestBTP2_Restart(t *testing.T) {
+	c := newTestChain(t, 4)
+	first := c.addBlock(testMessages(1)...)
+	keys, pc := newTestValidators(c.nt, 4)
+	c.addBlockWithProofContext(keys, pc, testMessages(1)...)
+	last := c.addBlock(testMessages(2)...)
+
+	dir := t.TempDir()
+	bls := testLinkStatus(testStartHeight, 0)
+	b := newTestBTP2(t, c, linktest.NewDatabase(t, dir), nil)
+	receiveStatuses(t, b, bls, 3)
+	b.Stop()
+	assert.NoError(t, b.store.Close())
+
+	// the destination didn't receive any of them
+	b = newTestBTP2(t, c, linktest.NewDatabase(t, dir), nil)
+	defer func() {
+		b.Stop()
+		b.store.Close()
+	}()
+	rss := receiveStatuses(t, b, bls, 3)
+	for i, rs := range rss {
+		assert.Equal(t, first+int64(i), rs.Height())
+	}
+	bus, err := b.BuildBlockUpdate(bls, 1024*1024)
+	assert.NoError(t, err)
+	if assert.Len(t, bus, 3) {
+		assert.Equal(t, last, bus[2].TargetHeight())
+	}
+}
+
+func TestBTP2_InvalidOption(t *testing.T) {
+	cfg := chain.BaseConfig{Address: testSrc, Type: TYPE}
+	_, err := newBTP2(cfg, testDst, []string{"http://localhost"}, db.NewMapDB(), log.New(),
+		map[string]interface{}{"skip_empty_block": "yes"})
+	assert.Error(t, err)
+
+	// the database is closed on errors
+	database := linktest.NewDatabase(t, t.TempDir())
+	bk, err := database.GetBucket(db.SchemaBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, bk.Set([]byte("Version"), intconv.Int64ToBytes(3)))
+	_, err = newBTP2(cfg, testDst, []string{"http://localhost"}, database, log.New(), nil)
+	assert.True(t, errors.UnsupportedError.Equals(err), "err:%+v", err)
+	assert.Error(t, database.Close(), "already closed")
+}
+
+// TestBTP2_Race relays blocks like the link, while the monitor receives
+// following blocks and finalized statuses are handled.
+func TestBTP2_Race(t *testing.T) {
+	const blocks = 20
+	c := newTestChain(t, 4)
+	b := newTestBTP2(t, c, db.NewMapDB(), nil)
+	defer b.Stop()
+	bls := testLinkStatus(testStartHeight, 0)
+	rsc, err := b.Start(bls)
+	assert.NoError(t, err)
+	blsc := make(chan *types.BMCLinkStatus)
+	b.FinalizedStatus(blsc)
+
+	go func() {
+		for i := 0; i < blocks; i++ {
+			c.addBlock(testMessages(i % 3)...)
+		}
+	}()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if rs, err := b.GetStatus(); err == nil {
+				b.GetHeightForSeq(rs.Seq())
+			}
+		}
+	}()
+
+	for received := 0; received < blocks; received++ {
+		var rs link.ReceiveStatus
+		select {
+		case v := <-rsc:
+			var ok bool
+			if rs, ok = v.(link.ReceiveStatus); !ok {
+				assert.FailNow(t, "unexpected notification", "%+v", v)
+			}
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timeout", "received %d of %d", received, blocks)
+		}
+		for bls.Verifier.Height < rs.Height() {
+			bus, err := b.BuildBlockUpdate(bls, 1024*1024)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, bus)
+			assert.NoError(t, bus[0].UpdateBMCLinkStatus(bls))
+			for {
+				mp, err := b.BuildMessageProof(bls, 1024*1024)
+				assert.NoError(t, err)
+				if mp == nil {
+					break
+				}
+				assert.NoError(t, mp.UpdateBMCLinkStatus(bls))
+			}
+		}
+		assert.Equal(t, rs.Seq(), bls.RxSeq)
+
+		// the accumulator of the BMV started from zero at the start height
+		if bls.Verifier.Height > testStartHeight+1 {
+			accBls := accumulatorLinkStatus(bls.Verifier.Height, bls.Verifier.Height-testStartHeight, 0)
+			bp, err := b.BuildBlockProof(accBls, bls.Verifier.Height-1)
+			assert.NoError(t, err)
+			assert.NotNil(t, bp)
+		}
+
+		finalized := *bls
+		blsc <- &finalized
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/icon-project/btp2/chain"
 	"github.com/icon-project/btp2/chain/icon"
+	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/link"
 	"github.com/icon-project/btp2/common/log"
 	"github.com/icon-project/btp2/common/types"
@@ -16,11 +17,13 @@ import (
 const TYPE = "icon-btpblock"
 
 func RegisterIconBtp2() {
+	db.RegisterSchema(TYPE, schemaMigrations...)
 	link.RegisterFactory(&link.Factory{
 		Type:             TYPE,
 		ParseChainConfig: ParseChainConfig,
 		NewReceiver:      NewReceiver,
 		NewSender:        NewSender,
+		OpenStore:        OpenStore,
 	})
 }
 
@@ -37,8 +40,25 @@ func ParseChainConfig(raw json.RawMessage) (link.ChainConfig, error) {
 
 func NewReceiver(srcCfg link.ChainConfig, dstAddr types.BtpAddress, baseDir string, l log.Logger) (link.Receiver, error) {
 	src := srcCfg.(chain.BaseConfig)
+	database, err := src.OpenDatabase(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return newBTP2(src, dstAddr, src.GetEndpoints(), database, l, src.Options)
+}
 
-	return newBTP2(src, dstAddr, src.Endpoint, baseDir, l)
+func OpenStore(srcCfg link.ChainConfig, baseDir string, l log.Logger) (link.Store, error) {
+	src := srcCfg.(chain.BaseConfig)
+	database, err := src.OpenDatabase(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newStore(database, l)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	return s, nil
 }
 
 func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, baseDir string, l log.Logger) (types.Sender, error) {
@@ -48,7 +68,7 @@ func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, baseDir string
 		return nil, err
 	}
 
-	return icon.NewSender(srcAddr, dst, w, dst.Endpoint, dst.Options, l), nil
+	return icon.NewSender(srcAddr, dst, w, dst.GetEndpoints(), dst.Options, l)
 }
 
 func newWallet(passwd, secret string, keyStorePath string) (types.Wallet, error) {
@@ -14,7 +14,6 @@ type BTPRelayMessage struct {
 
 type relayMessageItem struct {
 	it      link.MessageItemType
-	nextBls *types.BMCLinkStatus
 	payload []byte
 }
 
@@ -27,9 +26,6 @@ func (c *relayMessageItem) Len() int64 {
 }
 
 func (c *relayMessageItem) UpdateBMCLinkStatus(bls *types.BMCLinkStatus) error {
-	bls.Verifier.Height = c.nextBls.Verifier.Height
-	bls.RxSeq = c.nextBls.RxSeq
-	bls.TxSeq = c.nextBls.TxSeq
 	return nil
 }
 
@@ -46,6 +42,16 @@ func (c *blockProof) ProofHeight() int64 {
 	return c.ph
 }
 
+func NewBlockProof(height int64, v interface{}) *blockProof {
+	return &blockProof{
+		relayMessageItem: relayMessageItem{
+			it:      link.TypeBlockProof,
+			payload: codec.RLP.MustMarshalToBytes(v),
+		},
+		ph: height,
+	}
+}
+
 type blockUpdate struct {
 	blockProof
 	srcHeight    int64
@@ -60,11 +66,14 @@ func (c *blockUpdate) TargetHeight() int64 {
 	return c.targetHeight
 }
 
+// UpdateBMCLinkStatus only moves the verifier height, so that block updates
+// and message proofs could be chained in a relay message.
+func (c *blockUpdate) UpdateBMCLinkStatus(bls *types.BMCLinkStatus) error {
+	bls.Verifier.Height = c.targetHeight
+	return nil
+}
+
 func NewBlockUpdate(bs *types.BMCLinkStatus, targetHeight int64, v interface{}) *blockUpdate {
-	nextBls := &types.BMCLinkStatus{}
-	nextBls.Verifier.Height = targetHeight
-	nextBls.TxSeq = bs.TxSeq
-	nextBls.RxSeq = bs.RxSeq
 	return &blockUpdate{
 		srcHeight:    bs.Verifier.Height,
 		targetHeight: targetHeight,
@@ -72,7 +81,6 @@ func NewBlockUpdate(bs *types.BMCLinkStatus, targetHeight int64, v interface{})
 			relayMessageItem: relayMessageItem{
 				it:      link.TypeBlockUpdate,
 				payload: codec.RLP.MustMarshalToBytes(v),
-				nextBls: nextBls,
 			},
 			ph: targetHeight,
 		},
@@ -93,19 +101,19 @@ func (m *MessageProof) LastSeqNum() int64 {
 	return m.lastSeq
 }
 
-func NewMessageProof(bs *types.BMCLinkStatus, ls int64, v interface{}) *MessageProof {
-	nextBls := &types.BMCLinkStatus{}
-	nextBls.Verifier.Height = bs.Verifier.Height
-	nextBls.TxSeq = bs.TxSeq
-	nextBls.RxSeq = ls
+// UpdateBMCLinkStatus only moves the received sequence.
+func (m *MessageProof) UpdateBMCLinkStatus(bls *types.BMCLinkStatus) error {
+	bls.RxSeq = m.lastSeq
+	return nil
+}
 
+func NewMessageProof(bs *types.BMCLinkStatus, ls int64, v interface{}) *MessageProof {
 	return &MessageProof{
 		startSeq: bs.RxSeq,
 		lastSeq:  ls,
 		relayMessageItem: relayMessageItem{
 			it:      link.TypeMessageProof,
 			payload: codec.RLP.MustMarshalToBytes(v),
-			nextBls: nextBls,
 		},
 	}
 }
@@ -129,6 +137,10 @@ func NewTypePrefixedMessage(rmi link.RelayMessageItem) (*TypePrefixedMessage, er
 		bn := rmi.(*blockUpdate)
 		tpm.Type = RelayMessageTypeBlockUpdate
 		tpm.Payload = bn.Payload()
+	case link.TypeBlockProof:
+		bp := rmi.(*blockProof)
+		tpm.Type = RelayMessageTypeBlockProof
+		tpm.Payload = bp.Payload()
 	case link.TypeMessageProof:
 		mp := rmi.(*MessageProof)
 		tpm.Type = RelayMessageTypeMessageProof
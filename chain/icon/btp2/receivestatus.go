@@ -0,0 +1,119 @@
+package btp2
+
+import (
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/types"
+)
+
+// serializedReceiveStatus is stored with the height of previous one, so
+// that the statuses could be restored from the last one without scanning.
+type serializedReceiveStatus struct {
+	Height       int64
+	Seq          int64
+	MessageCount int64
+	PrevHeight   int64
+}
+
+func (s *store) addReceiveStatus(rs *receiveStatus) error {
+	prev, err := s.getHeightProperty(keyLastReceiveStatus)
+	if err != nil {
+		return err
+	}
+	srs := &serializedReceiveStatus{
+		Height:       rs.height,
+		Seq:          rs.seq,
+		MessageCount: rs.msgCount,
+		PrevHeight:   prev,
+	}
+	batch := s.db.NewBatch()
+	batch.Set(ReceiveStatusBucket, heightToKey(rs.height), codec.RLP.MustMarshalToBytes(srs))
+	batch.Set(PropertyBucket, []byte(keyLastReceiveStatus), heightToKey(rs.height))
+	return batch.Write()
+}
+
+func decodeReceiveStatus(bs []byte) (*serializedReceiveStatus, error) {
+	srs := &serializedReceiveStatus{}
+	if _, err := codec.RLP.UnmarshalFromBytes(bs, srs); err != nil {
+		return nil, err
+	}
+	return srs, nil
+}
+
+func (s *store) getReceiveStatus(height int64) (*serializedReceiveStatus, error) {
+	bs, err := s.statuses.Get(heightToKey(height))
+	if err != nil || bs == nil {
+		return nil, err
+	}
+	return decodeReceiveStatus(bs)
+}
+
+func (s *store) removeReceiveStatus(height int64) error {
+	return s.statuses.Delete(heightToKey(height))
+}
+
+// restoreReceiveStatus loads the receive statuses from the verifier height,
+// and returns the height to resume monitoring. Statuses before the verifier
+// height are removed.
+func (b *btp2) restoreReceiveStatus(bls *types.BMCLinkStatus) (int64, error) {
+	last, err := b.getHeightProperty(keyLastReceiveStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	rss := make([]*receiveStatus, 0)
+	for height := last; height > 0; {
+		s, err := b.getReceiveStatus(height)
+		if err != nil {
+			return 0, err
+		}
+		if s == nil {
+			break
+		}
+		if s.Height < bls.Verifier.Height {
+			if err = b.removeReceiveStatus(s.Height); err != nil {
+				return 0, err
+			}
+		} else {
+			rs, _ := newReceiveStatus(s.Height, s.Seq, s.MessageCount)
+			rss = append([]*receiveStatus{rs}, rss...)
+		}
+		height = s.PrevHeight
+	}
+
+	for i := 1; i < len(rss); i++ {
+		prev, rs := rss[i-1], rss[i]
+		if rs.seq-rs.msgCount != prev.seq {
+			return 0, errors.InvalidStateError.Errorf(
+				"discontinuous receive status (height:%d seq:%d count:%d, prev height:%d seq:%d)",
+				rs.height, rs.seq, rs.msgCount, prev.height, prev.seq)
+		}
+	}
+
+	height := bls.Verifier.Height
+	if len(rss) > 0 {
+		last := rss[len(rss)-1]
+		if last.seq < b.seq {
+			return 0, errors.InvalidStateError.Errorf(
+				"receive status behind the destination (height:%d seq:%d, rxSeq:%d)",
+				last.height, last.seq, bls.RxSeq)
+		}
+		b.seq = last.seq
+		height = last.height
+	}
+	lastHeight, err := b.getLastReceiveHeight()
+	if err != nil {
+		return 0, err
+	}
+	if lastHeight > height {
+		height = lastHeight
+	}
+	items := make([]link.ReceiveStatus, len(rss))
+	for i, rs := range rss {
+		items[i] = rs
+	}
+	b.rss.Reset(items)
+	b.l.Debugf("restore receive status (count:%d, resume height:%d, seq:%d)", len(rss), height, b.seq)
+	return height, nil
+}
@@ -0,0 +1,324 @@
+package btp2
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+const (
+	ReceiveBlockBucket     db.BucketID = "H|"
+	ReceiveStatusBucket    db.BucketID = "R|"
+	AccumulatorBucket      db.BucketID = "A|"
+	AccumulatorIndexBucket db.BucketID = "I|"
+	PropertyBucket         db.BucketID = "P|"
+	CacheBucket            db.BucketID = "K|"
+
+	// LegacyPropertyBucket is for the keys stored without bucket before
+	// schema version 1.
+	LegacyPropertyBucket db.BucketID = ""
+)
+
+const (
+	keyLastReceiveHeight = "LastReceiveHeight"
+	keyLastReceiveStatus = "LastReceiveStatus"
+)
+
+func heightToKey(height int64) []byte {
+	return big.NewInt(height).Bytes()
+}
+
+func heightFromKey(key []byte) int64 {
+	return new(big.Int).SetBytes(key).Int64()
+}
+
+var schemaMigrations = []*db.Migration{
+	{
+		Version:     1,
+		Description: "move LastReceiveHeight to the property bucket",
+		Migrate:     migrateLastReceiveHeight,
+	},
+	{
+		Version:     2,
+		Description: "key receive statuses by height bytes, linked to the previous one",
+		Migrate:     migrateReceiveStatusKeys,
+	},
+}
+
+func migrateLastReceiveHeight(database db.Database, batch db.Batch) error {
+	bk, err := database.GetBucket(LegacyPropertyBucket)
+	if err != nil {
+		return err
+	}
+	bs, err := bk.Get([]byte(keyLastReceiveHeight))
+	if err != nil || bs == nil {
+		return err
+	}
+	batch.Set(PropertyBucket, []byte(keyLastReceiveHeight), bs)
+	batch.Delete(LegacyPropertyBucket, []byte(keyLastReceiveHeight))
+	return nil
+}
+
+// legacyReceiveStatusKeyLen is the length of keys of receive statuses
+// before schema version 2, which are heights in fixed length.
+const legacyReceiveStatusKeyLen = 8
+
+type legacyReceiveStatus struct {
+	Height       int64
+	Seq          int64
+	MessageCount int64
+}
+
+func migrateReceiveStatusKeys(database db.Database, batch db.Batch) error {
+	bk, err := database.GetBucket(ReceiveStatusBucket)
+	if err != nil {
+		return err
+	}
+	it := bk.NewIterator(nil)
+	defer it.Release()
+	// legacy keys are iterated in order of height
+	var prev int64
+	for it.Next() {
+		if len(it.Key()) != legacyReceiveStatusKeyLen {
+			continue
+		}
+		lrs := &legacyReceiveStatus{}
+		if _, err = codec.RLP.UnmarshalFromBytes(it.Value(), lrs); err != nil {
+			return err
+		}
+		srs := &serializedReceiveStatus{
+			Height:       lrs.Height,
+			Seq:          lrs.Seq,
+			MessageCount: lrs.MessageCount,
+			PrevHeight:   prev,
+		}
+		batch.Delete(ReceiveStatusBucket, append([]byte{}, it.Key()...))
+		batch.Set(ReceiveStatusBucket, heightToKey(srs.Height), codec.RLP.MustMarshalToBytes(srs))
+		prev = srs.Height
+	}
+	if err = it.Error(); err != nil {
+		return err
+	}
+	if prev > 0 {
+		batch.Set(PropertyBucket, []byte(keyLastReceiveStatus), heightToKey(prev))
+	}
+	return nil
+}
+
+// store is the database of the receiver.
+type store struct {
+	db        db.Database
+	blocks    db.Bucket
+	statuses  db.Bucket
+	props     db.Bucket
+	accBucket db.Bucket
+	accIndex  db.Bucket
+}
+
+// newStore migrates the database to the latest schema, and returns the
+// store on it.
+func newStore(database db.Database, l log.Logger) (*store, error) {
+	version, applied, err := db.Migrate(database, TYPE, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range applied {
+		l.Infof("migrate database from version %d to %d (%s)", version, m.Version, m.Description)
+		version = m.Version
+	}
+	s := &store{db: database}
+	for _, bk := range []struct {
+		id  db.BucketID
+		ptr *db.Bucket
+	}{
+		{ReceiveBlockBucket, &s.blocks},
+		{ReceiveStatusBucket, &s.statuses},
+		{PropertyBucket, &s.props},
+		{AccumulatorBucket, &s.accBucket},
+		{AccumulatorIndexBucket, &s.accIndex},
+	} {
+		if *bk.ptr, err = database.GetBucket(bk.id); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+func (s *store) addReceiveBlock(height int64, data []byte) error {
+	return s.blocks.Set(heightToKey(height), data)
+}
+
+func (s *store) getReceiveBlock(height int64) ([]byte, error) {
+	return s.blocks.Get(heightToKey(height))
+}
+
+// removeReceiveData removes the blocks and the statuses at once.
+func (s *store) removeReceiveData(rss []link.ReceiveStatus) error {
+	batch := s.db.NewBatch()
+	for _, rs := range rss {
+		batch.Delete(ReceiveBlockBucket, heightToKey(rs.Height()))
+		batch.Delete(ReceiveStatusBucket, heightToKey(rs.Height()))
+	}
+	return batch.Write()
+}
+
+func (s *store) setHeightProperty(key string, height int64) error {
+	return s.props.Set([]byte(key), heightToKey(height))
+}
+
+func (s *store) getHeightProperty(key string) (int64, error) {
+	bs, err := s.props.Get([]byte(key))
+	if err != nil {
+		return 0, err
+	}
+	return new(big.Int).SetBytes(bs).Int64(), nil
+}
+
+func (s *store) setLastReceiveHeight(height int64) error {
+	return s.setHeightProperty(keyLastReceiveHeight, height)
+}
+
+func (s *store) getLastReceiveHeight() (int64, error) {
+	return s.getHeightProperty(keyLastReceiveHeight)
+}
+
+// forEach calls f for the entries of the bucket keyed by height, keys are
+// not ordered by height.
+func forEach(bk db.Bucket, f func(height int64, value []byte) error) error {
+	it := bk.NewIterator(nil)
+	defer it.Release()
+	for it.Next() {
+		if err := f(heightFromKey(it.Key()), append([]byte{}, it.Value()...)); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+type receiveStatusEntry struct {
+	MessageCount int64 `json:"message_count"`
+	PrevHeight   int64 `json:"prev_height"`
+}
+
+func (s *store) LastReceiveHeight() (int64, error) {
+	return s.getLastReceiveHeight()
+}
+
+func (s *store) Entries() ([]*link.StoreEntry, error) {
+	entries := make([]*link.StoreEntry, 0)
+	err := forEach(s.blocks, func(height int64, value []byte) error {
+		entries = append(entries, &link.StoreEntry{
+			Kind:   link.StoreEntryHeader,
+			Height: height,
+			Value:  common.HexBytes(value),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = forEach(s.statuses, func(height int64, value []byte) error {
+		srs, err := decodeReceiveStatus(value)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &link.StoreEntry{
+			Kind:   link.StoreEntryStatus,
+			Height: height,
+			Seq:    srs.Seq,
+			Value: &receiveStatusEntry{
+				MessageCount: srs.MessageCount,
+				PrevHeight:   srs.PrevHeight,
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Height < entries[j].Height
+	})
+	return entries, nil
+}
+
+// Reset removes the headers and the statuses after the height. The
+// accumulator is kept, because the headers are not changed once finalized.
+func (s *store) Reset(height int64) error {
+	if height < 0 {
+		return errors.IllegalArgumentError.Errorf("invalid height %d", height)
+	}
+	batch := s.db.NewBatch()
+	err := forEach(s.blocks, func(h int64, value []byte) error {
+		if h > height {
+			batch.Delete(ReceiveBlockBucket, heightToKey(h))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var last int64
+	err = forEach(s.statuses, func(h int64, value []byte) error {
+		if h > height {
+			batch.Delete(ReceiveStatusBucket, heightToKey(h))
+		} else if h > last {
+			last = h
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if last > 0 {
+		batch.Set(PropertyBucket, []byte(keyLastReceiveStatus), heightToKey(last))
+	} else {
+		batch.Delete(PropertyBucket, []byte(keyLastReceiveStatus))
+	}
+	lastHeight, err := s.getLastReceiveHeight()
+	if err != nil {
+		return err
+	}
+	if lastHeight > height {
+		batch.Set(PropertyBucket, []byte(keyLastReceiveHeight), heightToKey(height))
+	}
+	return batch.Write()
+}
+
+// Verify checks the last receive status is not behind the destination,
+// which is rejected by restoreReceiveStatus on start.
+func (s *store) Verify(bls *types.BMCLinkStatus) error {
+	vs := &client.VerifierStatus{}
+	if _, err := codec.RLP.UnmarshalFromBytes(bls.Verifier.Extra, vs); err != nil {
+		return err
+	}
+	last, err := s.getHeightProperty(keyLastReceiveStatus)
+	if err != nil || last < bls.Verifier.Height {
+		return err
+	}
+	srs, err := s.getReceiveStatus(last)
+	if err != nil || srs == nil {
+		return err
+	}
+	if bls.RxSeq != 0 && srs.Seq < bls.RxSeq+vs.SequenceOffset {
+		return errors.InvalidStateError.Errorf(
+			"receive status behind the destination (height:%d seq:%d, rxSeq:%d)",
+			srs.Height, srs.Seq, bls.RxSeq)
+	}
+	return nil
+}
+
+func (s *store) Compact() error {
+	return db.Compact(s.db)
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,261 @@
+package btp2
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/intconv"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/link/linktest"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+func newTestStore(t *testing.T, database db.Database) *store {
+	s, err := newStore(database, log.New())
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+// setLegacyReceiveStatus writes the receive status as before schema
+// version 2.
+func setLegacyReceiveStatus(t *testing.T, database db.Database, height, seq, msgCount int64) {
+	bk, err := database.GetBucket(ReceiveStatusBucket)
+	assert.NoError(t, err)
+	key := make([]byte, legacyReceiveStatusKeyLen)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	value := codec.RLP.MustMarshalToBytes(&legacyReceiveStatus{
+		Height:       height,
+		Seq:          seq,
+		MessageCount: msgCount,
+	})
+	assert.NoError(t, bk.Set(key, value))
+}
+
+func TestStore_MigrateLastReceiveHeight(t *testing.T) {
+	database := linktest.NewDatabase(t, t.TempDir())
+	legacy, err := database.GetBucket(LegacyPropertyBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Set([]byte(keyLastReceiveHeight), heightToKey(100)))
+
+	s := newTestStore(t, database)
+	version, err := db.GetSchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+	height, err := s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), height)
+	assert.Nil(t, linktest.BucketKeys(t, database, LegacyPropertyBucket))
+	assert.Equal(t, [][]byte{[]byte(keyLastReceiveHeight)}, linktest.BucketKeys(t, database, PropertyBucket))
+}
+
+func TestStore_NewerSchema(t *testing.T) {
+	database := linktest.NewDatabase(t, t.TempDir())
+	defer database.Close()
+	bk, err := database.GetBucket(db.SchemaBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, bk.Set([]byte("Version"), intconv.Int64ToBytes(3)))
+	_, err = newStore(database, log.New())
+	assert.True(t, errors.UnsupportedError.Equals(err), "err:%+v", err)
+}
+
+func TestStore_MigrateReceiveStatusKeys(t *testing.T) {
+	database := linktest.NewDatabase(t, t.TempDir())
+	setLegacyReceiveStatus(t, database, 0x100, 2, 2)
+	setLegacyReceiveStatus(t, database, 20, 1, 1)
+	setLegacyReceiveStatus(t, database, 0x101, 5, 3)
+
+	s := newTestStore(t, database)
+	version, err := db.GetSchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	assert.Equal(t, [][]byte{{0x01, 0x00}, {0x01, 0x01}, {20}},
+		linktest.BucketKeys(t, database, ReceiveStatusBucket))
+	last, err := s.getHeightProperty(keyLastReceiveStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0x101), last)
+	for _, expected := range []*serializedReceiveStatus{
+		{Height: 20, Seq: 1, MessageCount: 1, PrevHeight: 0},
+		{Height: 0x100, Seq: 2, MessageCount: 2, PrevHeight: 20},
+		{Height: 0x101, Seq: 5, MessageCount: 3, PrevHeight: 0x100},
+	} {
+		srs, err := s.getReceiveStatus(expected.Height)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, srs)
+	}
+}
+
+func addReceiveStatus(t *testing.T, s *store, height, seq, msgCount int64) {
+	rs, _ := newReceiveStatus(height, seq, msgCount)
+	assert.NoError(t, s.addReceiveStatus(rs))
+}
+
+func TestStore_ReceiveStatus(t *testing.T) {
+	database := linktest.NewDatabase(t, t.TempDir())
+	s := newTestStore(t, database)
+	addReceiveStatus(t, s, 20, 1, 1)
+	addReceiveStatus(t, s, 0x100, 3, 2)
+	assert.NoError(t, s.addReceiveBlock(0x100, []byte("header")))
+
+	// keys are heights in bytes of big.Int
+	assert.Equal(t, [][]byte{{0x01, 0x00}, {20}}, linktest.BucketKeys(t, database, ReceiveStatusBucket))
+	assert.Equal(t, [][]byte{{0x01, 0x00}}, linktest.BucketKeys(t, database, ReceiveBlockBucket))
+	last, err := s.getHeightProperty(keyLastReceiveStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0x100), last)
+	srs, err := s.getReceiveStatus(0x100)
+	assert.NoError(t, err)
+	assert.Equal(t, &serializedReceiveStatus{Height: 0x100, Seq: 3, MessageCount: 2, PrevHeight: 20}, srs)
+	srs, err = s.getReceiveStatus(21)
+	assert.NoError(t, err)
+	assert.Nil(t, srs)
+
+	entries, err := s.Entries()
+	assert.NoError(t, err)
+	assert.Equal(t, []*link.StoreEntry{
+		{Kind: link.StoreEntryStatus, Height: 20, Seq: 1,
+			Value: &receiveStatusEntry{MessageCount: 1, PrevHeight: 0}},
+		{Kind: link.StoreEntryHeader, Height: 0x100, Value: common.HexBytes("header")},
+		{Kind: link.StoreEntryStatus, Height: 0x100, Seq: 3,
+			Value: &receiveStatusEntry{MessageCount: 2, PrevHeight: 20}},
+	}, entries)
+}
+
+func newTestRestoreBTP2(t *testing.T, s *store, bls *types.BMCLinkStatus) *btp2 {
+	return &btp2{
+		store: s,
+		l:     log.New(),
+		rss:   link.NewReceiveStatusList(),
+		seq:   bls.RxSeq,
+	}
+}
+
+func TestBTP2_RestoreReceiveStatus(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestStore(t, linktest.NewDatabase(t, dir))
+	addReceiveStatus(t, s, 20, 1, 1)
+	addReceiveStatus(t, s, 22, 3, 2)
+	addReceiveStatus(t, s, 25, 4, 1)
+	assert.NoError(t, s.setLastReceiveHeight(27))
+	assert.NoError(t, s.Close())
+
+	// statuses are restored from the reopened database
+	s = newTestStore(t, linktest.NewDatabase(t, dir))
+	bls := testLinkStatus(22, 1)
+	b := newTestRestoreBTP2(t, s, bls)
+	height, err := b.restoreReceiveStatus(bls)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(27), height, "resume from LastReceiveHeight")
+	assert.Equal(t, int64(4), b.seq)
+	var heights []int64
+	for _, rs := range b.rss.All() {
+		heights = append(heights, rs.Height())
+	}
+	assert.Equal(t, []int64{22, 25}, heights)
+
+	// statuses before the verifier height are removed
+	srs, err := s.getReceiveStatus(20)
+	assert.NoError(t, err)
+	assert.Nil(t, srs)
+	srs, err = s.getReceiveStatus(22)
+	assert.NoError(t, err)
+	assert.NotNil(t, srs)
+}
+
+func TestBTP2_RestoreReceiveStatusWithoutLastReceiveHeight(t *testing.T) {
+	s := newTestStore(t, linktest.NewDatabase(t, t.TempDir()))
+	addReceiveStatus(t, s, 20, 1, 1)
+	addReceiveStatus(t, s, 22, 3, 2)
+	bls := testLinkStatus(10, 0)
+	height, err := newTestRestoreBTP2(t, s, bls).restoreReceiveStatus(bls)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(22), height, "resume from the last status")
+
+	// without statuses, it resumes from the verifier height
+	s = newTestStore(t, linktest.NewDatabase(t, t.TempDir()))
+	height, err = newTestRestoreBTP2(t, s, bls).restoreReceiveStatus(bls)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), height)
+}
+
+func TestBTP2_RestoreInvalidReceiveStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rss   [][3]int64
+		rxSeq int64
+	}{
+		{"discontinuous", [][3]int64{{20, 1, 1}, {22, 4, 2}}, 0},
+		{"behind the destination", [][3]int64{{20, 1, 1}, {22, 3, 2}}, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestStore(t, linktest.NewDatabase(t, t.TempDir()))
+			for _, rs := range tc.rss {
+				addReceiveStatus(t, s, rs[0], rs[1], rs[2])
+			}
+			bls := testLinkStatus(10, tc.rxSeq)
+			_, err := newTestRestoreBTP2(t, s, bls).restoreReceiveStatus(bls)
+			assert.True(t, errors.InvalidStateError.Equals(err), "err:%+v", err)
+		})
+	}
+}
+
+func TestStore_Reset(t *testing.T) {
+	s := newTestStore(t, linktest.NewDatabase(t, t.TempDir()))
+	for i, h := range []int64{20, 22, 25} {
+		addReceiveStatus(t, s, h, int64(i+1), 1)
+		assert.NoError(t, s.addReceiveBlock(h, []byte("header")))
+	}
+	assert.NoError(t, s.setLastReceiveHeight(27))
+	assert.True(t, errors.IllegalArgumentError.Equals(s.Reset(-1)))
+
+	heights := func() (blocks, statuses []int64) {
+		entries, err := s.Entries()
+		assert.NoError(t, err)
+		for _, e := range entries {
+			if e.Kind == link.StoreEntryHeader {
+				blocks = append(blocks, e.Height)
+			} else {
+				statuses = append(statuses, e.Height)
+			}
+		}
+		return
+	}
+
+	assert.NoError(t, s.Reset(23))
+	blocks, statuses := heights()
+	assert.Equal(t, []int64{20, 22}, blocks)
+	assert.Equal(t, []int64{20, 22}, statuses)
+	last, err := s.getHeightProperty(keyLastReceiveStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(22), last)
+	height, err := s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(23), height)
+
+	// LastReceiveHeight is not raised
+	assert.NoError(t, s.Reset(30))
+	height, err = s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(23), height)
+
+	assert.NoError(t, s.Reset(10))
+	blocks, statuses = heights()
+	assert.Nil(t, blocks)
+	assert.Nil(t, statuses)
+	has, err := s.props.Has([]byte(keyLastReceiveStatus))
+	assert.NoError(t, err)
+	assert.False(t, has)
+	height, err = s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), height)
+}
@@ -0,0 +1,249 @@
+package btp2
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/mbt"
+	"github.com/icon-project/btp2/common/ntm"
+)
+
+type networkSection struct {
+	NetworkID    int64
+	UpdateNumber int64
+	PrevHash     []byte
+	MessageCount int64
+	MessagesRoot []byte
+}
+
+type networkTypeSection struct {
+	NextProofContextHash []byte
+	NetworkSectionsRoot  []byte
+}
+
+type networkTypeSectionDecision struct {
+	SrcNetworkID           []byte
+	NetworkTypeID          int64
+	Height                 int64
+	Round                  int32
+	NetworkTypeSectionHash []byte
+}
+
+type secp256k1Proof struct {
+	Signatures [][]byte
+}
+
+type secp256k1ProofContext struct {
+	Validators [][]byte
+}
+
+type proofContext struct {
+	height     int64
+	hash       []byte
+	validators [][]byte
+}
+
+func newProofContext(hashFunc mbt.HashFunc, height int64, b []byte) (*proofContext, error) {
+	pc := &secp256k1ProofContext{}
+	if _, err := codec.RLP.UnmarshalFromBytes(b, pc); err != nil {
+		return nil, errors.Wrapf(err, "fail to decode proof context height:%d", height)
+	}
+	return &proofContext{
+		height:     height,
+		hash:       hashFunc(b),
+		validators: pc.Validators,
+	}, nil
+}
+
+// verifier checks BTP block headers, proofs and messages fetched from the
+// node, so that invalid data is not relayed to the destination.
+type verifier struct {
+	mtx           sync.Mutex
+	hashFunc      mbt.HashFunc
+	srcNetworkID  []byte
+	networkTypeID int64
+	lastNSHash    []byte
+	// proof contexts ordered by height, the last one is for upcoming headers
+	pcs []*proofContext
+}
+
+func newVerifier(srcNetworkID string, nt *ntm.NetworkType, networkTypeID, height int64,
+	nextProofContext []byte) (*verifier, error) {
+	pc, err := newProofContext(nt.HashFunc, height, nextProofContext)
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{
+		hashFunc:      nt.HashFunc,
+		srcNetworkID:  []byte(srcNetworkID),
+		networkTypeID: networkTypeID,
+		pcs:           []*proofContext{pc},
+	}, nil
+}
+
+func (v *verifier) hash(o interface{}) []byte {
+	return v.hashFunc(codec.RLP.MustMarshalToBytes(o))
+}
+
+func (v *verifier) networkSectionHash(bh *client.BTPBlockHeader) []byte {
+	return v.hash(&networkSection{
+		NetworkID:    bh.NetworkID,
+		UpdateNumber: bh.UpdateNumber,
+		PrevHash:     bh.PrevNetworkSectionHash,
+		MessageCount: bh.MessageCount,
+		MessagesRoot: bh.MessagesRoot,
+	})
+}
+
+func (v *verifier) networkSectionsRoot(bh *client.BTPBlockHeader) ([]byte, error) {
+	h := v.networkSectionHash(bh)
+	for i, mn := range bh.NetworkSectionToRoot {
+		if len(mn) != 2 {
+			return nil, errors.Errorf("invalid NetworkSectionToRoot[%d]", i)
+		}
+		var dir client.Dir
+		if len(mn[0]) > 0 {
+			dir = client.Dir(mn[0][0])
+		}
+		switch dir {
+		case client.DirLeft:
+			h = v.hashFunc(mn[1], h)
+		case client.DirRight:
+			h = v.hashFunc(h, mn[1])
+		default:
+			return nil, errors.Errorf("invalid NetworkSectionToRoot[%d] dir:%d", i, dir)
+		}
+	}
+	return h, nil
+}
+
+func (v *verifier) proofContextFor(height int64) *proofContext {
+	for i := len(v.pcs) - 1; i >= 0; i-- {
+		if v.pcs[i].height < height {
+			return v.pcs[i]
+		}
+	}
+	return nil
+}
+
+// VerifyHeader checks the header is chained to the previous one, and tracks
+// the proof context changed by the header.
+func (v *verifier) VerifyHeader(bh *client.BTPBlockHeader) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	if v.lastNSHash != nil && !bytes.Equal(bh.PrevNetworkSectionHash, v.lastNSHash) {
+		return errors.InvalidStateError.Errorf(
+			"invalid PrevNetworkSectionHash height:%d expected:%x actual:%x",
+			bh.MainHeight, v.lastNSHash, bh.PrevNetworkSectionHash)
+	}
+	if bh.UpdateNumber&1 == 1 {
+		if !bytes.Equal(v.hashFunc(bh.NextProofContext), bh.NextProofContextHash) {
+			return errors.InvalidStateError.Errorf(
+				"invalid NextProofContext height:%d", bh.MainHeight)
+		}
+		pc, err := newProofContext(v.hashFunc, bh.MainHeight, bh.NextProofContext)
+		if err != nil {
+			return err
+		}
+		v.pcs = append(v.pcs, pc)
+	} else if pc := v.pcs[len(v.pcs)-1]; !bytes.Equal(pc.hash, bh.NextProofContextHash) {
+		return errors.InvalidStateError.Errorf(
+			"invalid NextProofContextHash height:%d expected:%x actual:%x",
+			bh.MainHeight, pc.hash, bh.NextProofContextHash)
+	}
+	v.lastNSHash = v.networkSectionHash(bh)
+	return nil
+}
+
+// VerifyProof checks the signatures of the proof with the validators of
+// the proof context for the header.
+func (v *verifier) VerifyProof(bh *client.BTPBlockHeader, proof []byte) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	pc := v.proofContextFor(bh.MainHeight)
+	if pc == nil {
+		return errors.InvalidStateError.Errorf("no proof context for height:%d", bh.MainHeight)
+	}
+	nsRoot, err := v.networkSectionsRoot(bh)
+	if err != nil {
+		return err
+	}
+	ntsHash := v.hash(&networkTypeSection{
+		NextProofContextHash: bh.NextProofContextHash,
+		NetworkSectionsRoot:  nsRoot,
+	})
+	ntsdHash := v.hash(&networkTypeSectionDecision{
+		SrcNetworkID:           v.srcNetworkID,
+		NetworkTypeID:          v.networkTypeID,
+		Height:                 bh.MainHeight,
+		Round:                  bh.Round,
+		NetworkTypeSectionHash: ntsHash,
+	})
+
+	p := &secp256k1Proof{}
+	if _, err = codec.RLP.UnmarshalFromBytes(proof, p); err != nil {
+		return errors.Wrapf(err, "fail to decode proof height:%d", bh.MainHeight)
+	}
+	signed := make([]bool, len(pc.validators))
+	count := 0
+	for _, sb := range p.Signatures {
+		if len(sb) == 0 {
+			continue
+		}
+		sig, err := crypto.ParseSignature(sb)
+		if err != nil {
+			continue
+		}
+		pk, err := sig.RecoverPublicKey(ntsdHash)
+		if err != nil {
+			continue
+		}
+		addr := v.hashFunc(pk.SerializeUncompressed()[1:])[12:]
+		for i, validator := range pc.validators {
+			if !signed[i] && bytes.Equal(addr, validator) {
+				signed[i] = true
+				count++
+				break
+			}
+		}
+	}
+	if count*3 <= len(pc.validators)*2 {
+		return errors.InvalidStateError.Errorf(
+			"insufficient signatures height:%d signed:%d validators:%d",
+			bh.MainHeight, count, len(pc.validators))
+	}
+	return nil
+}
+
+// VerifyMessages checks the messages against MessageCount and MessagesRoot
+// of the header.
+func (v *verifier) VerifyMessages(bh *client.BTPBlockHeader, mt *mbt.MerkleBinaryTree) error {
+	if int64(mt.Len()) != bh.MessageCount {
+		return errors.InvalidStateError.Errorf(
+			"invalid MessageCount height:%d expected:%d actual:%d",
+			bh.MainHeight, bh.MessageCount, mt.Len())
+	}
+	if !bytes.Equal(mt.Root(), bh.MessagesRoot) {
+		return errors.InvalidStateError.Errorf(
+			"invalid MessagesRoot height:%d expected:%x actual:%x",
+			bh.MainHeight, bh.MessagesRoot, mt.Root())
+	}
+	return nil
+}
+
+// Finalize drops the proof contexts which are not used for the headers
+// after the height.
+func (v *verifier) Finalize(height int64) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	for len(v.pcs) > 1 && v.pcs[1].height < height {
+		v.pcs = v.pcs[1:]
+	}
+}
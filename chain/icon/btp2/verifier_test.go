@@ -0,0 +1,214 @@
+package btp2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/mbt"
+)
+
+func newTestVerifier(t *testing.T, c *testChain) *verifier {
+	v, err := newVerifier(testSrc.NetworkAddress(), c.nt, testNetworkTypeID, 0, c.pc)
+	assert.NoError(t, err)
+	return v
+}
+
+func (c *testChain) blockHeader(height int64) *client.BTPBlockHeader {
+	bh := &client.BTPBlockHeader{}
+	_, err := codec.RLP.UnmarshalFromBytes(c.headers[height], bh)
+	assert.NoError(c.t, err)
+	return bh
+}
+
+func TestVerifier_VerifyHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(c *testChain, bh *client.BTPBlockHeader)
+		valid  bool
+	}{
+		{"valid", func(c *testChain, bh *client.BTPBlockHeader) {}, true},
+		{"invalid PrevNetworkSectionHash", func(c *testChain, bh *client.BTPBlockHeader) {
+			bh.PrevNetworkSectionHash = c.nt.HashFunc([]byte("invalid"))
+		}, false},
+		{"invalid NextProofContextHash", func(c *testChain, bh *client.BTPBlockHeader) {
+			bh.NextProofContextHash = c.nt.HashFunc([]byte("invalid"))
+		}, false},
+		{"valid proof context change", func(c *testChain, bh *client.BTPBlockHeader) {
+			_, pc := newTestValidators(c.nt, 4)
+			bh.UpdateNumber |= 1
+			bh.NextProofContext = pc
+			bh.NextProofContextHash = c.nt.HashFunc(pc)
+		}, true},
+		{"proof context mismatch with NextProofContextHash", func(c *testChain, bh *client.BTPBlockHeader) {
+			_, pc := newTestValidators(c.nt, 4)
+			bh.UpdateNumber |= 1
+			bh.NextProofContext = pc
+		}, false},
+		{"invalid proof context", func(c *testChain, bh *client.BTPBlockHeader) {
+			bh.UpdateNumber |= 1
+			bh.NextProofContext = []byte("invalid")
+			bh.NextProofContextHash = c.nt.HashFunc(bh.NextProofContext)
+		}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChain(t, 4)
+			v := newTestVerifier(t, c)
+			h := c.addBlock(testMessages(2)...)
+			assert.NoError(t, v.VerifyHeader(c.blockHeader(h)))
+
+			bh := c.header(testMessages(1), nil)
+			tc.modify(c, bh)
+			err := v.VerifyHeader(bh)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_VerifyProof(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		proof func(c *testChain, bh *client.BTPBlockHeader) []byte
+		valid bool
+	}{
+		{"all signed", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			return c.sign(bh, c.keys, 4)
+		}, true},
+		{"more than 2/3 signed", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			return c.sign(bh, c.keys, 3)
+		}, true},
+		{"2/3 signed", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			return c.sign(bh, c.keys, 2)
+		}, false},
+		{"no signature", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			return c.sign(bh, c.keys, 0)
+		}, false},
+		{"duplicated signatures", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			keys := []*crypto.PrivateKey{c.keys[0], c.keys[0], c.keys[1], c.keys[1]}
+			return c.sign(bh, keys, 4)
+		}, false},
+		{"signatures of unknown validators", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			keys, _ := newTestValidators(c.nt, 4)
+			keys = append(c.keys[:2:2], keys[2:]...)
+			return c.sign(bh, keys, 4)
+		}, false},
+		{"signatures for the other height", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			other := *bh
+			other.MainHeight++
+			return c.sign(&other, c.keys, 4)
+		}, false},
+		{"invalid proof", func(c *testChain, bh *client.BTPBlockHeader) []byte {
+			return []byte("invalid")
+		}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChain(t, 4)
+			v := newTestVerifier(t, c)
+			bh := c.header(testMessages(1), nil)
+			err := v.VerifyProof(bh, tc.proof(c, bh))
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_VerifyProofWithChangedValidators(t *testing.T) {
+	c := newTestChain(t, 4)
+	v := newTestVerifier(t, c)
+	oldKeys := c.keys
+	keys, pc := newTestValidators(c.nt, 3)
+	changed := c.addBlockWithProofContext(keys, pc)
+	assert.NoError(t, v.VerifyHeader(c.blockHeader(changed)))
+	assert.NoError(t, v.VerifyProof(c.blockHeader(changed), c.proofs[changed]))
+
+	// headers after the change are signed by new validators
+	h := c.addBlock(testMessages(1)...)
+	bh := c.blockHeader(h)
+	assert.NoError(t, v.VerifyHeader(bh))
+	assert.NoError(t, v.VerifyProof(bh, c.proofs[h]))
+	assert.NoError(t, v.VerifyProof(bh, c.sign(bh, keys, 3)))
+	assert.Error(t, v.VerifyProof(bh, c.sign(bh, keys, 2)))
+	assert.Error(t, v.VerifyProof(bh, c.sign(bh, oldKeys, 4)))
+}
+
+func TestVerifier_VerifyMessages(t *testing.T) {
+	c := newTestChain(t, 4)
+	v := newTestVerifier(t, c)
+	msgs := testMessages(3)
+	bh := c.header(msgs, nil)
+	for _, tc := range []struct {
+		name  string
+		msgs  [][]byte
+		valid bool
+	}{
+		{"valid", msgs, true},
+		{"missing message", msgs[:2], false},
+		{"modified message", [][]byte{msgs[0], msgs[1], []byte("modified")}, false},
+		{"reordered messages", [][]byte{msgs[1], msgs[0], msgs[2]}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt, err := mbt.NewMerkleBinaryTree(c.nt.HashFunc, tc.msgs)
+			assert.NoError(t, err)
+			err = v.VerifyMessages(bh, mt)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.InvalidStateError.Equals(err), "err:%+v", err)
+			}
+		})
+	}
+}
+
+func TestVerifier_Finalize(t *testing.T) {
+	c := newTestChain(t, 4)
+	v := newTestVerifier(t, c)
+	for i := 0; i < 2; i++ {
+		h := c.addBlock(testMessages(1)...)
+		assert.NoError(t, v.VerifyHeader(c.blockHeader(h)))
+		keys, pc := newTestValidators(c.nt, 4)
+		h = c.addBlockWithProofContext(keys, pc)
+		assert.NoError(t, v.VerifyHeader(c.blockHeader(h)))
+	}
+	assert.Len(t, v.pcs, 3)
+
+	for _, tc := range []struct {
+		height int64
+		pcs    int
+		// the lowest height verifiable after Finalize
+		lowest int64
+	}{
+		{testStartHeight + 1, 3, testStartHeight + 1},
+		{testStartHeight + 3, 2, testStartHeight + 3},
+		{testStartHeight + 4, 2, testStartHeight + 3},
+		{testStartHeight + 5, 1, testStartHeight + 5},
+		{testStartHeight + 10, 1, testStartHeight + 5},
+	} {
+		v.Finalize(tc.height)
+		assert.Len(t, v.pcs, tc.pcs, "height:%d", tc.height)
+		for h := int64(testStartHeight + 1); h <= c.last; h++ {
+			err := v.VerifyProof(c.blockHeader(h), c.proofs[h])
+			if h < tc.lowest {
+				assert.True(t, errors.InvalidStateError.Equals(err), "finalized:%d height:%d", tc.height, h)
+			} else {
+				assert.NoError(t, err, "finalized:%d height:%d", tc.height, h)
+			}
+		}
+	}
+}
+
+func TestBTP2_VerifierNotStarted(t *testing.T) {
+	b := &btp2{}
+	_, err := b.verifier()
+	assert.True(t, errors.InvalidStateError.Equals(err))
+}
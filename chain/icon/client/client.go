@@ -33,7 +33,10 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/cache"
+	"github.com/icon-project/btp2/common/capture"
 	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/endpoint"
 	"github.com/icon-project/btp2/common/errors"
 	"github.com/icon-project/btp2/common/jsonrpc"
 	"github.com/icon-project/btp2/common/log"
@@ -52,9 +55,57 @@ var (
 
 type Client struct {
 	*jsonrpc.Client
-	conns map[string]*websocket.Conn
-	l     log.Logger
-	mtx   sync.Mutex
+	pool    *endpoint.Pool
+	tr      *http.Transport
+	clients map[string]*jsonrpc.Client
+	conns   map[string]*websocket.Conn
+	l       log.Logger
+	mtx     sync.Mutex
+	rec     *capture.Recorder
+	cache   *cache.Cache
+}
+
+// kinds of cached data
+const (
+	cacheKindBTPHeader   = 'h'
+	cacheKindBTPProof    = 'p'
+	cacheKindBTPMessage  = 'm'
+	cacheKindBlockHeader = 'b'
+)
+
+// SetCache sets the cache for BTP blocks and block headers, which are
+// finalized on ICON.
+func (c *Client) SetCache(ch *cache.Cache) {
+	c.cache = ch
+}
+
+func btpCacheKey(kind byte, p *BTPBlockParam) []byte {
+	height, err := p.Height.Value()
+	if err != nil {
+		return nil
+	}
+	nid, err := p.NetworkId.Value()
+	if err != nil {
+		return nil
+	}
+	return cache.Key(height, kind, intconv.Int64ToBytes(nid))
+}
+
+// Do calls the method with the best endpoint, and fails over to others
+// on failures of the endpoint.
+func (c *Client) Do(method string, reqPtr, respPtr interface{}) (*jsonrpc.Response, error) {
+	var resp *jsonrpc.Response
+	err := c.pool.Do(func(url string) error {
+		var err error
+		resp, err = c.clients[url].Do(method, reqPtr, respPtr)
+		return err
+	})
+	return resp, err
+}
+
+// Endpoints returns the health of endpoints.
+func (c *Client) Endpoints() []*endpoint.Status {
+	return c.pool.Statuses()
 }
 
 type SendKeepaliveMessage struct {
@@ -215,6 +266,10 @@ func (c *Client) GetBTPMessage(height, nid int64) ([]string, error) {
 }
 
 func (c *Client) GetBTPHeader(p *BTPBlockParam) (string, error) {
+	key := btpCacheKey(cacheKindBTPHeader, p)
+	if bs := c.cache.Get(key); bs != nil {
+		return string(bs), nil
+	}
 	var header string
 	var retry = BlockRetryLimit
 	for {
@@ -233,12 +288,20 @@ func (c *Client) GetBTPHeader(p *BTPBlockParam) (string, error) {
 				}
 			}
 		} else {
+			c.cache.Put(key, []byte(header))
 			return header, nil
 		}
 	}
 }
 
 func (c *Client) getBTPMessage(p *BTPBlockParam) ([]string, error) {
+	key := btpCacheKey(cacheKindBTPMessage, p)
+	if bs := c.cache.Get(key); bs != nil {
+		var msgs []string
+		if err := json.Unmarshal(bs, &msgs); err == nil {
+			return msgs, nil
+		}
+	}
 	var result []string
 	var retry = BlockRetryLimit
 	for {
@@ -257,12 +320,19 @@ func (c *Client) getBTPMessage(p *BTPBlockParam) ([]string, error) {
 				}
 			}
 		} else {
+			if bs, err := json.Marshal(result); err == nil {
+				c.cache.Put(key, bs)
+			}
 			return result, nil
 		}
 	}
 }
 
 func (c *Client) GetBTPProof(p *BTPBlockParam) (string, error) {
+	key := btpCacheKey(cacheKindBTPProof, p)
+	if bs := c.cache.Get(key); bs != nil {
+		return string(bs), nil
+	}
 	var result string
 	var retry = BlockRetryLimit
 	for {
@@ -281,6 +351,7 @@ func (c *Client) GetBTPProof(p *BTPBlockParam) (string, error) {
 				}
 			}
 		} else {
+			c.cache.Put(key, []byte(result))
 			return result, nil
 		}
 	}
@@ -294,6 +365,14 @@ func (c *Client) GetBTPNetworkInfo(p *BTPNetworkInfoParam) (*BTPNetworkInfo, err
 	return result, nil
 }
 
+func (c *Client) GetBTPNetworkTypeInfo(p *BTPNetworkTypeInfoParam) (*BTPNetworkTypeInfo, error) {
+	result := &BTPNetworkTypeInfo{}
+	if _, err := c.Do("btp_getNetworkTypeInfo", p, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (c *Client) GetBlockByHeight(p *BlockHeightParam) (*Block, error) {
 	result := &Block{}
 	if _, err := c.Do("icx_getBlockByHeight", p, &result); err != nil {
@@ -302,11 +381,29 @@ func (c *Client) GetBlockByHeight(p *BlockHeightParam) (*Block, error) {
 	return result, nil
 }
 
+func (c *Client) GetLastBlock() (*Block, error) {
+	result := &Block{}
+	if _, err := c.Do("icx_getLastBlock", nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (c *Client) GetBlockHeaderByHeight(p *BlockHeightParam) ([]byte, error) {
+	var key []byte
+	if height, err := p.Height.Value(); err == nil {
+		key = cache.Key(height, cacheKindBlockHeader)
+		if bs := c.cache.Get(key); bs != nil {
+			return bs, nil
+		}
+	}
 	var result []byte
 	if _, err := c.Do("icx_getBlockHeaderByHeight", p, &result); err != nil {
 		return nil, err
 	}
+	if key != nil {
+		c.cache.Put(key, result)
+	}
 	return result, nil
 }
 func (c *Client) GetVotesByHeight(p *BlockHeightParam) ([]byte, error) {
@@ -489,6 +586,12 @@ func (c *Client) CloseAllMonitor() {
 	}
 }
 
+// Close closes monitors and idle connections of endpoints.
+func (c *Client) Close() {
+	c.CloseAllMonitor()
+	c.tr.CloseIdleConnections()
+}
+
 type wsReadCallback func(*websocket.Conn, interface{})
 
 func (c *Client) _addWsConn(conn *websocket.Conn) {
@@ -516,9 +619,14 @@ type wsConnectError struct {
 }
 
 func (c *Client) wsConnect(reqUrl string, reqHeader http.Header) (*websocket.Conn, error) {
-	wsEndpoint := strings.Replace(c.Endpoint, "http", "ws", 1)
+	return c.wsConnectTo(c.pool.Best(), reqUrl, reqHeader)
+}
+
+func (c *Client) wsConnectTo(url string, reqUrl string, reqHeader http.Header) (*websocket.Conn, error) {
+	wsEndpoint := strings.Replace(url, "http", "ws", 1)
 	conn, httpResp, err := websocket.DefaultDialer.Dial(wsEndpoint+reqUrl, reqHeader)
 	if err != nil {
+		c.pool.Report(url, 0, err)
 		wsErr := wsConnectError{error: err}
 		wsErr.httpResp = httpResp
 		return nil, wsErr
@@ -542,7 +650,22 @@ func (c *Client) wsRequest(conn *websocket.Conn, reqPtr interface{}) error {
 		return wsRequestError{fmt.Errorf("fail to WriteJSON err:%+v", err), nil}
 	}
 
-	if err = conn.ReadJSON(wsResp); err != nil {
+	if c.rec == nil {
+		err = conn.ReadJSON(wsResp)
+	} else {
+		var bs []byte
+		if _, bs, err = conn.ReadMessage(); err == nil {
+			req, _ := json.Marshal(reqPtr)
+			c.rec.Record(&capture.Record{
+				Kind:     capture.KindWS,
+				URL:      conn.RemoteAddr().String(),
+				Request:  req,
+				Response: bs,
+			})
+			err = json.Unmarshal(bs, wsResp)
+		}
+	}
+	if err != nil {
 		return wsRequestError{fmt.Errorf("fail to ReadJSON err:%+v", err), nil}
 	}
 
@@ -572,6 +695,18 @@ func (c *Client) wsRead(conn *websocket.Conn, respPtr interface{}) error {
 	if mt == websocket.CloseMessage {
 		return io.EOF
 	}
+	if c.rec != nil {
+		bs, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		c.rec.Record(&capture.Record{
+			Kind:     capture.KindWS,
+			URL:      conn.RemoteAddr().String(),
+			Response: bs,
+		})
+		return json.Unmarshal(bs, respPtr)
+	}
 	return json.NewDecoder(r).Decode(respPtr)
 }
 
@@ -630,19 +765,55 @@ func MapError(err error) error {
 	return err
 }
 
-func NewClient(uri string, l log.Logger) *Client {
-	//TODO options {MaxRetrySendTx, MaxRetryGetResult, MaxIdleConnsPerHost, Debug, Dump}
+func isEndpointFailure(err error) bool {
+	_, ok := err.(*jsonrpc.Error)
+	return !ok
+}
+
+// NewClient returns the client for endpoints, calls are routed to
+// the best endpoint by health checks with the last block. Each endpoint
+// has its own rate limit by opt. Requests are retried by opt, and recorded
+// for debugging with the capture of opt.
+func NewClient(uris []string, opt *jsonrpc.Options, l log.Logger) (*Client, error) {
 	tr := &http.Transport{MaxIdleConnsPerHost: 1000}
 	c := &Client{
-		Client: jsonrpc.NewJsonRpcClient(&http.Client{Transport: tr}, uri),
-		conns:  make(map[string]*websocket.Conn),
-		l:      l,
+		clients: make(map[string]*jsonrpc.Client),
+		conns:   make(map[string]*websocket.Conn),
+		l:       l,
+		tr:      tr,
+	}
+	pool, err := endpoint.NewPool(uris, func(url string) (int64, error) {
+		blk := &Block{}
+		if _, err := c.clients[url].Do("icx_getLastBlock", nil, blk); err != nil {
+			return 0, err
+		}
+		return blk.Height, nil
+	}, isEndpointFailure, l)
+	if err != nil {
+		return nil, err
+	}
+	c.pool = pool
+	if opt != nil && opt.Capture != nil && opt.Capture.Filename != "" {
+		if c.rec, err = capture.OpenRecorder(opt.Capture); err != nil {
+			return nil, err
+		}
 	}
 	opts := IconOptions{}
 	opts.SetBool(IconOptionsDebug, true)
-	c.CustomHeader[HeaderKeyIconOptions] = opts.ToHeaderValue()
-
-	return c
+	header := map[string]string{HeaderKeyIconOptions: opts.ToHeaderValue()}
+	for _, url := range pool.URLs() {
+		jc, err := jsonrpc.NewJsonRpcClientWithOptions(tr, url, opt)
+		if err != nil {
+			return nil, err
+		}
+		// share headers between endpoints
+		jc.CustomHeader = header
+		c.clients[url] = jc
+		if c.Client == nil {
+			c.Client = jc
+		}
+	}
+	return c, nil
 }
 
 const (
@@ -0,0 +1,368 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"encoding/base64"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/jsonrpc"
+)
+
+const (
+	DefaultReconnectMinBackoff = time.Second
+	DefaultReconnectMaxBackoff = time.Minute
+	DefaultReadTimeout         = 2 * time.Minute
+	DefaultPingInterval        = 30 * time.Second
+	DefaultWriteTimeout        = 10 * time.Second
+)
+
+type SubscriptionOptions struct {
+	// MinBackoff and MaxBackoff bound the delay before reconnecting,
+	// the delay doubles on each failure without progress.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// ReadTimeout is the read deadline, which is extended by
+	// any message or pong.
+	ReadTimeout  time.Duration
+	PingInterval time.Duration
+	// Polling uses HTTP JSON-RPC instead of websocket, it polls the last
+	// block for every PollingInterval.
+	Polling         bool
+	PollingInterval time.Duration
+}
+
+func (o *SubscriptionOptions) setDefaults() {
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = DefaultReconnectMinBackoff
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = DefaultReconnectMaxBackoff
+		if o.MaxBackoff < o.MinBackoff {
+			o.MaxBackoff = o.MinBackoff
+		}
+	}
+	if o.ReadTimeout <= 0 {
+		o.ReadTimeout = DefaultReadTimeout
+	}
+	if o.PingInterval <= 0 {
+		o.PingInterval = DefaultPingInterval
+	}
+	if o.PingInterval >= o.ReadTimeout {
+		o.PingInterval = o.ReadTimeout / 2
+	}
+	if o.PollingInterval <= 0 {
+		o.PollingInterval = DefaultGetBtpBlockInterval
+	}
+}
+
+// callbackError is the error returned by the callback, which stops
+// the subscription instead of reconnecting.
+type callbackError struct {
+	error
+}
+
+// Subscription keeps monitoring over websocket, or by polling with
+// HTTP JSON-RPC if the endpoint doesn't support websocket. On failures,
+// it reconnects with jittered backoff, and resumes from the next height
+// of the last processed one.
+type Subscription struct {
+	c      *Client
+	opt    SubscriptionOptions
+	mtx    sync.Mutex
+	conn   *websocket.Conn
+	closed bool
+	stop   chan struct{}
+}
+
+func NewSubscription(c *Client, opt *SubscriptionOptions) *Subscription {
+	s := &Subscription{
+		c:    c,
+		stop: make(chan struct{}),
+	}
+	if opt != nil {
+		s.opt = *opt
+	}
+	s.opt.setDefaults()
+	return s
+}
+
+// MonitorBTP monitors BTP blocks from p.Height until cb returns an error
+// or the subscription is closed. It returns nil if it's closed.
+// scb is called on each connection, but not in polling mode.
+func (s *Subscription) MonitorBTP(p *BTPRequest, cb func(v *BTPNotification) error,
+	scb func(conn *websocket.Conn)) error {
+	req := *p
+	retry := 0
+	for {
+		var progressed bool
+		var err error
+		if s.opt.Polling {
+			progressed, err = s.pollBTP(&req, cb)
+		} else {
+			progressed, err = s.monitorBTP(&req, cb, scb)
+		}
+		if cbErr, ok := err.(callbackError); ok {
+			return cbErr.error
+		}
+		if s.isClosed() {
+			return nil
+		}
+		if progressed {
+			retry = 0
+		}
+		delay := s.backoff(retry)
+		retry++
+		s.c.l.Warnf("restart BTP monitor after %v from height %s err:%+v", delay, req.Height, err)
+		select {
+		case <-time.After(delay):
+		case <-s.stop:
+			return nil
+		}
+	}
+}
+
+func (s *Subscription) backoff(retry int) time.Duration {
+	d := s.opt.MinBackoff
+	for i := 0; i < retry && d < s.opt.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.opt.MaxBackoff {
+		d = s.opt.MaxBackoff
+	}
+	// full jitter over the upper half
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (s *Subscription) setConn(conn *websocket.Conn) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conn = conn
+	return true
+}
+
+func (s *Subscription) isClosed() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.closed
+}
+
+// monitorBTP handles a connection, and updates the height of req
+// with processed notifications.
+func (s *Subscription) monitorBTP(req *BTPRequest, cb func(v *BTPNotification) error,
+	scb func(conn *websocket.Conn)) (bool, error) {
+	url := s.c.pool.Best()
+	conn, err := s.c.wsConnectTo(url, "/btp", nil)
+	if err != nil {
+		return false, err
+	}
+	if !s.setConn(conn) {
+		s.c.wsClose(conn)
+		return false, nil
+	}
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		s.setConn(nil)
+		s.c.wsClose(conn)
+	}()
+
+	if err = s.c.wsRequest(conn, req); err != nil {
+		if re, ok := err.(wsRequestError); !ok || re.wsResp == nil {
+			s.c.pool.Report(url, 0, err)
+		}
+		return false, err
+	}
+	if scb != nil {
+		scb(conn)
+	}
+
+	extend := func() error {
+		return conn.SetReadDeadline(time.Now().Add(s.opt.ReadTimeout))
+	}
+	if err = extend(); err != nil {
+		return false, err
+	}
+	conn.SetPongHandler(func(string) error {
+		return extend()
+	})
+	go s.ping(conn, done)
+
+	progressed := false
+	for {
+		v := &BTPNotification{}
+		if err = s.c.wsRead(conn, v); err != nil {
+			if !s.isClosed() {
+				// choose other endpoint for reconnecting
+				s.c.pool.Report(url, 0, err)
+			}
+			return progressed, err
+		}
+		if err = extend(); err != nil {
+			return progressed, err
+		}
+		if err = cb(v); err != nil {
+			return progressed, callbackError{err}
+		}
+		height, err := notifiedHeight(v)
+		if err != nil {
+			return progressed, callbackError{err}
+		}
+		if height > 0 {
+			req.Height = NewHexInt(height + 1)
+			progressed = true
+		}
+	}
+}
+
+// pollBTP produces notifications same as websocket, by getting headers
+// for each height before the last block. It updates the height of req
+// with processed notifications.
+func (s *Subscription) pollBTP(req *BTPRequest, cb func(v *BTPNotification) error) (bool, error) {
+	progressed := false
+	next, err := req.Height.Value()
+	if err != nil {
+		return false, callbackError{err}
+	}
+	interval, _ := req.ProgressInterval.Value()
+	notified := next - 1
+	for {
+		blk, err := s.c.GetLastBlock()
+		if err != nil {
+			return progressed, err
+		}
+		// the proof of the last block is not finalized until the next block
+		for ; next < blk.Height; next++ {
+			if s.isClosed() {
+				return progressed, nil
+			}
+			v, ready, err := s.pollBTPNotification(req, next)
+			if err != nil {
+				return progressed, err
+			}
+			if !ready {
+				break
+			}
+			if v == nil {
+				if interval <= 0 || next-notified < interval {
+					continue
+				}
+				v = &BTPNotification{}
+				v.Progress.Value = next
+			}
+			if err = cb(v); err != nil {
+				return progressed, callbackError{err}
+			}
+			notified = next
+			req.Height = NewHexInt(next + 1)
+			progressed = true
+		}
+		select {
+		case <-time.After(s.opt.PollingInterval):
+		case <-s.stop:
+			return progressed, nil
+		}
+	}
+}
+
+func isNotFound(err error) bool {
+	je, ok := err.(*jsonrpc.Error)
+	return ok && je.Code == JsonrpcErrorCodeNotFound
+}
+
+// pollBTPNotification returns the notification for the height, or nil if
+// there is no BTP block at the height. It returns false if the proof is not
+// available yet, then the height should be polled again.
+func (s *Subscription) pollBTPNotification(req *BTPRequest, height int64) (*BTPNotification, bool, error) {
+	p := &BTPBlockParam{Height: NewHexInt(height), NetworkId: req.NetworkID}
+	v := &BTPNotification{}
+	if _, err := s.c.Do("btp_getHeader", p, &v.Header); err != nil {
+		if isNotFound(err) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	if flag, _ := req.ProofFlag.Value(); flag != 0 {
+		if _, err := s.c.Do("btp_getProof", p, &v.Proof); err != nil {
+			if isNotFound(err) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+	}
+	return v, true, nil
+}
+
+func (s *Subscription) ping(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(s.opt.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(DefaultWriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				s.c.l.Debugf("fail to ping %s err:%+v", conn.LocalAddr().String(), err)
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Close stops monitoring, and closes the current connection.
+func (s *Subscription) Close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	if s.conn != nil {
+		// wake up the reader, then it closes the connection
+		s.conn.SetReadDeadline(time.Now())
+	}
+}
+
+// notifiedHeight returns the height processed by the notification,
+// or zero if the notification has neither the header nor progress.
+func notifiedHeight(v *BTPNotification) (int64, error) {
+	if v.Progress.Value != 0 {
+		return v.Progress.Value, nil
+	}
+	if len(v.Header) == 0 {
+		return 0, nil
+	}
+	h, err := base64.StdEncoding.DecodeString(v.Header)
+	if err != nil {
+		return 0, err
+	}
+	bh := &BTPBlockHeader{}
+	if _, err = codec.RLP.UnmarshalFromBytes(h, bh); err != nil {
+		return 0, err
+	}
+	return bh.MainHeight, nil
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/jsonrpc"
+	"github.com/icon-project/btp2/common/link/linktest"
+	"github.com/icon-project/btp2/common/log"
+)
+
+// testNode serves BTP blocks as an ICON node, by JSON-RPC and websocket.
+type testNode struct {
+	mtx     sync.Mutex
+	last    int64
+	headers map[int64]string
+	// proofs are served for the heights, others are not found
+	proofs map[int64]bool
+	// monitor handles the websocket connection after the request,
+	// the connection is closed when it returns.
+	monitor func(n int, req *BTPRequest, conn *websocket.Conn)
+	// reject fails the upgrade of the connection if it returns true.
+	reject   func(n int) bool
+	conns    int
+	requests []int64
+}
+
+func newTestNode() *testNode {
+	return &testNode{
+		headers: make(map[int64]string),
+		proofs:  make(map[int64]bool),
+	}
+}
+
+func testHeader(height int64) string {
+	bh := &BTPBlockHeader{MainHeight: height, NetworkID: 1}
+	return base64.StdEncoding.EncodeToString(codec.RLP.MustMarshalToBytes(bh))
+}
+
+// addBlock adds the BTP block at the height, and serves its proof if proof
+// is true.
+func (n *testNode) addBlock(height int64, proof bool) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.headers[height] = testHeader(height)
+	n.proofs[height] = proof
+}
+
+func (n *testNode) setLast(height int64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.last = height
+}
+
+func (n *testNode) setProof(height int64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.proofs[height] = true
+}
+
+func (n *testNode) Requests() []int64 {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return append([]int64{}, n.requests...)
+}
+
+func (n *testNode) handle(method string, params json.RawMessage) (interface{}, *jsonrpc.Error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	notFound := &jsonrpc.Error{Code: JsonrpcErrorCodeNotFound, Message: "NotFound"}
+	switch method {
+	case "icx_getLastBlock":
+		return &Block{Height: n.last}, nil
+	case "btp_getHeader", "btp_getProof":
+		p := &BTPBlockParam{}
+		if err := json.Unmarshal(params, p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		height, _ := p.Height.Value()
+		if height > n.last {
+			return nil, notFound
+		}
+		header, ok := n.headers[height]
+		if !ok {
+			return nil, notFound
+		}
+		if method == "btp_getHeader" {
+			return header, nil
+		}
+		if !n.proofs[height] {
+			return nil, notFound
+		}
+		return base64.StdEncoding.EncodeToString([]byte("proof")), nil
+	default:
+		return nil, &jsonrpc.Error{Code: jsonrpc.ErrorCodeMethodNotFound, Message: method}
+	}
+}
+
+func (n *testNode) serveBTP(w http.ResponseWriter, r *http.Request) {
+	n.mtx.Lock()
+	n.conns++
+	cn := n.conns
+	reject := n.reject != nil && n.reject(cn)
+	n.mtx.Unlock()
+	if reject {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	req := &BTPRequest{}
+	if err = conn.ReadJSON(req); err != nil {
+		return
+	}
+	height, _ := req.Height.Value()
+	n.mtx.Lock()
+	n.requests = append(n.requests, height)
+	n.mtx.Unlock()
+	if err = conn.WriteJSON(&WSResponse{}); err != nil {
+		return
+	}
+	n.monitor(cn, req, conn)
+}
+
+func (n *testNode) serve(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/btp", n.serveBTP)
+	mux.Handle("/", linktest.JSONRPCHandler(n.handle))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestSubscription(t *testing.T, n *testNode, opt *SubscriptionOptions) *Subscription {
+	srv := n.serve(t)
+	c, err := NewClient([]string{srv.URL}, nil, log.New())
+	assert.NoError(t, err)
+	return NewSubscription(c, opt)
+}
+
+// monitor runs MonitorBTP from the height, and returns the channels of
+// notified heights and the result.
+func monitor(t *testing.T, s *Subscription, height, proofFlag, interval int64) (<-chan int64, <-chan error) {
+	hc := make(chan int64, 100)
+	errc := make(chan error, 1)
+	req := &BTPRequest{
+		Height:           NewHexInt(height),
+		NetworkID:        NewHexInt(1),
+		ProofFlag:        NewHexInt(proofFlag),
+		ProgressInterval: NewHexInt(interval),
+	}
+	go func() {
+		errc <- s.MonitorBTP(req, func(v *BTPNotification) error {
+			if len(v.Header) > 0 && proofFlag != 0 {
+				assert.NotEmpty(t, v.Proof)
+			}
+			h, err := notifiedHeight(v)
+			assert.NoError(t, err)
+			hc <- h
+			return nil
+		}, nil)
+	}()
+	return hc, errc
+}
+
+func expectHeights(t *testing.T, hc <-chan int64, heights ...int64) {
+	for _, expected := range heights {
+		select {
+		case h := <-hc:
+			assert.Equal(t, expected, h)
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timeout", "expected height:%d", expected)
+		}
+	}
+}
+
+func expectNoHeight(t *testing.T, hc <-chan int64) {
+	select {
+	case h := <-hc:
+		assert.Fail(t, "unexpected notification", "height:%d", h)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func closeSubscription(t *testing.T, s *Subscription, errc <-chan error) {
+	s.Close()
+	select {
+	case err := <-errc:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "MonitorBTP is not returned after Close")
+	}
+}
+
+func TestSubscription_Polling(t *testing.T) {
+	n := newTestNode()
+	n.addBlock(11, true)
+	n.addBlock(13, true)
+	n.addBlock(14, false)
+	n.addBlock(15, true)
+	n.setLast(15)
+	s := newTestSubscription(t, n, &SubscriptionOptions{
+		Polling:         true,
+		PollingInterval: 10 * time.Millisecond,
+	})
+	hc, errc := monitor(t, s, 11, 1, 0)
+
+	// the proof of 14 is not finalized yet
+	expectHeights(t, hc, 11, 13)
+	expectNoHeight(t, hc)
+
+	// the last block is not polled
+	n.setProof(14)
+	expectHeights(t, hc, 14)
+	expectNoHeight(t, hc)
+
+	n.setLast(16)
+	expectHeights(t, hc, 15)
+	closeSubscription(t, s, errc)
+}
+
+func TestSubscription_PollingProgress(t *testing.T) {
+	n := newTestNode()
+	n.addBlock(13, true)
+	n.setLast(16)
+	s := newTestSubscription(t, n, &SubscriptionOptions{
+		Polling:         true,
+		PollingInterval: 10 * time.Millisecond,
+	})
+	hc, errc := monitor(t, s, 11, 0, 2)
+	expectHeights(t, hc, 12, 13, 15)
+	expectNoHeight(t, hc)
+	closeSubscription(t, s, errc)
+}
+
+func TestSubscription_Backoff(t *testing.T) {
+	s := NewSubscription(nil, &SubscriptionOptions{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	})
+	for retry, max := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		for i := 0; i < 10; i++ {
+			d := s.backoff(retry)
+			assert.GreaterOrEqual(t, d, max/2, "retry:%d", retry)
+			assert.LessOrEqual(t, d, max, "retry:%d", retry)
+		}
+	}
+}
+
+func TestSubscription_Resume(t *testing.T) {
+	n := newTestNode()
+	// the first connection fails, and the second one is closed after
+	// notifications
+	n.reject = func(cn int) bool {
+		return cn == 1
+	}
+	n.monitor = func(cn int, req *BTPRequest, conn *websocket.Conn) {
+		height, _ := req.Height.Value()
+		notify := func(h int64) {
+			conn.WriteJSON(&BTPNotification{Header: testHeader(h), Proof: "proof"})
+		}
+		notify(height)
+		notify(height + 1)
+		if cn == 2 {
+			return
+		}
+		// keeps the connection until the client closes it
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+	s := newTestSubscription(t, n, &SubscriptionOptions{
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	})
+	hc, errc := monitor(t, s, 11, 1, 0)
+	expectHeights(t, hc, 11, 12, 13, 14)
+	assert.Equal(t, []int64{11, 13}, n.Requests())
+	closeSubscription(t, s, errc)
+}
@@ -72,6 +72,10 @@ type VerifierStatus struct {
 	SequenceOffset int64
 	FirstMessageSn int64
 	MessageCount   int64
+	// AccumulatorHeight and AccumulatorOffset are of the accumulator of
+	// BTP headers, which are zero if the BMV doesn't verify block proofs.
+	AccumulatorHeight int64
+	AccumulatorOffset int64
 }
 
 type BlockHeader struct {
@@ -456,6 +460,18 @@ type BTPNetworkInfo struct {
 	NetworkTypeName         string   `json:"networkTypeName"`
 }
 
+type BTPNetworkTypeInfoParam struct {
+	Height HexInt `json:"height" validate:"optional,t_int"`
+	Id     HexInt `json:"id" validate:"required,t_int"`
+}
+
+type BTPNetworkTypeInfo struct {
+	NetworkTypeName  string   `json:"networkTypeName"`
+	NextProofContext HexBytes `json:"nextProofContext"`
+	OpenNetworkIDs   []HexInt `json:"openNetworkIDs"`
+	NetworkTypeID    HexInt   `json:"networkTypeID"`
+}
+
 type BTPBlockUpdate struct {
 	BTPBlockHeader []byte
 	BTPBlockProof  []byte
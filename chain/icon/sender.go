@@ -99,13 +99,15 @@ type sender struct {
 	l       log.Logger
 	opt     struct {
 		StepLimit int64
+		// retries and the rate limit of JSON-RPC requests
+		jsonrpc.Options
 	}
 	rr                 chan *types.RelayResult
 	isFoundOffsetBySeq bool
 	queue              *queue
 }
 
-func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, w types.Wallet, endpoint string, opt map[string]interface{}, l log.Logger) types.Sender {
+func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, w types.Wallet, endpoints []string, opt map[string]interface{}, l log.Logger) (types.Sender, error) {
 	s := &sender{
 		srcAddr: srcAddr,
 		dstCfg:  dstCfg.(chain.BaseConfig),
@@ -124,8 +126,10 @@ func NewSender(srcAddr types.BtpAddress, dstCfg link.ChainConfig, w types.Wallet
 	if s.opt.StepLimit <= 0 {
 		s.opt.StepLimit = DefaultStepLimit
 	}
-	s.c = client.NewClient(endpoint, l)
-	return s
+	if s.c, err = client.NewClient(endpoints, &s.opt.Options, l); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func (s *sender) Start() (<-chan *types.RelayResult, error) {
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2021 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/cli"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/relay"
+)
+
+type receiverConfig struct {
+	chain.BaseConfig
+	raw    json.RawMessage
+	dstRaw json.RawMessage
+}
+
+// receiverConfigs returns configurations of the chains having receivers
+// for the direction. With network, it returns the matched one only.
+func receiverConfigs(cfg *relay.Config, network string) ([]*receiverConfig, error) {
+	var pairs [][2]json.RawMessage
+	switch cfg.Direction {
+	case relay.FrontDirection:
+		pairs = [][2]json.RawMessage{{cfg.Src, cfg.Dst}}
+	case relay.ReverseDirection:
+		pairs = [][2]json.RawMessage{{cfg.Dst, cfg.Src}}
+	default:
+		pairs = [][2]json.RawMessage{{cfg.Src, cfg.Dst}, {cfg.Dst, cfg.Src}}
+	}
+	rcs := make([]*receiverConfig, 0, len(pairs))
+	for _, pair := range pairs {
+		raw := pair[0]
+		if len(raw) == 0 {
+			continue
+		}
+		rc := &receiverConfig{raw: raw, dstRaw: pair[1]}
+		if err := json.Unmarshal(raw, &rc.BaseConfig); err != nil {
+			return nil, err
+		}
+		if network != "" && rc.Address.NetworkAddress() != network {
+			continue
+		}
+		rcs = append(rcs, rc)
+	}
+	if len(rcs) == 0 {
+		return nil, fmt.Errorf("no receiver for network=%s", network)
+	}
+	return rcs, nil
+}
+
+func forEachStore(cmd *cobra.Command, cfg *relay.Config, f func(rc *receiverConfig, s link.Store) error) error {
+	network, _ := cmd.Flags().GetString("network")
+	rcs, err := receiverConfigs(cfg, network)
+	if err != nil {
+		return err
+	}
+	for _, rc := range rcs {
+		s, err := link.OpenStore(rc.raw, cfg.BaseDir, log.GlobalLogger())
+		if err != nil {
+			return fmt.Errorf("fail to open database of %s (stop the relay first) err=%+v",
+				rc.Address.NetworkAddress(), err)
+		}
+		err = f(rc, s)
+		s.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type storeExport struct {
+	Network           string             `json:"network"`
+	Type              string             `json:"type"`
+	LastReceiveHeight int64              `json:"last_receive_height"`
+	Entries           []*link.StoreEntry `json:"entries"`
+}
+
+func newDBCommand(cfg *relay.Config) *cobra.Command {
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage databases of receivers",
+	}
+	dbCmd.PersistentFlags().String("network", "",
+		"Network address of the receiver (all receivers for the direction if empty)")
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate databases to the latest schema",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			network, _ := cmd.Flags().GetString("network")
+			rcs, err := receiverConfigs(cfg, network)
+			if err != nil {
+				return err
+			}
+			for _, rc := range rcs {
+				s, ok := db.GetSchema(rc.Type)
+				if !ok {
+					cmd.Printf("%s (%s): no schema\n", rc.Address.NetworkAddress(), rc.Type)
+					continue
+				}
+				database, err := rc.OpenDatabase(cfg.BaseDir)
+				if err != nil {
+					return err
+				}
+				version, pending, err := db.Migrate(database, rc.Type, dryRun)
+				database.Close()
+				if err != nil {
+					return err
+				}
+				cmd.Printf("%s (%s): version %d, latest %d\n",
+					rc.Address.NetworkAddress(), rc.Type, version, s.Version())
+				for _, m := range pending {
+					if dryRun {
+						cmd.Printf("  pending %d: %s\n", m.Version, m.Description)
+					} else {
+						cmd.Printf("  applied %d: %s\n", m.Version, m.Description)
+					}
+				}
+			}
+			return nil
+		},
+	}
+	migrateCmd.Flags().Bool("dry-run", false, "Show pending migrations without applying")
+	dbCmd.AddCommand(migrateCmd)
+
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "inspect",
+		Short: "List stored headers and receive statuses by height",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachStore(cmd, cfg, func(rc *receiverConfig, s link.Store) error {
+				height, err := s.LastReceiveHeight()
+				if err != nil {
+					return err
+				}
+				entries, err := s.Entries()
+				if err != nil {
+					return err
+				}
+				cmd.Printf("%s (%s): LastReceiveHeight %d, %d entries\n",
+					rc.Address.NetworkAddress(), rc.Type, height, len(entries))
+				for _, e := range entries {
+					switch v := e.Value.(type) {
+					case common.HexBytes:
+						cmd.Printf("  %10d %-6s %d bytes\n", e.Height, e.Kind, len(v))
+					default:
+						bs, _ := json.Marshal(v)
+						cmd.Printf("  %10d %-6s %s\n", e.Height, e.Kind, bs)
+					}
+				}
+				return nil
+			})
+		},
+	})
+
+	exportCmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export stored headers and receive statuses as JSON",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exports := make([]*storeExport, 0)
+			err := forEachStore(cmd, cfg, func(rc *receiverConfig, s link.Store) error {
+				height, err := s.LastReceiveHeight()
+				if err != nil {
+					return err
+				}
+				entries, err := s.Entries()
+				if err != nil {
+					return err
+				}
+				exports = append(exports, &storeExport{
+					Network:           rc.Address.NetworkAddress(),
+					Type:              rc.Type,
+					LastReceiveHeight: height,
+					Entries:           entries,
+				})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				return cli.JsonPrettyPrintln(cmd.OutOrStdout(), exports)
+			}
+			if err = cli.JsonPrettySaveFile(args[0], 0644, exports); err != nil {
+				return err
+			}
+			cmd.Println("Export databases to", args[0])
+			return nil
+		},
+	}
+	dbCmd.AddCommand(exportCmd)
+
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "last-height",
+		Short: "Show LastReceiveHeight",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachStore(cmd, cfg, func(rc *receiverConfig, s link.Store) error {
+				height, err := s.LastReceiveHeight()
+				if err != nil {
+					return err
+				}
+				cmd.Printf("%s (%s): %d\n", rc.Address.NetworkAddress(), rc.Type, height)
+				return nil
+			})
+		},
+	})
+
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "compact",
+		Short: "Compact databases",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachStore(cmd, cfg, func(rc *receiverConfig, s link.Store) error {
+				if err := s.Compact(); err != nil {
+					return err
+				}
+				cmd.Printf("%s (%s): compacted\n", rc.Address.NetworkAddress(), rc.Type)
+				return nil
+			})
+		},
+	})
+
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "reset HEIGHT",
+		Short: "Remove stored data after the height, to resume from the height",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height, err := strconv.ParseInt(args[0], 0, 64)
+			if err != nil {
+				return fmt.Errorf("invalid height=%s err=%+v", args[0], err)
+			}
+			network, _ := cmd.Flags().GetString("network")
+			if rcs, err := receiverConfigs(cfg, network); err != nil {
+				return err
+			} else if len(rcs) > 1 {
+				return fmt.Errorf("multiple receivers for the direction, use --network")
+			}
+			return forEachStore(cmd, cfg, func(rc *receiverConfig, s link.Store) error {
+				if err := s.Reset(height); err != nil {
+					return err
+				}
+				cmd.Printf("%s (%s): reset to %d\n", rc.Address.NetworkAddress(), rc.Type, height)
+				return nil
+			})
+		},
+	})
+	return dbCmd
+}
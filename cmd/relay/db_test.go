@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain/ethbr"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/relay"
+	"github.com/icon-project/btp2/common/types"
+)
+
+func runDBCommand(cfg *relay.Config, args ...string) (string, error) {
+	return runCommand(newDBCommand(cfg), args...)
+}
+
+// addReceiveBlocks writes headers to the database of the receiver.
+func addReceiveBlocks(t *testing.T, cfg *relay.Config, heights ...int64) {
+	database, err := testReceiverConfig(t, cfg).OpenDatabase(cfg.BaseDir)
+	assert.NoError(t, err)
+	defer database.Close()
+	bk, err := database.GetBucket(ethbr.ReceiveBlockBucket)
+	assert.NoError(t, err)
+	for _, h := range heights {
+		assert.NoError(t, bk.Set(big.NewInt(h).Bytes(), []byte(fmt.Sprintf("header of %d", h))))
+	}
+}
+
+func TestDB_Inspect(t *testing.T) {
+	cfg := newTestConfig(t)
+	setLastReceiveHeight(t, cfg, 500, 3)
+	addReceiveBlocks(t, cfg, 10, 11)
+
+	out, err := runDBCommand(cfg, "inspect")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "LastReceiveHeight 500, 2 entries")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 3, "output:\n%s", out)
+	assert.Equal(t, 1, strings.Count(out, "        10 header"), "output:\n%s", out)
+	assert.Equal(t, 1, strings.Count(out, "        11 header"), "output:\n%s", out)
+}
+
+func TestDB_Export(t *testing.T) {
+	cfg := newTestConfig(t)
+	setLastReceiveHeight(t, cfg, 500, 3)
+	addReceiveBlocks(t, cfg, 10, 11)
+
+	check := func(bs []byte) {
+		var exports []*storeExport
+		assert.NoError(t, json.Unmarshal(bs, &exports))
+		assert.Len(t, exports, 1)
+		e := exports[0]
+		assert.Equal(t, types.BtpAddress(testSrc).NetworkAddress(), e.Network)
+		assert.Equal(t, ethbr.TYPE, e.Type)
+		assert.Equal(t, int64(500), e.LastReceiveHeight)
+		assert.Len(t, e.Entries, 2)
+		for i, h := range []int64{10, 11} {
+			assert.Equal(t, h, e.Entries[i].Height)
+		}
+	}
+	out, err := runDBCommand(cfg, "export")
+	assert.NoError(t, err)
+	check([]byte(out))
+
+	file := filepath.Join(t.TempDir(), "export.json")
+	_, err = runDBCommand(cfg, "export", file)
+	assert.NoError(t, err)
+	bs, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	check(bs)
+}
+
+func TestDB_Reset(t *testing.T) {
+	cfg := newTestConfig(t)
+	setLastReceiveHeight(t, cfg, 500, 3)
+	addReceiveBlocks(t, cfg, 10, 11)
+
+	_, err := runDBCommand(cfg, "reset", "invalid")
+	assert.Error(t, err)
+	_, err = runDBCommand(cfg, "reset", "10")
+	assert.NoError(t, err)
+	out, err := runDBCommand(cfg, "last-height")
+	assert.NoError(t, err)
+	assert.Contains(t, out, fmt.Sprintf("(%s): 10\n", ethbr.TYPE))
+	out, err = runDBCommand(cfg, "inspect")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "LastReceiveHeight 10, 1 entries")
+
+	// receivers of both directions
+	cfg.Direction = relay.BothDirection
+	_, err = runDBCommand(cfg, "reset", "5")
+	assert.ErrorContains(t, err, "use --network")
+}
+
+func TestDB_Migrate(t *testing.T) {
+	cfg := newTestConfig(t)
+	rc := testReceiverConfig(t, cfg)
+	database, err := rc.OpenDatabase(cfg.BaseDir)
+	assert.NoError(t, err)
+	legacy, err := database.GetBucket(ethbr.LegacyPropertyBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Set([]byte(ethbr.KeyLastReceiveHeight), big.NewInt(500).Bytes()))
+	assert.NoError(t, database.Close())
+
+	version := func() int {
+		database, err := rc.OpenDatabase(cfg.BaseDir)
+		assert.NoError(t, err)
+		defer database.Close()
+		v, err := db.GetSchemaVersion(database)
+		assert.NoError(t, err)
+		return v
+	}
+
+	out, err := runDBCommand(cfg, "migrate", "--dry-run")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "version 0, latest 1")
+	assert.Contains(t, out, "pending 1:")
+	assert.Equal(t, 0, version())
+
+	out, err = runDBCommand(cfg, "migrate")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "applied 1:")
+	assert.Equal(t, 1, version())
+
+	out, err = runDBCommand(cfg, "last-height")
+	assert.NoError(t, err)
+	assert.Contains(t, out, fmt.Sprintf("(%s): 500\n", ethbr.TYPE))
+}
@@ -199,6 +199,10 @@ func main() {
 
 	cli.BindPFlags(rootVc, startFlags)
 
+	rootCmd.AddCommand(newDBCommand(cfg))
+	rootCmd.AddCommand(newStateCommand(cfg))
+	rootCmd.AddCommand(newVerifyProofCommand())
+
 	genMdCmd := cli.NewGenerateMarkdownCommand(rootCmd, rootVc)
 	genMdCmd.Hidden = true
 
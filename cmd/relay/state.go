@@ -0,0 +1,619 @@
+/*
+ * Copyright 2021 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/icon-project/btp2/chain"
+	"github.com/icon-project/btp2/common/cli"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/relay"
+)
+
+const (
+	stateManifestName    = "manifest.json"
+	stateManifestVersion = 1
+)
+
+// linkState describes the database of the receiver in the archive.
+type linkState struct {
+	Network           string `json:"network"`
+	Dst               string `json:"dst"`
+	Type              string `json:"type"`
+	DBType            string `json:"db_type"`
+	SchemaVersion     int    `json:"schema_version"`
+	LastReceiveHeight int64  `json:"last_receive_height"`
+	FirstHeight       int64  `json:"first_height"`
+	LastHeight        int64  `json:"last_height"`
+	LastSeq           int64  `json:"last_seq"`
+	Entries           int    `json:"entries"`
+	// Files maps the path in the archive to SHA-256 of the file
+	Files map[string]string `json:"files"`
+}
+
+type stateManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Links     []*linkState `json:"links"`
+}
+
+func dstNetwork(rc *receiverConfig) string {
+	var dst link.ChainConfigCommon
+	if err := json.Unmarshal(rc.dstRaw, &dst); err != nil {
+		return ""
+	}
+	return dst.Address.NetworkAddress()
+}
+
+func dbType(rc *receiverConfig) string {
+	if rc.DBType == "" {
+		return string(chain.DefaultDBType)
+	}
+	return rc.DBType
+}
+
+func openStore(rc *receiverConfig, baseDir string) (link.Store, error) {
+	s, err := link.OpenStore(rc.raw, baseDir, log.GlobalLogger())
+	if err != nil {
+		return nil, fmt.Errorf("fail to open database of %s (stop the relay first) err=%+v",
+			rc.Address.NetworkAddress(), err)
+	}
+	return s, nil
+}
+
+// newLinkState reads the state of the store.
+func newLinkState(rc *receiverConfig, s link.Store) (*linkState, error) {
+	ls := &linkState{
+		Network:       rc.Address.NetworkAddress(),
+		Dst:           dstNetwork(rc),
+		Type:          rc.Type,
+		DBType:        dbType(rc),
+		SchemaVersion: schemaVersion(rc),
+		Files:         make(map[string]string),
+	}
+	var err error
+	if ls.LastReceiveHeight, err = s.LastReceiveHeight(); err != nil {
+		return nil, err
+	}
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	ls.Entries = len(entries)
+	for _, e := range entries {
+		if ls.FirstHeight == 0 || e.Height < ls.FirstHeight {
+			ls.FirstHeight = e.Height
+		}
+		if e.Height > ls.LastHeight {
+			ls.LastHeight = e.Height
+		}
+		if e.Seq > ls.LastSeq {
+			ls.LastSeq = e.Seq
+		}
+	}
+	return ls, nil
+}
+
+// matchLinkState checks the state read from the restored store is the same
+// as the state in the manifest.
+func matchLinkState(ls, restored *linkState) error {
+	if ls.LastReceiveHeight != restored.LastReceiveHeight ||
+		ls.FirstHeight != restored.FirstHeight ||
+		ls.LastHeight != restored.LastHeight ||
+		ls.LastSeq != restored.LastSeq ||
+		ls.Entries != restored.Entries {
+		return fmt.Errorf("mismatch with manifest for %s "+
+			"(LastReceiveHeight:%d heights:%d-%d last seq:%d entries:%d)",
+			ls.Network, restored.LastReceiveHeight, restored.FirstHeight, restored.LastHeight,
+			restored.LastSeq, restored.Entries)
+	}
+	return nil
+}
+
+// schemaVersion returns the version of the opened store, which is migrated
+// to the latest version of the schema.
+func schemaVersion(rc *receiverConfig) int {
+	if s, ok := db.GetSchema(rc.Type); ok {
+		return s.Version()
+	}
+	return 0
+}
+
+// addDatabaseToZip adds files of the database directory under the network
+// address, and records their hashes.
+func addDatabaseToZip(zw *zip.Writer, dir string, ls *linkState) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(ls.Network, filepath.ToSlash(rel))
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		if _, err = io.Copy(io.MultiWriter(w, h), f); err != nil {
+			return err
+		}
+		ls.Files[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+}
+
+func writeStateArchive(w io.Writer, cfg *relay.Config, rcs []*receiverConfig, m *stateManifest) error {
+	zw := zip.NewWriter(w)
+	for i, rc := range rcs {
+		if err := addDatabaseToZip(zw, rc.DatabaseDir(cfg.BaseDir), m.Links[i]); err != nil {
+			return err
+		}
+	}
+	mw, err := zw.Create(stateManifestName)
+	if err != nil {
+		return err
+	}
+	if err = cli.JsonPrettyPrintln(mw, m); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func backupState(cmd *cobra.Command, cfg *relay.Config, file string) error {
+	network, _ := cmd.Flags().GetString("network")
+	rcs, err := receiverConfigs(cfg, network)
+	if err != nil {
+		return err
+	}
+	m := &stateManifest{
+		Version:   stateManifestVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+	// stores are kept open while copying, so that the relay can't start
+	// and write to the databases.
+	for _, rc := range rcs {
+		dir := rc.DatabaseDir(cfg.BaseDir)
+		if isDir, err := cli.IsDirectory(dir); err != nil || !isDir {
+			return fmt.Errorf("no database of %s in %s", rc.Address.NetworkAddress(), dir)
+		}
+		s, err := openStore(rc, cfg.BaseDir)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		ls, err := newLinkState(rc, s)
+		if err != nil {
+			return err
+		}
+		// no background compaction changes files while copying
+		if err = s.Compact(); err != nil && !errors.UnsupportedError.Equals(err) {
+			return err
+		}
+		m.Links = append(m.Links, ls)
+	}
+
+	if _, err = os.Stat(file); err == nil {
+		return fmt.Errorf("%s already exists", file)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		f.Close()
+		if tmp != "" {
+			os.Remove(tmp)
+		}
+	}()
+	if err = writeStateArchive(f, cfg, rcs, m); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		return err
+	}
+	tmp = ""
+	for _, ls := range m.Links {
+		cmd.Printf("%s (%s): LastReceiveHeight %d, heights %d-%d, last seq %d, %d files\n",
+			ls.Network, ls.Type, ls.LastReceiveHeight, ls.FirstHeight, ls.LastHeight, ls.LastSeq, len(ls.Files))
+	}
+	cmd.Println("Backup state to", file)
+	return nil
+}
+
+func readManifest(zr *zip.ReadCloser) (*stateManifest, error) {
+	for _, zf := range zr.File {
+		if zf.Name != stateManifestName {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		m := &stateManifest{}
+		if err = json.NewDecoder(r).Decode(m); err != nil {
+			return nil, fmt.Errorf("invalid manifest err=%+v", err)
+		}
+		if m.Version != stateManifestVersion {
+			return nil, fmt.Errorf("unknown manifest version=%d", m.Version)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("no %s in the archive", stateManifestName)
+}
+
+func fileHash(zf *zip.File) (string, error) {
+	r, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyArchive checks all files of the archive are listed in the manifest
+// with the same hashes.
+func verifyArchive(zr *zip.ReadCloser, m *stateManifest) error {
+	hashes := make(map[string]string)
+	for _, ls := range m.Links {
+		for name, h := range ls.Files {
+			hashes[name] = h
+		}
+	}
+	for _, zf := range zr.File {
+		if zf.Name == stateManifestName {
+			continue
+		}
+		expected, ok := hashes[zf.Name]
+		if !ok {
+			return fmt.Errorf("unknown file %s in the archive", zf.Name)
+		}
+		h, err := fileHash(zf)
+		if err != nil {
+			return err
+		}
+		if h != expected {
+			return fmt.Errorf("hash mismatch for %s", zf.Name)
+		}
+		delete(hashes, zf.Name)
+	}
+	for name := range hashes {
+		return fmt.Errorf("missing file %s in the archive", name)
+	}
+	return nil
+}
+
+func extractDatabase(zr *zip.ReadCloser, network, dir string) error {
+	prefix := network + "/"
+	for _, zf := range zr.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			continue
+		}
+		rel := filepath.FromSlash(strings.TrimPrefix(zf.Name, prefix))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file %s in the archive", zf.Name)
+		}
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return err
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err == nil {
+			_, err = io.Copy(f, r)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDBDir returns the raw configuration with db_dir replaced.
+func withDBDir(raw json.RawMessage, dir string) (json.RawMessage, error) {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	m["db_dir"] = dir
+	return json.Marshal(m)
+}
+
+// verifyRestored opens the restored database, and checks it with the
+// status of the destination unless offline.
+func verifyRestored(cmd *cobra.Command, cfg *relay.Config, rc *receiverConfig, ls *linkState,
+	tmpDir string, offline bool) error {
+	raw, err := withDBDir(rc.raw, tmpDir)
+	if err != nil {
+		return err
+	}
+	s, err := link.OpenStore(raw, cfg.BaseDir, log.GlobalLogger())
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	restored, err := newLinkState(rc, s)
+	if err != nil {
+		return err
+	}
+	if err = matchLinkState(ls, restored); err != nil {
+		return err
+	}
+	if offline {
+		return nil
+	}
+	sender, err := link.CreateSender(rc.raw, rc.dstRaw, cfg.BaseDir, log.GlobalLogger())
+	if err != nil {
+		return fmt.Errorf("fail to create sender for %s (use --offline to skip) err=%+v", ls.Dst, err)
+	}
+	bls, err := sender.GetStatus()
+	if err != nil {
+		return fmt.Errorf("fail to get status from %s (use --offline to skip) err=%+v", ls.Dst, err)
+	}
+	if err = s.Verify(bls); err != nil {
+		return err
+	}
+	cmd.Printf("%s (%s): verifier height %d, rxSeq %d, archive last height %d\n",
+		ls.Network, ls.Type, bls.Verifier.Height, bls.RxSeq, ls.LastHeight)
+	if ls.LastReceiveHeight < bls.Verifier.Height && ls.LastHeight < bls.Verifier.Height {
+		cmd.Printf("%s (%s): archive is behind the destination, it resumes from height %d\n",
+			ls.Network, ls.Type, bls.Verifier.Height)
+	}
+	return nil
+}
+
+func restoreState(cmd *cobra.Command, cfg *relay.Config, file string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	offline, _ := cmd.Flags().GetBool("offline")
+	network, _ := cmd.Flags().GetString("network")
+
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	m, err := readManifest(zr)
+	if err != nil {
+		return err
+	}
+	if err = verifyArchive(zr, m); err != nil {
+		return err
+	}
+	rcs, err := receiverConfigs(cfg, network)
+	if err != nil {
+		return err
+	}
+
+	type target struct {
+		rc *receiverConfig
+		ls *linkState
+	}
+	targets := make([]*target, 0)
+	for _, rc := range rcs {
+		var found *linkState
+		for _, ls := range m.Links {
+			if ls.Network == rc.Address.NetworkAddress() {
+				found = ls
+				break
+			}
+		}
+		if found == nil {
+			return fmt.Errorf("no database of %s in the archive", rc.Address.NetworkAddress())
+		}
+		if found.Type != rc.Type || found.Dst != dstNetwork(rc) || found.DBType != dbType(rc) {
+			return fmt.Errorf("mismatch with config for %s (type:%s dst:%s db_type:%s)",
+				found.Network, found.Type, found.Dst, found.DBType)
+		}
+		if s, ok := db.GetSchema(rc.Type); ok && found.SchemaVersion > s.Version() {
+			return fmt.Errorf("unknown schema version %d for %s", found.SchemaVersion, found.Network)
+		}
+		dir := rc.DatabaseDir(cfg.BaseDir)
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+			if !force {
+				return fmt.Errorf("database of %s exists in %s (use --force to replace)", found.Network, dir)
+			}
+			database, err := rc.OpenDatabase(cfg.BaseDir)
+			if err != nil {
+				return fmt.Errorf("fail to open database of %s (stop the relay first) err=%+v",
+					found.Network, err)
+			}
+			database.Close()
+		}
+		targets = append(targets, &target{rc, found})
+	}
+
+	// all databases are extracted and verified before replacing any of them
+	dirs := make([]string, len(targets))
+	for i, t := range targets {
+		dirs[i] = t.rc.DatabaseDir(cfg.BaseDir)
+	}
+	defer func() {
+		for _, dir := range dirs {
+			os.RemoveAll(filepath.Dir(stagedDir(dir)))
+		}
+	}()
+	for i, t := range targets {
+		staged := stagedDir(dirs[i])
+		if err = os.RemoveAll(staged); err != nil {
+			return err
+		}
+		if err = extractDatabase(zr, t.ls.Network, staged); err != nil {
+			return err
+		}
+		if err = verifyRestored(cmd, cfg, t.rc, t.ls, filepath.Dir(staged), offline); err != nil {
+			return err
+		}
+	}
+	if err = replaceDatabases(dirs); err != nil {
+		return err
+	}
+	for i, t := range targets {
+		cmd.Printf("%s (%s): restored to %s\n", t.ls.Network, t.ls.Type, dirs[i])
+	}
+	return nil
+}
+
+// stagedDir returns the directory for the database extracted from the
+// archive, which is in the same file system with dir for renaming.
+func stagedDir(dir string) string {
+	return filepath.Join(filepath.Dir(dir), ".restore", filepath.Base(dir))
+}
+
+// oldDir returns the directory for the existing database while replacing.
+func oldDir(dir string) string {
+	return filepath.Join(filepath.Dir(dir), ".restore.old", filepath.Base(dir))
+}
+
+// replaceDatabases replaces the database directories with the staged ones.
+// Existing databases are moved aside, and moved back if any of them fails,
+// so that all databases are restored or none of them.
+func replaceDatabases(dirs []string) error {
+	type replaced struct {
+		dir    string
+		hasOld bool
+	}
+	done := make([]*replaced, 0, len(dirs))
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			r := done[i]
+			if err := os.RemoveAll(r.dir); err != nil {
+				log.Warnf("fail to remove restored database %s err=%+v", r.dir, err)
+				continue
+			}
+			if !r.hasOld {
+				continue
+			}
+			if err := os.Rename(oldDir(r.dir), r.dir); err != nil {
+				log.Warnf("fail to move back database %s from %s err=%+v", r.dir, oldDir(r.dir), err)
+				continue
+			}
+			os.Remove(filepath.Dir(oldDir(r.dir)))
+		}
+	}
+	for _, dir := range dirs {
+		r := &replaced{dir: dir}
+		if _, err := os.Stat(dir); err == nil {
+			if err = os.RemoveAll(oldDir(dir)); err != nil {
+				rollback()
+				return err
+			}
+			if err = os.MkdirAll(filepath.Dir(oldDir(dir)), 0700); err != nil {
+				rollback()
+				return err
+			}
+			if err = os.Rename(dir, oldDir(dir)); err != nil {
+				rollback()
+				return err
+			}
+			r.hasOld = true
+		} else if !os.IsNotExist(err) {
+			rollback()
+			return err
+		}
+		done = append(done, r)
+		if err := os.Rename(stagedDir(dir), dir); err != nil {
+			rollback()
+			return err
+		}
+	}
+	for _, r := range done {
+		if r.hasOld {
+			os.RemoveAll(filepath.Dir(oldDir(r.dir)))
+		}
+	}
+	return nil
+}
+
+func newStateCommand(cfg *relay.Config) *cobra.Command {
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Backup and restore databases of receivers",
+	}
+	stateCmd.PersistentFlags().String("network", "",
+		"Network address of the receiver (all receivers for the direction if empty)")
+
+	stateCmd.AddCommand(&cobra.Command{
+		Use:   "backup FILE",
+		Short: "Backup databases with the manifest to the archive",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return backupState(cmd, cfg, args[0])
+		},
+	})
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore FILE",
+		Short: "Restore databases from the archive",
+		Args:  cli.ArgsWithDefaultErrorFunc(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return restoreState(cmd, cfg, args[0])
+		},
+	}
+	restoreFlags := restoreCmd.Flags()
+	restoreFlags.Bool("force", false, "Replace existing databases")
+	restoreFlags.Bool("offline", false, "Skip verification with the status of the destination")
+	stateCmd.AddCommand(restoreCmd)
+	return stateCmd
+}
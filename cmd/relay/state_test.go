@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/chain/ethbr"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/relay"
+)
+
+const (
+	testSrc = "btp://0x1.eth/0x0000000000000000000000000000000000000001"
+	testDst = "btp://0x2.icon/cx0000000000000000000000000000000000000002"
+)
+
+func newTestConfig(t *testing.T) *relay.Config {
+	cfg := &relay.Config{}
+	cfg.BaseDir = t.TempDir()
+	cfg.Direction = relay.FrontDirection
+	cfg.Src = json.RawMessage(fmt.Sprintf(`{"address":"%s","endpoint":"http://localhost","type":"%s"}`,
+		testSrc, ethbr.TYPE))
+	cfg.Dst = json.RawMessage(fmt.Sprintf(`{"address":"%s","endpoint":"http://localhost","type":"icon-btpblock"}`,
+		testDst))
+	return cfg
+}
+
+func testReceiverConfig(t *testing.T, cfg *relay.Config) *receiverConfig {
+	rcs, err := receiverConfigs(cfg, "")
+	assert.NoError(t, err)
+	assert.Len(t, rcs, 1)
+	return rcs[0]
+}
+
+// setLastReceiveHeight writes LastReceiveHeight to the database of the
+// receiver as the receiver does.
+func setLastReceiveHeight(t *testing.T, cfg *relay.Config, height, seq int64) {
+	rc := testReceiverConfig(t, cfg)
+	s, err := link.OpenStore(rc.raw, cfg.BaseDir, log.GlobalLogger())
+	assert.NoError(t, err)
+	assert.NoError(t, s.Close())
+	database, err := rc.OpenDatabase(cfg.BaseDir)
+	assert.NoError(t, err)
+	defer database.Close()
+	bk, err := database.GetBucket(ethbr.PropertyBucket)
+	assert.NoError(t, err)
+	assert.NoError(t, bk.Set([]byte(ethbr.KeyLastReceiveHeight), big.NewInt(height).Bytes()))
+	assert.NoError(t, bk.Set([]byte(ethbr.KeyLastReceiveSeq), big.NewInt(seq).Bytes()))
+}
+
+func runStateCommand(cfg *relay.Config, args ...string) (string, error) {
+	return runCommand(newStateCommand(cfg), args...)
+}
+
+func runCommand(cmd *cobra.Command, args ...string) (string, error) {
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return buf.String(), err
+}
+
+// rewriteManifest copies the archive with the manifest modified by f.
+func rewriteManifest(t *testing.T, src, dst string, f func(m *stateManifest)) {
+	zr, err := zip.OpenReader(src)
+	assert.NoError(t, err)
+	defer zr.Close()
+	out, err := os.Create(dst)
+	assert.NoError(t, err)
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	for _, zf := range zr.File {
+		r, err := zf.Open()
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+		if zf.Name == stateManifestName {
+			m := &stateManifest{}
+			assert.NoError(t, json.Unmarshal(bs, m))
+			f(m)
+			bs, err = json.Marshal(m)
+			assert.NoError(t, err)
+		}
+		w, err := zw.Create(zf.Name)
+		assert.NoError(t, err)
+		_, err = w.Write(bs)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+}
+
+func TestState_BackupAndRestore(t *testing.T) {
+	cfg := newTestConfig(t)
+	setLastReceiveHeight(t, cfg, 500, 3)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "state.zip")
+
+	_, err := runStateCommand(cfg, "backup", file)
+	assert.NoError(t, err)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file is left")
+
+	_, err = runStateCommand(cfg, "backup", file)
+	assert.Error(t, err, "backup overwrites the archive")
+
+	// restore to the other base directory
+	cfg2 := newTestConfig(t)
+	_, err = runStateCommand(cfg2, "restore", "--offline", file)
+	assert.NoError(t, err)
+	s, err := link.OpenStore(testReceiverConfig(t, cfg2).raw, cfg2.BaseDir, log.GlobalLogger())
+	assert.NoError(t, err)
+	height, err := s.LastReceiveHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), height)
+	assert.NoError(t, s.Close())
+
+	_, err = runStateCommand(cfg2, "restore", "--offline", file)
+	assert.Error(t, err, "restore replaces the database without --force")
+	_, err = runStateCommand(cfg2, "restore", "--offline", "--force", file)
+	assert.NoError(t, err)
+}
+
+func TestState_RunningRelay(t *testing.T) {
+	cfg := newTestConfig(t)
+	setLastReceiveHeight(t, cfg, 500, 3)
+	file := filepath.Join(t.TempDir(), "state.zip")
+	_, err := runStateCommand(cfg, "backup", file)
+	assert.NoError(t, err)
+
+	// the running relay keeps the database open
+	database, err := testReceiverConfig(t, cfg).OpenDatabase(cfg.BaseDir)
+	assert.NoError(t, err)
+	defer database.Close()
+
+	file2 := filepath.Join(t.TempDir(), "state.zip")
+	_, err = runStateCommand(cfg, "backup", file2)
+	assert.ErrorContains(t, err, "stop the relay first")
+	_, err = os.Stat(file2)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = runStateCommand(cfg, "restore", "--offline", "--force", file)
+	assert.ErrorContains(t, err, "stop the relay first")
+}
+
+func TestState_RestoreMismatchedManifest(t *testing.T) {
+	cfg := newTestConfig(t)
+	setLastReceiveHeight(t, cfg, 500, 3)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "state.zip")
+	_, err := runStateCommand(cfg, "backup", file)
+	assert.NoError(t, err)
+
+	modified := filepath.Join(dir, "modified.zip")
+	rewriteManifest(t, file, modified, func(m *stateManifest) {
+		m.Links[0].LastReceiveHeight = 1000
+	})
+	cfg2 := newTestConfig(t)
+	_, err = runStateCommand(cfg2, "restore", "--offline", modified)
+	assert.ErrorContains(t, err, "mismatch with manifest")
+	_, err = os.Stat(testReceiverConfig(t, cfg2).DatabaseDir(cfg2.BaseDir))
+	assert.True(t, os.IsNotExist(err), "database is restored")
+}
+
+func writeTestDatabase(t *testing.T, dir, content string) {
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data"), []byte(content), 0600))
+}
+
+func readTestDatabase(t *testing.T, dir string) string {
+	bs, err := os.ReadFile(filepath.Join(dir, "data"))
+	assert.NoError(t, err)
+	return string(bs)
+}
+
+func TestState_ReplaceDatabases(t *testing.T) {
+	base := t.TempDir()
+	dirs := []string{filepath.Join(base, "0x1.eth"), filepath.Join(base, "0x2.icon")}
+	writeTestDatabase(t, dirs[0], "old")
+	writeTestDatabase(t, stagedDir(dirs[0]), "restored")
+	writeTestDatabase(t, stagedDir(dirs[1]), "restored")
+	assert.NoError(t, replaceDatabases(dirs))
+	for _, dir := range dirs {
+		assert.Equal(t, "restored", readTestDatabase(t, dir))
+	}
+	_, err := os.Stat(filepath.Dir(oldDir(dirs[0])))
+	assert.True(t, os.IsNotExist(err), "existing database is left")
+
+	// the first one is moved back, if the second one fails
+	writeTestDatabase(t, stagedDir(dirs[0]), "restored again")
+	os.RemoveAll(dirs[1])
+	assert.Error(t, replaceDatabases(dirs))
+	assert.Equal(t, "restored", readTestDatabase(t, dirs[0]))
+	_, err = os.Stat(dirs[1])
+	assert.True(t, os.IsNotExist(err))
+}
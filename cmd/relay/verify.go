@@ -0,0 +1,210 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/icon-project/btp2/chain/icon/btp2"
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/cli"
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/mbt"
+	"github.com/icon-project/btp2/common/mta"
+	"github.com/icon-project/btp2/common/ntm"
+)
+
+// accStatus is the status of the accumulator in JSON.
+type accStatus struct {
+	Height int64             `json:"height"`
+	Roots  []common.HexBytes `json:"roots"`
+	Offset int64             `json:"offset"`
+}
+
+// readArg returns the value, or the content of the file if it starts
+// with '@'.
+func readArg(v string) (string, error) {
+	if strings.HasPrefix(v, "@") {
+		bs, err := os.ReadFile(v[1:])
+		if err != nil {
+			return "", err
+		}
+		v = string(bs)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// decodeBytes decodes hex with 0x prefix, or base64.
+func decodeBytes(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") {
+		return hex.DecodeString(s[2:])
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding,
+		base64.RawStdEncoding, base64.RawURLEncoding} {
+		if bs, err := enc.DecodeString(s); err == nil {
+			return bs, nil
+		}
+	}
+	return nil, fmt.Errorf("neither hex with 0x nor base64")
+}
+
+func parseAccStatus(v string) (*mta.Status, error) {
+	v, err := readArg(v)
+	if err != nil || v == "" {
+		return nil, err
+	}
+	as := &accStatus{}
+	if err = json.Unmarshal([]byte(v), as); err != nil {
+		return nil, err
+	}
+	s := &mta.Status{Height: as.Height, Offset: as.Offset}
+	for _, r := range as.Roots {
+		s.Roots = append(s.Roots, r)
+	}
+	return s, nil
+}
+
+// verifyBlockProof returns the height of the header in the accumulator,
+// which is found by the witness.
+func verifyBlockProof(s *mta.Status, bp *client.BlockProof) (int64, error) {
+	if bp.BlockWitness == nil {
+		return 0, fmt.Errorf("no witness")
+	}
+	h := crypto.SHA3Sum256(bp.Header)
+	for height := s.Offset + 1; height <= s.Height; height++ {
+		if s.VerifyAt(height, bp.BlockWitness.Height, bp.BlockWitness.Witness, h) == nil {
+			return height, nil
+		}
+	}
+	return 0, fmt.Errorf("no header for the witness in the accumulator")
+}
+
+func verifyProof(cmd *cobra.Command, nt *ntm.NetworkType, msg []byte, messagesRoot []byte, s *mta.Status) error {
+	rm := &btp2.BTPRelayMessage{}
+	if _, err := codec.RLP.UnmarshalFromBytes(msg, rm); err != nil {
+		return fmt.Errorf("fail to decode relay message err:%+v", err)
+	}
+	for i, tpm := range rm.Messages {
+		switch tpm.Type {
+		case btp2.RelayMessageTypeBlockUpdate:
+			bu := &client.BTPBlockUpdate{}
+			if _, err := codec.RLP.UnmarshalFromBytes(tpm.Payload, bu); err != nil {
+				return fmt.Errorf("[%d] fail to decode block update err:%+v", i, err)
+			}
+			bh := &client.BTPBlockHeader{}
+			if _, err := nt.HeaderCodec.UnmarshalFromBytes(bu.BTPBlockHeader, bh); err != nil {
+				return fmt.Errorf("[%d] fail to decode header err:%+v", i, err)
+			}
+			messagesRoot = bh.MessagesRoot
+			cmd.Printf("[%d] block update: main_height=%d network_id=%d update_number=%d "+
+				"message_count=%d messages_root=%#x (signatures are not verified)\n",
+				i, bh.MainHeight, bh.NetworkID, bh.UpdateNumber, bh.MessageCount, bh.MessagesRoot)
+		case btp2.RelayMessageTypeMessageProof:
+			p := &mbt.MerkleBinaryTreeProof{}
+			if _, err := codec.RLP.UnmarshalFromBytes(tpm.Payload, p); err != nil {
+				return fmt.Errorf("[%d] fail to decode message proof err:%+v", i, err)
+			}
+			if messagesRoot == nil {
+				return fmt.Errorf("[%d] no messages root for message proof", i)
+			}
+			left, total, err := mbt.VerifyRange(nt.HashFunc, messagesRoot, nil, p)
+			if err != nil {
+				return fmt.Errorf("[%d] invalid message proof err:%+v", i, err)
+			}
+			cmd.Printf("[%d] message proof: messages %d-%d of %d verified\n",
+				i, left+1, left+len(p.Contents), total)
+		case btp2.RelayMessageTypeBlockProof:
+			bp := &client.BlockProof{}
+			if _, err := codec.RLP.UnmarshalFromBytes(tpm.Payload, bp); err != nil {
+				return fmt.Errorf("[%d] fail to decode block proof err:%+v", i, err)
+			}
+			bh := &client.BTPBlockHeader{}
+			if _, err := nt.HeaderCodec.UnmarshalFromBytes(bp.Header, bh); err != nil {
+				return fmt.Errorf("[%d] fail to decode header err:%+v", i, err)
+			}
+			messagesRoot = bh.MessagesRoot
+			if s == nil {
+				cmd.Printf("[%d] block proof: main_height=%d (witness is not verified without --acc_status)\n",
+					i, bh.MainHeight)
+				continue
+			}
+			height, err := verifyBlockProof(s, bp)
+			if err != nil {
+				return fmt.Errorf("[%d] invalid block proof err:%+v", i, err)
+			}
+			cmd.Printf("[%d] block proof: main_height=%d accumulator_height=%d verified\n",
+				i, bh.MainHeight, height)
+		default:
+			return fmt.Errorf("[%d] unknown type %d", i, tpm.Type)
+		}
+	}
+	return nil
+}
+
+func newVerifyProofCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-proof MESSAGE",
+		Short: "Verify proofs in the relay message of icon-btpblock",
+		Long: "Verify proofs in the relay message of icon-btpblock without the database.\n" +
+			"MESSAGE is hex with 0x prefix or base64, '@FILE' for the file.",
+		Args: cli.ArgsWithDefaultErrorFunc(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := readArg(args[0])
+			if err != nil {
+				return err
+			}
+			msg, err := decodeBytes(v)
+			if err != nil {
+				return err
+			}
+			v, _ = cmd.Flags().GetString("network_type")
+			nt, err := ntm.ByName(v)
+			if err != nil {
+				return err
+			}
+			var messagesRoot []byte
+			if v, _ := cmd.Flags().GetString("messages_root"); v != "" {
+				if messagesRoot, err = decodeBytes(v); err != nil {
+					return err
+				}
+			}
+			v, _ = cmd.Flags().GetString("acc_status")
+			s, err := parseAccStatus(v)
+			if err != nil {
+				return err
+			}
+			return verifyProof(cmd, nt, msg, messagesRoot, s)
+		},
+	}
+	flags := cmd.Flags()
+	flags.String("network_type", "eth",
+		fmt.Sprintf("Network type of the BTP network (%s)", strings.Join(ntm.Names(), ",")))
+	flags.String("messages_root", "",
+		"Messages root for message proofs before block updates (hex with 0x prefix)")
+	flags.String("acc_status", "",
+		"Status of the accumulator for block proofs, {\"height\",\"roots\",\"offset\"} in JSON or '@FILE'")
+	return cmd
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/log"
+)
+
+const (
+	DefaultMaxSize = 32 * 1024 * 1024
+)
+
+type Options struct {
+	// MaxSize is the maximum size of values in memory, zero for
+	// DefaultMaxSize and negative to disable the cache.
+	MaxSize int `json:"max_size,omitempty"`
+	// Persist stores values in the database of the receiver, so they are
+	// available after restart.
+	Persist bool `json:"persist,omitempty"`
+}
+
+// Key returns the key for the data of the kind at the height. Keys are
+// ordered by height, so the data of heights can be removed by RemoveFrom
+// and RemoveBelow.
+func Key(height int64, kind byte, id ...[]byte) []byte {
+	size := 9
+	for _, b := range id {
+		size += len(b)
+	}
+	k := make([]byte, 9, size)
+	binary.BigEndian.PutUint64(k, uint64(height))
+	k[8] = kind
+	for _, b := range id {
+		k = append(k, b...)
+	}
+	return k
+}
+
+func heightKey(height int64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(height))
+	return k
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+// Cache is a LRU cache bounded by the size of values, for immutable data
+// such as finalized blocks. If the bucket is set, values are also stored
+// in the bucket. Methods of nil Cache do nothing.
+type Cache struct {
+	mtx     sync.Mutex
+	maxSize int
+	size    int
+	ll      *list.List
+	items   map[string]*list.Element
+	bk      db.Bucket
+}
+
+// New returns the cache with maxSize bytes of values in memory. bk may be
+// nil for the memory only cache.
+func New(maxSize int, bk db.Bucket) *Cache {
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxSize < 0 {
+		return nil
+	}
+	return &Cache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+		bk:      bk,
+	}
+}
+
+// NewWithOptions returns the cache by opt, the bucket of the database is
+// used only if opt.Persist is set.
+func NewWithOptions(opt *Options, database db.Database, id db.BucketID) (*Cache, error) {
+	var bk db.Bucket
+	if opt.Persist && opt.MaxSize >= 0 {
+		var err error
+		if bk, err = database.GetBucket(id); err != nil {
+			return nil, err
+		}
+	}
+	return New(opt.MaxSize, bk), nil
+}
+
+func (c *Cache) add(key string, value []byte) {
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		en := e.Value.(*entry)
+		c.size += len(value) - len(en.value)
+		en.value = value
+	} else {
+		c.items[key] = c.ll.PushFront(&entry{key: key, value: value})
+		c.size += len(value)
+	}
+	for c.size > c.maxSize && c.ll.Len() > 0 {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *Cache) removeElement(e *list.Element) {
+	en := c.ll.Remove(e).(*entry)
+	delete(c.items, en.key)
+	c.size -= len(en.value)
+}
+
+// Get returns the value for the key, nil if it's not cached.
+func (c *Cache) Get(key []byte) []byte {
+	if c == nil || len(key) == 0 {
+		return nil
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if e, ok := c.items[string(key)]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*entry).value
+	}
+	if c.bk == nil {
+		return nil
+	}
+	value, err := c.bk.Get(key)
+	if err != nil {
+		log.Warnf("fail to get cache key:%x err:%+v", key, err)
+		return nil
+	}
+	if value != nil {
+		c.add(string(key), value)
+	}
+	return value
+}
+
+func (c *Cache) Put(key, value []byte) {
+	if c == nil || len(key) == 0 || value == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.add(string(key), value)
+	if c.bk != nil {
+		if err := c.bk.Set(key, value); err != nil {
+			log.Warnf("fail to set cache key:%x err:%+v", key, err)
+		}
+	}
+}
+
+func (c *Cache) removeRange(r *db.Range) {
+	for k, e := range c.items {
+		if r.Contains([]byte(k)) {
+			c.removeElement(e)
+		}
+	}
+	if c.bk == nil {
+		return
+	}
+	keys := make([][]byte, 0)
+	it := c.bk.NewIterator(r)
+	for it.Next() {
+		keys = append(keys, append([]byte{}, it.Key()...))
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		log.Warnf("fail to iterate cache err:%+v", err)
+	}
+	for _, k := range keys {
+		if err = c.bk.Delete(k); err != nil {
+			log.Warnf("fail to delete cache key:%x err:%+v", k, err)
+		}
+	}
+}
+
+// RemoveFrom removes the data of the height and higher, on reorganization
+// of the chain.
+func (c *Cache) RemoveFrom(height int64) {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.removeRange(&db.Range{Start: heightKey(height)})
+}
+
+// RemoveBelow removes the data lower than the height, which is no longer
+// required.
+func (c *Cache) RemoveBelow(height int64) {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.removeRange(&db.Range{Limit: heightKey(height)})
+}
+
+// Size returns the size of values in memory.
+func (c *Cache) Size() int {
+	if c == nil {
+		return 0
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.size
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/db"
+)
+
+func TestCache_Evict(t *testing.T) {
+	c := New(10, nil)
+	c.Put(Key(1, 'h'), []byte("1234"))
+	c.Put(Key(2, 'h'), []byte("5678"))
+	assert.Equal(t, []byte("1234"), c.Get(Key(1, 'h')))
+
+	// the least recently used one is evicted
+	c.Put(Key(3, 'h'), []byte("90"))
+	c.Put(Key(4, 'h'), []byte("ab"))
+	assert.Nil(t, c.Get(Key(2, 'h')))
+	assert.Equal(t, []byte("1234"), c.Get(Key(1, 'h')))
+	assert.Equal(t, 8, c.Size())
+
+	// larger than the cache
+	c.Put(Key(5, 'h'), []byte("0123456789a"))
+	assert.Nil(t, c.Get(Key(5, 'h')))
+	assert.Equal(t, 0, c.Size())
+}
+
+func TestCache_Remove(t *testing.T) {
+	database := db.NewMapDB()
+	bk, _ := database.GetBucket("cache")
+	c := New(0, bk)
+	for h := int64(1); h <= 5; h++ {
+		c.Put(Key(h, 'h'), []byte{byte(h)})
+		c.Put(Key(h, 'm', []byte("nid")), []byte{byte(h)})
+	}
+	c.RemoveFrom(4)
+	c.RemoveBelow(2)
+	for h := int64(1); h <= 5; h++ {
+		if h >= 2 && h < 4 {
+			assert.Equal(t, []byte{byte(h)}, c.Get(Key(h, 'h')))
+			assert.Equal(t, []byte{byte(h)}, c.Get(Key(h, 'm', []byte("nid"))))
+		} else {
+			assert.Nil(t, c.Get(Key(h, 'h')))
+			v, err := bk.Get(Key(h, 'm', []byte("nid")))
+			assert.NoError(t, err)
+			assert.Nil(t, v)
+		}
+	}
+
+	// values in the bucket are loaded by other cache
+	c2 := New(0, bk)
+	assert.Equal(t, []byte{2}, c2.Get(Key(2, 'h')))
+	assert.Equal(t, 1, c2.Size())
+}
+
+func TestCache_Nil(t *testing.T) {
+	c := New(-1, nil)
+	assert.Nil(t, c)
+	c.Put(Key(1, 'h'), []byte("value"))
+	assert.Nil(t, c.Get(Key(1, 'h')))
+	c.RemoveFrom(0)
+	assert.Equal(t, 0, c.Size())
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/icon-project/btp2/common/log"
+)
+
+const (
+	KindHTTP = "http"
+	KindWS   = "ws"
+
+	Redacted = "REDACTED"
+)
+
+// Record is a line of the capture file. For websocket, Request is the
+// request of the subscription, and Response is a message from the server.
+type Record struct {
+	Time     time.Time       `json:"time"`
+	Kind     string          `json:"kind"`
+	URL      string          `json:"url"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Status   int             `json:"status,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type Options struct {
+	// rotating file for records
+	log.WriterConfig
+	// Redact replaces signatures and signed transactions in requests.
+	Redact bool `json:"redact"`
+}
+
+// Recorder writes records to the file as JSON lines.
+type Recorder struct {
+	mtx    sync.Mutex
+	w      io.Writer
+	redact bool
+}
+
+func NewRecorder(w io.Writer, redact bool) *Recorder {
+	return &Recorder{w: w, redact: redact}
+}
+
+var (
+	recordersMtx sync.Mutex
+	recorders    = make(map[string]*Recorder)
+)
+
+// OpenRecorder returns the recorder for the file of opt, clients with
+// the same file share the recorder.
+func OpenRecorder(opt *Options) (*Recorder, error) {
+	recordersMtx.Lock()
+	defer recordersMtx.Unlock()
+	if r, ok := recorders[opt.Filename]; ok {
+		return r, nil
+	}
+	// the writer opens the file on the first record, so check it here
+	if err := os.MkdirAll(filepath.Dir(opt.Filename), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(opt.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	w, err := log.NewWriter(&opt.WriterConfig)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorder(w, opt.Redact)
+	recorders[opt.Filename] = r
+	return r, nil
+}
+
+func (r *Recorder) Record(rec *Record) {
+	rec.Request = toJSON(rec.Request)
+	rec.Response = toJSON(rec.Response)
+	if r.redact {
+		rec.Request = redactRequest(rec.Request)
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	bs, err := json.Marshal(rec)
+	if err != nil {
+		log.Warnf("fail to marshal capture record err:%+v", err)
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, err = r.w.Write(append(bs, '\n')); err != nil {
+		log.Warnf("fail to write capture record err:%+v", err)
+	}
+}
+
+// toJSON returns bs as JSON, or JSON string if it's not valid JSON.
+func toJSON(bs []byte) json.RawMessage {
+	if len(bs) == 0 {
+		return nil
+	}
+	if json.Valid(bs) {
+		return bs
+	}
+	s, _ := json.Marshal(string(bs))
+	return s
+}
+
+// redactMethods are methods with signed transactions in params.
+var redactMethods = map[string]bool{
+	"eth_sendRawTransaction": true,
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if method, ok := t["method"].(string); ok && redactMethods[method] {
+			if _, ok := t["params"]; ok {
+				t["params"] = Redacted
+			}
+		}
+		for k, e := range t {
+			if k == "signature" {
+				t[k] = Redacted
+			} else {
+				t[k] = redactValue(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = redactValue(e)
+		}
+	}
+	return v
+}
+
+func redactRequest(req json.RawMessage) json.RawMessage {
+	if len(req) == 0 {
+		return req
+	}
+	var v interface{}
+	if err := json.Unmarshal(req, &v); err != nil {
+		return req
+	}
+	bs, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return req
+	}
+	return bs
+}
+
+// ReadRecords reads records from the capture file.
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	recs := make([]*Record, 0)
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for s.Scan() {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		rec := &Record{}
+		if err := json.Unmarshal(s.Bytes(), rec); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, s.Err()
+}
@@ -0,0 +1,151 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/log"
+)
+
+func post(t *testing.T, hc *http.Client, url, body string) string {
+	resp, err := hc.Post(url, "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	bs, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(bs)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	height := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := make(map[string]interface{})
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		height++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":{"height":%d}}`, req["id"], height)
+	}))
+	defer srv.Close()
+
+	buf := bytes.NewBuffer(nil)
+	hc := &http.Client{Transport: NewTransport(nil, NewRecorder(buf, true))}
+	post(t, hc, srv.URL, `{"jsonrpc":"2.0","id":1,"method":"last","params":{"a":1,"b":2}}`)
+	post(t, hc, srv.URL, `{"jsonrpc":"2.0","id":2,"method":"last","params":{"b":2,"a":1}}`)
+	post(t, hc, srv.URL, `{"jsonrpc":"2.0","id":3,"method":"send","params":{"signature":"secret"}}`)
+	post(t, hc, srv.URL, `{"jsonrpc":"2.0","id":4,"method":"eth_sendRawTransaction","params":["0xsigned"]}`)
+	assert.NotContains(t, buf.String(), "secret")
+	assert.NotContains(t, buf.String(), "0xsigned")
+
+	recs, err := ReadRecords(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, recs, 4)
+	assert.Equal(t, KindHTTP, recs[0].Kind)
+	assert.Equal(t, http.StatusOK, recs[0].Status)
+
+	// replay with different IDs, in order of records
+	hc = &http.Client{Transport: NewReplayTransport(recs)}
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":10,"result":{"height":1}}`,
+		post(t, hc, "http://replay", `{"jsonrpc":"2.0","id":10,"method":"last","params":{"a":1,"b":2}}`))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":11,"result":{"height":2}}`,
+		post(t, hc, "http://replay", `{"jsonrpc":"2.0","id":11,"method":"last","params":{"a":1,"b":2}}`))
+	// the last one is repeated
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":12,"result":{"height":2}}`,
+		post(t, hc, "http://replay", `{"jsonrpc":"2.0","id":12,"method":"last","params":{"a":1,"b":2}}`))
+
+	_, err = hc.Post("http://replay", "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":13,"method":"unknown"}`))
+	assert.Error(t, err)
+}
+
+func TestReplayBatch(t *testing.T) {
+	recs := []*Record{{
+		Kind:     KindHTTP,
+		Request:  json.RawMessage(`[{"id":1,"method":"a"},{"id":2,"method":"b"}]`),
+		Response: json.RawMessage(`[{"id":2,"result":"B"},{"id":1,"result":"A"}]`),
+	}}
+	hc := &http.Client{Transport: NewReplayTransport(recs)}
+	assert.JSONEq(t, `[{"id":8,"result":"B"},{"id":7,"result":"A"}]`,
+		post(t, hc, "http://replay", `[{"id":7,"method":"a"},{"id":8,"method":"b"}]`))
+}
+
+func TestConn_Websocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, bs, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = conn.WriteMessage(mt, bs); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	buf := bytes.NewBuffer(nil)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := NewDialer(NewRecorder(buf, false), url).Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// messages with 7, 16 and 64 bits lengths, the last one is fragmented
+	msgs := make([]string, 0)
+	for _, n := range []int{10, 1000, 70000} {
+		msgs = append(msgs, fmt.Sprintf(`{"data":"%s"}`, strings.Repeat("a", n)))
+	}
+	for _, msg := range msgs {
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+		assert.NoError(t, conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)))
+		_, bs, err := conn.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, msg, string(bs))
+	}
+
+	recs, err := ReadRecords(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	if assert.Len(t, recs, 2*len(msgs)) {
+		for i, msg := range msgs {
+			assert.Equal(t, KindWS, recs[2*i].Kind)
+			assert.Equal(t, url, recs[2*i].URL)
+			assert.JSONEq(t, msg, string(recs[2*i].Request))
+			assert.Nil(t, recs[2*i].Response)
+			assert.JSONEq(t, msg, string(recs[2*i+1].Response))
+			assert.Nil(t, recs[2*i+1].Request)
+		}
+	}
+}
+
+func TestOpenRecorder(t *testing.T) {
+	dir := t.TempDir()
+	opt := &Options{WriterConfig: log.WriterConfig{Filename: filepath.Join(dir, "sub", "capture.jsonl")}}
+	r, err := OpenRecorder(opt)
+	assert.NoError(t, err)
+	_, err = os.Stat(opt.Filename)
+	assert.NoError(t, err)
+	r2, err := OpenRecorder(opt)
+	assert.NoError(t, err)
+	assert.True(t, r == r2)
+
+	// parent of the file is a file
+	_, err = OpenRecorder(&Options{WriterConfig: log.WriterConfig{
+		Filename: filepath.Join(opt.Filename, "capture.jsonl")}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport records HTTP requests and responses.
+type Transport struct {
+	Base http.RoundTripper
+	r    *Recorder
+}
+
+func NewTransport(base http.RoundTripper, r *Recorder) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, r: r}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &Record{
+		Kind: KindHTTP,
+		URL:  req.URL.String(),
+	}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			bs, _ := io.ReadAll(body)
+			body.Close()
+			rec.Request = toJSON(bs)
+		}
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+		t.r.Record(rec)
+		return nil, err
+	}
+	rec.Status = resp.StatusCode
+	bs, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		rec.Error = err.Error()
+		t.r.Record(rec)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(bs))
+	rec.Response = toJSON(bs)
+	t.r.Record(rec)
+	return resp, nil
+}
+
+// ReplayTransport serves captured responses for JSON-RPC requests.
+// Requests are matched by method and params regardless of IDs, and the
+// IDs of responses are replaced with the ones of requests. Records for
+// the same request are served in order, and the last one is repeated.
+type ReplayTransport struct {
+	mtx     sync.Mutex
+	records map[string][]*Record
+}
+
+func NewReplayTransport(recs []*Record) *ReplayTransport {
+	t := &ReplayTransport{records: make(map[string][]*Record)}
+	for _, rec := range recs {
+		if rec.Kind != KindHTTP {
+			continue
+		}
+		k := requestKey(rec.Request)
+		t.records[k] = append(t.records[k], rec)
+	}
+	return t
+}
+
+// stripIDs removes IDs of JSON-RPC requests, and returns the IDs.
+func stripIDs(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		id := t["id"]
+		delete(t, "id")
+		return []interface{}{id}
+	case []interface{}:
+		ids := make([]interface{}, 0, len(t))
+		for _, e := range t {
+			ids = append(ids, stripIDs(e)...)
+		}
+		return ids
+	}
+	return nil
+}
+
+func requestKey(req json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(req, &v); err != nil {
+		return string(req)
+	}
+	stripIDs(v)
+	// map keys are sorted by json.Marshal
+	bs, _ := json.Marshal(v)
+	return string(bs)
+}
+
+func requestIDs(req []byte) []interface{} {
+	var v interface{}
+	if err := json.Unmarshal(req, &v); err != nil {
+		return nil
+	}
+	return stripIDs(v)
+}
+
+// replaceIDs replaces IDs of responses captured for the request with
+// original IDs, with the IDs of the new request.
+func replaceIDs(resp json.RawMessage, orgIDs, ids []interface{}) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(resp, &v); err != nil {
+		return resp
+	}
+	idOf := func(id interface{}) interface{} {
+		for i, org := range orgIDs {
+			if fmt.Sprint(org) == fmt.Sprint(id) && i < len(ids) {
+				return ids[i]
+			}
+		}
+		return id
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		t["id"] = idOf(t["id"])
+	case []interface{}:
+		for _, e := range t {
+			if m, ok := e.(map[string]interface{}); ok {
+				m["id"] = idOf(m["id"])
+			}
+		}
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return resp
+	}
+	return bs
+}
+
+func (t *ReplayTransport) next(k string) *Record {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	recs := t.records[k]
+	if len(recs) == 0 {
+		return nil
+	}
+	if len(recs) > 1 {
+		t.records[k] = recs[1:]
+	}
+	return recs[0]
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	rec := t.next(requestKey(body))
+	if rec == nil {
+		return nil, fmt.Errorf("no captured response for %s", string(body))
+	}
+	if rec.Error != "" {
+		return nil, fmt.Errorf("%s", rec.Error)
+	}
+	resp := replaceIDs(rec.Response, requestIDs(rec.Request), requestIDs(body))
+	status := rec.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(resp)),
+		ContentLength: int64(len(resp)),
+		Request:       req,
+	}, nil
+}
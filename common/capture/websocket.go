@@ -0,0 +1,187 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxMessageSize limits the size of a websocket message to be recorded,
+// larger messages are skipped.
+const maxMessageSize = 128 * 1024 * 1024
+
+const (
+	opContinuation = 0
+	opText         = 1
+	opBinary       = 2
+)
+
+// frameParser reassembles websocket messages from the stream of a
+// direction after the HTTP handshake.
+type frameParser struct {
+	handshaked bool
+	buf        []byte
+	msg        []byte
+	skip       bool
+	onMessage  func(msg []byte)
+}
+
+func (p *frameParser) write(b []byte) {
+	p.buf = append(p.buf, b...)
+	if !p.handshaked {
+		i := bytes.Index(p.buf, []byte("\r\n\r\n"))
+		if i < 0 {
+			return
+		}
+		p.handshaked = true
+		p.buf = p.buf[i+4:]
+	}
+	for p.next() {
+	}
+}
+
+// next consumes a frame in the buffer, and returns false if the frame is
+// not complete.
+func (p *frameParser) next() bool {
+	if len(p.buf) < 2 {
+		return false
+	}
+	fin := p.buf[0]&0x80 != 0
+	op := p.buf[0] & 0x0f
+	masked := p.buf[1]&0x80 != 0
+	offset := 2
+	length := uint64(p.buf[1] & 0x7f)
+	switch length {
+	case 126:
+		if len(p.buf) < offset+2 {
+			return false
+		}
+		length = uint64(binary.BigEndian.Uint16(p.buf[offset:]))
+		offset += 2
+	case 127:
+		if len(p.buf) < offset+8 {
+			return false
+		}
+		length = binary.BigEndian.Uint64(p.buf[offset:])
+		offset += 8
+	}
+	var mask []byte
+	if masked {
+		if len(p.buf) < offset+4 {
+			return false
+		}
+		mask = p.buf[offset : offset+4]
+		offset += 4
+	}
+	if length > maxMessageSize {
+		// unrecordable stream, stop parsing
+		p.onMessage = nil
+		p.buf = nil
+		return false
+	}
+	if uint64(len(p.buf)-offset) < length {
+		return false
+	}
+	payload := p.buf[offset : offset+int(length)]
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	switch op {
+	case opText, opBinary, opContinuation:
+		if op != opContinuation {
+			p.msg, p.skip = p.msg[:0], false
+		}
+		if !p.skip {
+			if len(p.msg)+len(payload) > maxMessageSize {
+				p.msg, p.skip = p.msg[:0], true
+			} else {
+				p.msg = append(p.msg, payload...)
+			}
+		}
+		if fin && !p.skip && p.onMessage != nil {
+			p.onMessage(append([]byte{}, p.msg...))
+		}
+	}
+	p.buf = p.buf[offset+int(length):]
+	return true
+}
+
+// Conn records websocket messages through the connection. It's used by
+// the dialer of clients, which don't expose messages to be recorded.
+// Sent messages are recorded as requests, and received ones as responses.
+type Conn struct {
+	net.Conn
+	in  frameParser
+	out frameParser
+}
+
+func NewConn(conn net.Conn, r *Recorder, url string) *Conn {
+	c := &Conn{Conn: conn}
+	c.in.onMessage = func(msg []byte) {
+		r.Record(&Record{Kind: KindWS, URL: url, Response: msg})
+	}
+	c.out.onMessage = func(msg []byte) {
+		r.Record(&Record{Kind: KindWS, URL: url, Request: msg})
+	}
+	return c
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.in.write(b[:n])
+	}
+	return n, err
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.out.write(b[:n])
+	}
+	return n, err
+}
+
+// NewDialer returns the websocket dialer recording messages for the url.
+func NewDialer(r *Recorder, url string) *websocket.Dialer {
+	d := &net.Dialer{}
+	return &websocket.Dialer{
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := d.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return NewConn(conn, r, url), nil
+		},
+		NetDialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			td := &tls.Dialer{NetDialer: d}
+			conn, err := td.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return NewConn(conn, r, url), nil
+		},
+	}
+}
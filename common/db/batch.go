@@ -0,0 +1,103 @@
+package db
+
+import "github.com/icon-project/btp2/common/errors"
+
+// Batch collects updates on buckets of the database, and writes them
+// atomically on Write.
+type Batch interface {
+	Set(id BucketID, key, value []byte)
+	Delete(id BucketID, key []byte)
+	Len() int
+	Reset()
+	Write() error
+}
+
+type batchOp struct {
+	id     BucketID
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// batch is Batch with the function writing the operations to the backend.
+type batch struct {
+	ops   []batchOp
+	write func(ops []batchOp) error
+}
+
+func (b *batch) Set(id BucketID, key, value []byte) {
+	b.ops = append(b.ops, batchOp{
+		id:    id,
+		key:   append([]byte{}, key...),
+		value: append([]byte{}, value...),
+	})
+}
+
+func (b *batch) Delete(id BucketID, key []byte) {
+	b.ops = append(b.ops, batchOp{
+		id:     id,
+		key:    append([]byte{}, key...),
+		delete: true,
+	})
+}
+
+func (b *batch) Len() int {
+	return len(b.ops)
+}
+
+func (b *batch) Reset() {
+	b.ops = nil
+}
+
+func (b *batch) Write() error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	return b.write(b.ops)
+}
+
+func newBatch(write func(ops []batchOp) error) Batch {
+	return &batch{write: write}
+}
+
+// ReadBucket is read-only Bucket used for Snapshot.
+type ReadBucket interface {
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	NewIterator(r *Range) Iterator
+}
+
+// Snapshot is the frozen state of the database, which is not affected by
+// updates after creation. It should be released after use.
+type Snapshot interface {
+	GetBucket(id BucketID) (ReadBucket, error)
+	Release()
+}
+
+// Snapshotter is implemented by the databases supporting Snapshot.
+type Snapshotter interface {
+	NewSnapshot() (Snapshot, error)
+}
+
+// NewSnapshot returns the snapshot of the database. It returns
+// UnsupportedError if the database doesn't support it.
+func NewSnapshot(database Database) (Snapshot, error) {
+	if s, ok := database.(Snapshotter); ok {
+		return s.NewSnapshot()
+	}
+	return nil, errors.UnsupportedError.Errorf("snapshot is not supported by %T", database)
+}
+
+// Compactor is implemented by the databases supporting compaction.
+type Compactor interface {
+	Compact() error
+}
+
+// Compact compacts the whole database. It returns UnsupportedError if the
+// database doesn't support it.
+func Compact(database Database) error {
+	if c, ok := Unwrap(database).(Compactor); ok {
+		return c.Compact()
+	}
+	return errors.UnsupportedError.Errorf("compaction is not supported by %T", database)
+}
@@ -1,8 +1,8 @@
 package db
 
 import (
-	"github.com/icon-project/btp2/common/crypto"
 	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/ntm"
 )
 
 // Bucket
@@ -11,6 +11,9 @@ type Bucket interface {
 	Has(key []byte) (bool, error)
 	Set(key []byte, value []byte) error
 	Delete(key []byte) error
+	// NewIterator returns iterator over the keys in the range, nil range
+	// for all keys in the bucket.
+	NewIterator(r *Range) Iterator
 }
 
 type BucketID string
@@ -20,30 +23,48 @@ type Hasher interface {
 	Hash(value []byte) []byte
 }
 
-type sha3Hasher struct{}
+// hashFuncHasher is the hasher with the hash function registered in ntm.
+type hashFuncHasher struct {
+	name string
+	f    ntm.HashFunc
+}
 
-func (h sha3Hasher) Name() string {
-	return "sha3"
+func (h hashFuncHasher) Name() string {
+	return h.name
 }
 
-func (h sha3Hasher) Hash(v []byte) []byte {
-	return crypto.SHA3Sum256(v)
+func (h hashFuncHasher) Hash(v []byte) []byte {
+	return h.f(v)
 }
 
-var hasherMap = map[BucketID]Hasher{
-	MerkleTrie:  sha3Hasher{},
-	BytesByHash: sha3Hasher{},
+// hasherMap maps buckets to names of hash functions in ntm.
+var hasherMap = map[BucketID]string{
+	MerkleTrie:  ntm.HashSha3FIPS256,
+	BytesByHash: ntm.HashSha3FIPS256,
 }
 
-func RegisterHasher(bk BucketID, hasher Hasher) {
+// RegisterHasher sets the hash function for keys of the bucket, it panics
+// on duplicated buckets or unknown hash functions.
+func RegisterHasher(bk BucketID, name string) {
 	if _, ok := hasherMap[bk]; ok {
 		panic("Duplicate BucketID")
 	}
-	hasherMap[bk] = hasher
+	if _, err := ntm.HashFuncByName(name); err != nil {
+		panic(err)
+	}
+	hasherMap[bk] = name
 }
 
 func (bk BucketID) Hasher() Hasher {
-	return hasherMap[bk]
+	name, ok := hasherMap[bk]
+	if !ok {
+		return nil
+	}
+	f, err := ntm.HashFuncByName(name)
+	if err != nil {
+		return nil
+	}
+	return hashFuncHasher{name: name, f: f}
 }
 
 //	Bucket ID
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func conformanceCreators() map[string]dbCreator {
+	creators := make(map[string]dbCreator)
+	for name, be := range backends {
+		creators[string(name)] = be
+	}
+	creators["layerdb"] = func(name string, dir string) (Database, error) {
+		return NewLayerDB(NewMapDB()), nil
+	}
+	creators["layerdb-flushed"] = func(name string, dir string) (Database, error) {
+		ldb := NewLayerDB(NewMapDB())
+		if err := ldb.Flush(true); err != nil {
+			return nil, err
+		}
+		return ldb, nil
+	}
+	return creators
+}
+
+func runConformance(t *testing.T, f func(t *testing.T, database Database)) {
+	for name, creator := range conformanceCreators() {
+		t.Run(name, func(t *testing.T) {
+			database, err := creator("test", t.TempDir())
+			assert.NoError(t, err)
+			defer database.Close()
+			f(t, database)
+		})
+	}
+}
+
+func collect(t *testing.T, it Iterator) []string {
+	defer it.Release()
+	var kvs []string
+	for it.Next() {
+		kvs = append(kvs, fmt.Sprintf("%s=%s", it.Key(), it.Value()))
+	}
+	assert.NoError(t, it.Error())
+	return kvs
+}
+
+func setAll(t *testing.T, bk Bucket, kvs ...string) {
+	for i := 0; i < len(kvs); i += 2 {
+		assert.NoError(t, bk.Set([]byte(kvs[i]), []byte(kvs[i+1])))
+	}
+}
+
+func TestConformance_Iterator(t *testing.T) {
+	runConformance(t, func(t *testing.T, database Database) {
+		bk, err := database.GetBucket("A|")
+		assert.NoError(t, err)
+		other, err := database.GetBucket("B|")
+		assert.NoError(t, err)
+
+		setAll(t, bk, "k3", "v3", "k1", "v1", "x1", "w1", "k2", "v2")
+		setAll(t, other, "k0", "o0", "k4", "o4")
+
+		assert.Equal(t, []string{"k1=v1", "k2=v2", "k3=v3", "x1=w1"},
+			collect(t, bk.NewIterator(nil)))
+		assert.Equal(t, []string{"k1=v1", "k2=v2", "k3=v3"},
+			collect(t, bk.NewIterator(PrefixRange([]byte("k")))))
+		assert.Equal(t, []string{"k2=v2", "k3=v3"},
+			collect(t, bk.NewIterator(&Range{Start: []byte("k2"), Limit: []byte("x")})))
+		assert.Equal(t, []string{"k1=v1"},
+			collect(t, bk.NewIterator(&Range{Limit: []byte("k2")})))
+		assert.Nil(t, collect(t, bk.NewIterator(PrefixRange([]byte("z")))))
+
+		assert.NoError(t, bk.Delete([]byte("k2")))
+		assert.Equal(t, []string{"k1=v1", "k3=v3"},
+			collect(t, bk.NewIterator(PrefixRange([]byte("k")))))
+	})
+}
+
+func TestConformance_Batch(t *testing.T) {
+	runConformance(t, func(t *testing.T, database Database) {
+		bk, err := database.GetBucket("A|")
+		assert.NoError(t, err)
+		setAll(t, bk, "k1", "v1", "k2", "v2")
+
+		b := database.NewBatch()
+		b.Set("A|", []byte("k3"), []byte("v3"))
+		b.Delete("A|", []byte("k1"))
+		b.Set("B|", []byte("k1"), []byte("o1"))
+		assert.Equal(t, 3, b.Len())
+
+		// nothing is written before Write
+		v, err := bk.Get([]byte("k3"))
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+
+		assert.NoError(t, b.Write())
+		assert.Equal(t, []string{"k2=v2", "k3=v3"}, collect(t, bk.NewIterator(nil)))
+		other, err := database.GetBucket("B|")
+		assert.NoError(t, err)
+		v, err = other.Get([]byte("k1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("o1"), v)
+
+		b.Reset()
+		assert.Equal(t, 0, b.Len())
+		assert.NoError(t, b.Write())
+	})
+}
+
+func TestConformance_Snapshot(t *testing.T) {
+	runConformance(t, func(t *testing.T, database Database) {
+		bk, err := database.GetBucket("A|")
+		assert.NoError(t, err)
+		setAll(t, bk, "k1", "v1", "k2", "v2")
+
+		ss, err := NewSnapshot(database)
+		if _, ok := database.(Snapshotter); !ok {
+			assert.Error(t, err)
+			return
+		}
+		assert.NoError(t, err)
+		defer ss.Release()
+
+		setAll(t, bk, "k1", "v1'", "k3", "v3")
+		assert.NoError(t, bk.Delete([]byte("k2")))
+
+		sbk, err := ss.GetBucket("A|")
+		assert.NoError(t, err)
+		v, err := sbk.Get([]byte("k1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v1"), v)
+		has, err := sbk.Has([]byte("k3"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+		assert.Equal(t, []string{"k1=v1", "k2=v2"}, collect(t, sbk.NewIterator(nil)))
+		assert.Equal(t, []string{"k1=v1'", "k3=v3"}, collect(t, bk.NewIterator(nil)))
+	})
+}
+
+func TestConformance_PrefixedBuckets(t *testing.T) {
+	runConformance(t, func(t *testing.T, database Database) {
+		root, err := database.GetBucket("")
+		assert.NoError(t, err)
+		bk, err := database.GetBucket("A|")
+		assert.NoError(t, err)
+		setAll(t, root, "k1", "v1", "k2", "v2")
+		setAll(t, bk, "k1", "a1", "k3", "a3")
+
+		assert.Equal(t, []string{"k1=v1", "k2=v2"}, collect(t, root.NewIterator(nil)))
+		assert.Equal(t, []string{"k1=a1", "k3=a3"}, collect(t, bk.NewIterator(nil)))
+
+		ss, err := NewSnapshot(database)
+		if err != nil {
+			return
+		}
+		defer ss.Release()
+		sroot, err := ss.GetBucket("")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"k1=v1", "k2=v2"}, collect(t, sroot.NewIterator(nil)))
+	})
+}
+
+func TestConformance_BatchAfterClose(t *testing.T) {
+	for name, creator := range backends {
+		if name == MapDBBackend {
+			continue
+		}
+		t.Run(string(name), func(t *testing.T) {
+			database, err := creator("test", t.TempDir())
+			assert.NoError(t, err)
+			b := database.NewBatch()
+			b.Set("A|", []byte("k1"), []byte("v1"))
+			assert.NoError(t, database.Close())
+			assert.Error(t, b.Write())
+		})
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	assert.Equal(t, &Range{Start: []byte{0x01, 0xff}, Limit: []byte{0x02}},
+		PrefixRange([]byte{0x01, 0xff}))
+	assert.Equal(t, &Range{Start: []byte{0xff, 0xff}}, PrefixRange([]byte{0xff, 0xff}))
+
+	r := PrefixRange([]byte("ab"))
+	assert.True(t, r.Contains([]byte("ab")))
+	assert.True(t, r.Contains([]byte("abz")))
+	assert.False(t, r.Contains([]byte("ac")))
+	assert.False(t, r.Contains([]byte("a")))
+}
@@ -8,6 +8,7 @@ import (
 
 type Database interface {
 	GetBucket(id BucketID) (Bucket, error)
+	NewBatch() Batch
 	Close() error
 }
 
@@ -75,6 +76,7 @@ func (e *errorBucket) Get(key []byte) ([]byte, error)     { return nil, e.error
 func (e *errorBucket) Has(key []byte) (bool, error)       { return false, e.error }
 func (e *errorBucket) Set(key []byte, value []byte) error { return e.error }
 func (e *errorBucket) Delete(key []byte) error            { return e.error }
+func (e *errorBucket) NewIterator(r *Range) Iterator      { return &errorIterator{e.error} }
 
 // BucketOf returns valid bucket always, but it
 func BucketOf(database Database, id BucketID) Bucket {
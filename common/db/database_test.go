@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/ntm"
 )
 
 func testDatabase_GetSetDelete(t *testing.T, creator dbCreator) {
@@ -258,3 +260,25 @@ func TestDatabase_OperationAfterClose(t *testing.T) {
 		})
 	}
 }
+
+func TestBucketID_Hasher(t *testing.T) {
+	h := BytesByHash.Hasher()
+	if assert.NotNil(t, h) {
+		assert.Equal(t, ntm.HashSha3FIPS256, h.Name())
+		assert.Equal(t, ntm.Sha3FIPS256([]byte("hello")), h.Hash([]byte("hello")))
+	}
+	assert.Nil(t, BucketID("unknown").Hasher())
+
+	assert.Panics(t, func() {
+		RegisterHasher(BytesByHash, ntm.HashSha3Keccak256)
+	})
+	assert.Panics(t, func() {
+		RegisterHasher(BucketID("unknown"), "unknown")
+	})
+	RegisterHasher(BucketID("keccak"), ntm.HashSha3Keccak256)
+	defer delete(hasherMap, BucketID("keccak"))
+	h = BucketID("keccak").Hasher()
+	if assert.NotNil(t, h) {
+		assert.Equal(t, ntm.Sha3Keccak256([]byte("hello")), h.Hash([]byte("hello")))
+	}
+}
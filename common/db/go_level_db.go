@@ -1,11 +1,15 @@
 package db
 
 import (
+	"bytes"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const GoLevelDBBackend BackendType = "goleveldb"
@@ -38,6 +42,8 @@ func NewGoLevelDBWithOpts(name string, dir string, o *opt.Options) (*GoLevelDB,
 // Database
 
 var _ Database = (*GoLevelDB)(nil)
+var _ Snapshotter = (*GoLevelDB)(nil)
+var _ Compactor = (*GoLevelDB)(nil)
 
 type GoLevelDB struct {
 	lock    sync.Mutex
@@ -57,14 +63,74 @@ func (db *GoLevelDB) GetBucket(id BucketID) (Bucket, error) {
 		return bk, nil
 	} else {
 		bk = &goLevelBucket{
-			id: id,
-			db: db.db,
+			id:       id,
+			db:       db.db,
+			excludes: db.excludes,
 		}
 		db.buckets[id] = bk
 		return bk, nil
 	}
 }
 
+func (db *GoLevelDB) NewBatch() Batch {
+	return newBatch(func(ops []batchOp) error {
+		b := new(leveldb.Batch)
+		for _, op := range ops {
+			if op.delete {
+				b.Delete(internalKey(op.id, op.key))
+			} else {
+				b.Put(internalKey(op.id, op.key), op.value)
+			}
+		}
+		db.lock.Lock()
+		defer db.lock.Unlock()
+
+		if db.db == nil {
+			return leveldb.ErrClosed
+		}
+		return db.db.Write(b, nil)
+	})
+}
+
+func (db *GoLevelDB) NewSnapshot() (Snapshot, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return nil, leveldb.ErrClosed
+	}
+	ss, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &goLevelSnapshot{ss: ss, excludes: db.excludes}, nil
+}
+
+// excludes returns prefixes of the other buckets having the id as prefix,
+// whose keys are in the range of the bucket.
+func (db *GoLevelDB) excludes(id BucketID) [][]byte {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var prefixes [][]byte
+	for other := range db.buckets {
+		if len(other) > len(id) && strings.HasPrefix(string(other), string(id)) {
+			prefixes = append(prefixes, []byte(other))
+		}
+	}
+	return prefixes
+}
+
+func (db *GoLevelDB) Compact() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return leveldb.ErrClosed
+	}
+	return db.db.CompactRange(util.Range{})
+}
+
 func (db *GoLevelDB) Close() error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -85,8 +151,9 @@ func (db *GoLevelDB) Close() error {
 var _ Bucket = (*goLevelBucket)(nil)
 
 type goLevelBucket struct {
-	id BucketID
-	db *leveldb.DB
+	id       BucketID
+	db       *leveldb.DB
+	excludes func(id BucketID) [][]byte
 }
 
 func (bucket *goLevelBucket) Get(key []byte) ([]byte, error) {
@@ -109,3 +176,105 @@ func (bucket *goLevelBucket) Set(key []byte, value []byte) error {
 func (bucket *goLevelBucket) Delete(key []byte) error {
 	return bucket.db.Delete(internalKey(bucket.id, key), nil)
 }
+
+func (bucket *goLevelBucket) NewIterator(r *Range) Iterator {
+	return newGoLevelIterator(bucket.id, r, bucket.excludes(bucket.id), bucket.db.NewIterator)
+}
+
+// internalRange returns the range of internal keys for the range in the
+// bucket. Note that keys of other buckets prefixed with the id are also
+// in the range, so iterators skip keys of the buckets opened in the
+// database (e.g. the bucket "" covers all the keys).
+func internalRange(id BucketID, r *Range) *util.Range {
+	ir := util.BytesPrefix([]byte(id))
+	if r == nil {
+		return ir
+	}
+	if r.Start != nil {
+		ir.Start = internalKey(id, r.Start)
+	}
+	if r.Limit != nil {
+		ir.Limit = internalKey(id, r.Limit)
+	}
+	return ir
+}
+
+type goLevelIterator struct {
+	iterator.Iterator
+	id       BucketID
+	excludes [][]byte
+}
+
+func (it *goLevelIterator) Next() bool {
+	for it.Iterator.Next() {
+		if !it.excluded(it.Iterator.Key()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *goLevelIterator) excluded(key []byte) bool {
+	for _, prefix := range it.excludes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *goLevelIterator) Key() []byte {
+	key := it.Iterator.Key()
+	if key == nil {
+		return nil
+	}
+	return key[len(it.id):]
+}
+
+func newGoLevelIterator(id BucketID, r *Range, excludes [][]byte,
+	f func(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator) Iterator {
+	return &goLevelIterator{
+		Iterator: f(internalRange(id, r), nil),
+		id:       id,
+		excludes: excludes,
+	}
+}
+
+//----------------------------------------
+// Snapshot
+
+type goLevelSnapshot struct {
+	ss       *leveldb.Snapshot
+	excludes func(id BucketID) [][]byte
+}
+
+func (s *goLevelSnapshot) GetBucket(id BucketID) (ReadBucket, error) {
+	return &goLevelSnapshotBucket{id: id, ss: s.ss, excludes: s.excludes}, nil
+}
+
+func (s *goLevelSnapshot) Release() {
+	s.ss.Release()
+}
+
+type goLevelSnapshotBucket struct {
+	id       BucketID
+	ss       *leveldb.Snapshot
+	excludes func(id BucketID) [][]byte
+}
+
+func (bucket *goLevelSnapshotBucket) Get(key []byte) ([]byte, error) {
+	value, err := bucket.ss.Get(internalKey(bucket.id, key), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else {
+		return value, err
+	}
+}
+
+func (bucket *goLevelSnapshotBucket) Has(key []byte) (bool, error) {
+	return bucket.ss.Has(internalKey(bucket.id, key), nil)
+}
+
+func (bucket *goLevelSnapshotBucket) NewIterator(r *Range) Iterator {
+	return newGoLevelIterator(bucket.id, r, bucket.excludes(bucket.id), bucket.ss.NewIterator)
+}
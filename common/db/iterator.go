@@ -0,0 +1,120 @@
+package db
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Range is the range of keys in a bucket, Start is included and Limit is
+// excluded. nil for Start or Limit means unbounded.
+type Range struct {
+	Start []byte
+	Limit []byte
+}
+
+// PrefixRange returns the range of keys with the prefix.
+func PrefixRange(prefix []byte) *Range {
+	var limit []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if c := prefix[i]; c < 0xff {
+			limit = make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i] = c + 1
+			break
+		}
+	}
+	return &Range{Start: prefix, Limit: limit}
+}
+
+// Contains returns whether the key is in the range. nil range contains
+// all keys.
+func (r *Range) Contains(key []byte) bool {
+	if r == nil {
+		return true
+	}
+	if r.Start != nil && bytes.Compare(key, r.Start) < 0 {
+		return false
+	}
+	if r.Limit != nil && bytes.Compare(key, r.Limit) >= 0 {
+		return false
+	}
+	return true
+}
+
+// Iterator iterates key-value pairs in ascending order of keys.
+// Key and Value are valid only until the next call of Next. Release
+// should be called after use.
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Release()
+}
+
+type kvPair struct {
+	key   []byte
+	value []byte
+}
+
+// sliceIterator iterates over the pairs collected from the bucket.
+type sliceIterator struct {
+	pairs []kvPair
+	idx   int
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.idx >= len(it.pairs) {
+		it.idx = len(it.pairs) + 1
+		return false
+	}
+	it.idx++
+	return true
+}
+
+func (it *sliceIterator) current() *kvPair {
+	if it.idx < 1 || it.idx > len(it.pairs) {
+		return nil
+	}
+	return &it.pairs[it.idx-1]
+}
+
+func (it *sliceIterator) Key() []byte {
+	if p := it.current(); p != nil {
+		return p.key
+	}
+	return nil
+}
+
+func (it *sliceIterator) Value() []byte {
+	if p := it.current(); p != nil {
+		return p.value
+	}
+	return nil
+}
+
+func (it *sliceIterator) Error() error {
+	return nil
+}
+
+func (it *sliceIterator) Release() {
+	it.pairs = nil
+	it.idx = 0
+}
+
+func newSliceIterator(pairs []kvPair) *sliceIterator {
+	sort.Slice(pairs, func(i, j int) bool {
+		return bytes.Compare(pairs[i].key, pairs[j].key) < 0
+	})
+	return &sliceIterator{pairs: pairs}
+}
+
+type errorIterator struct {
+	error
+}
+
+func (e *errorIterator) Next() bool    { return false }
+func (e *errorIterator) Key() []byte   { return nil }
+func (e *errorIterator) Value() []byte { return nil }
+func (e *errorIterator) Error() error  { return e.error }
+func (e *errorIterator) Release()      {}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"sort"
 	"sync"
 )
 
@@ -60,6 +61,37 @@ func (bk *layerBucket) Delete(key []byte) error {
 	}
 }
 
+func (bk *layerBucket) NewIterator(r *Range) Iterator {
+	bk.lock.Lock()
+	defer bk.lock.Unlock()
+
+	if bk.data == nil {
+		return bk.real.NewIterator(r)
+	}
+	values := make(map[string][]byte)
+	it := bk.real.NewIterator(r)
+	for it.Next() {
+		values[string(it.Key())] = append([]byte{}, it.Value()...)
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		return &errorIterator{err}
+	}
+	for k, v := range bk.data {
+		if r.Contains([]byte(k)) {
+			values[k] = v
+		}
+	}
+	pairs := make([]kvPair, 0, len(values))
+	for k, v := range values {
+		if v != nil {
+			pairs = append(pairs, kvPair{[]byte(k), v})
+		}
+	}
+	return newSliceIterator(pairs)
+}
+
 func (bk *layerBucket) Flush(write bool) error {
 	bk.lock.Lock()
 	defer bk.lock.Unlock()
@@ -93,6 +125,10 @@ func (ldb *layerDB) GetBucket(id BucketID) (Bucket, error) {
 	ldb.lock.Lock()
 	defer ldb.lock.Unlock()
 
+	return ldb.getBucket(id)
+}
+
+func (ldb *layerDB) getBucket(id BucketID) (Bucket, error) {
 	if bk, ok := ldb.buckets[string(id)]; ok {
 		return bk, nil
 	}
@@ -112,6 +148,57 @@ func (ldb *layerDB) GetBucket(id BucketID) (Bucket, error) {
 	return bk, nil
 }
 
+func (ldb *layerDB) NewBatch() Batch {
+	return newBatch(ldb.writeBatch)
+}
+
+// writeBatch applies the operations to the layer before flush, or writes
+// them to the real database after flush.
+func (ldb *layerDB) writeBatch(ops []batchOp) error {
+	ldb.lock.Lock()
+	defer ldb.lock.Unlock()
+
+	if ldb.flushed {
+		b := ldb.real.NewBatch()
+		for _, op := range ops {
+			if op.delete {
+				b.Delete(op.id, op.key)
+			} else {
+				b.Set(op.id, op.key, op.value)
+			}
+		}
+		return b.Write()
+	}
+
+	ids := make([]string, 0)
+	for _, op := range ops {
+		if _, ok := ldb.buckets[string(op.id)]; !ok {
+			if _, err := ldb.getBucket(op.id); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, string(op.id))
+	}
+	sort.Strings(ids)
+	for i, id := range ids {
+		if i > 0 && ids[i-1] == id {
+			continue
+		}
+		bk := ldb.buckets[id]
+		bk.lock.Lock()
+		defer bk.lock.Unlock()
+	}
+	for _, op := range ops {
+		bk := ldb.buckets[string(op.id)]
+		if op.delete {
+			bk.data[string(op.key)] = nil
+		} else {
+			bk.data[string(op.key)] = op.value
+		}
+	}
+	return nil
+}
+
 func (ldb *layerDB) Flush(write bool) error {
 	ldb.lock.Lock()
 	defer ldb.lock.Unlock()
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/icon-project/btp2/common/log"
@@ -32,6 +33,7 @@ func NewMapDB() Database {
 // DB
 
 var _ Database = (*mapDatabase)(nil)
+var _ Snapshotter = (*mapDatabase)(nil)
 
 type mapDatabase struct {
 	lock sync.Mutex
@@ -43,15 +45,65 @@ func (t *mapDatabase) GetBucket(id BucketID) (Bucket, error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	return t.getBucket(id), nil
+}
+
+func (t *mapDatabase) getBucket(id BucketID) *mapBucket {
 	if bk, ok := t.bks[id]; ok {
-		return bk, nil
+		return bk
 	}
 	bk := &mapBucket{
 		id:   fmt.Sprintf("%s:%s", t.name, id),
 		real: make(map[string]string),
 	}
 	t.bks[id] = bk
-	return bk, nil
+	return bk
+}
+
+func (t *mapDatabase) NewBatch() Batch {
+	return newBatch(t.writeBatch)
+}
+
+func (t *mapDatabase) writeBatch(ops []batchOp) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ids := make([]string, 0)
+	bks := make(map[string]*mapBucket)
+	for _, op := range ops {
+		if _, ok := bks[string(op.id)]; !ok {
+			bks[string(op.id)] = t.getBucket(op.id)
+			ids = append(ids, string(op.id))
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		bk := bks[id]
+		bk.mutex.Lock()
+		defer bk.mutex.Unlock()
+	}
+	for _, op := range ops {
+		bk := bks[string(op.id)]
+		if op.delete {
+			delete(bk.real, string(op.key))
+		} else {
+			bk.real[string(op.key)] = string(op.value)
+		}
+	}
+	return nil
+}
+
+func (t *mapDatabase) NewSnapshot() (Snapshot, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ss := &mapSnapshot{
+		bks: make(map[BucketID]*mapBucket),
+	}
+	for id, bk := range t.bks {
+		ss.bks[id] = bk.clone()
+	}
+	return ss, nil
 }
 
 func (t *mapDatabase) Close() error {
@@ -115,3 +167,43 @@ func (t *mapBucket) Delete(k []byte) error {
 	delete(t.real, string(k))
 	return nil
 }
+
+func (t *mapBucket) NewIterator(r *Range) Iterator {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	pairs := make([]kvPair, 0)
+	for k, v := range t.real {
+		if r.Contains([]byte(k)) {
+			pairs = append(pairs, kvPair{[]byte(k), []byte(v)})
+		}
+	}
+	return newSliceIterator(pairs)
+}
+
+func (t *mapBucket) clone() *mapBucket {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	real := make(map[string]string, len(t.real))
+	for k, v := range t.real {
+		real[k] = v
+	}
+	return &mapBucket{id: t.id, real: real}
+}
+
+//----------------------------------------
+// Snapshot
+
+type mapSnapshot struct {
+	bks map[BucketID]*mapBucket
+}
+
+func (s *mapSnapshot) GetBucket(id BucketID) (ReadBucket, error) {
+	if bk, ok := s.bks[id]; ok {
+		return bk, nil
+	}
+	return &mapBucket{id: string(id), real: map[string]string{}}, nil
+}
+
+func (s *mapSnapshot) Release() {
+	s.bks = nil
+}
@@ -20,6 +20,7 @@
 package db
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"path"
@@ -199,7 +200,7 @@ func unsafePointerOf(p []byte) unsafe.Pointer {
 	}
 }
 
-func (db *RocksDB) getValue(cf *C.rocksdb_column_family_handle_t, k []byte) ([]byte, error) {
+func (db *RocksDB) getValue(ro *C.rocksdb_readoptions_t, cf *C.rocksdb_column_family_handle_t, k []byte) ([]byte, error) {
 	db.lock.RLock()
 	defer db.lock.RUnlock()
 
@@ -211,7 +212,7 @@ func (db *RocksDB) getValue(cf *C.rocksdb_column_family_handle_t, k []byte) ([]b
 		cValLen C.size_t
 		cKey    = (*C.char)(unsafePointerOf(k))
 	)
-	cValue := C.rocksdb_get_cf(db.db, db.ro, cf, cKey, C.size_t(len(k)), &cValLen, &cErr)
+	cValue := C.rocksdb_get_cf(db.db, ro, cf, cKey, C.size_t(len(k)), &cValLen, &cErr)
 	if cErr != nil {
 		defer C.rocksdb_free(unsafe.Pointer(cErr))
 		return nil, errors.New(C.GoString(cErr))
@@ -228,7 +229,7 @@ func (db *RocksDB) getValue(cf *C.rocksdb_column_family_handle_t, k []byte) ([]b
 	return value, nil
 }
 
-func (db *RocksDB) hasValue(cf *C.rocksdb_column_family_handle_t, k []byte) (bool, error) {
+func (db *RocksDB) hasValue(ro *C.rocksdb_readoptions_t, cf *C.rocksdb_column_family_handle_t, k []byte) (bool, error) {
 	db.lock.RLock()
 	defer db.lock.RUnlock()
 
@@ -240,7 +241,7 @@ func (db *RocksDB) hasValue(cf *C.rocksdb_column_family_handle_t, k []byte) (boo
 		cValLen C.size_t
 		cKey    = (*C.char)(unsafePointerOf(k))
 	)
-	cValue := C.rocksdb_get_cf(db.db, db.ro, cf, cKey, C.size_t(len(k)), &cValLen, &cErr)
+	cValue := C.rocksdb_get_cf(db.db, ro, cf, cKey, C.size_t(len(k)), &cValLen, &cErr)
 	if cErr != nil {
 		defer C.rocksdb_free(unsafe.Pointer(cErr))
 		return false, errors.New(C.GoString(cErr))
@@ -288,17 +289,112 @@ func (db *RocksDB) deleteValue(cf *C.rocksdb_column_family_handle_t, k []byte) e
 	return nil
 }
 
+func (db *RocksDB) NewBatch() Batch {
+	return newBatch(db.writeBatch)
+}
+
+func (db *RocksDB) writeBatch(ops []batchOp) error {
+	cfs := make([]*C.rocksdb_column_family_handle_t, len(ops))
+	for i, op := range ops {
+		bk, err := db.GetBucket(op.id)
+		if err != nil {
+			return err
+		}
+		cfs[i] = bk.(*RocksBucket).cf
+	}
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.db == nil {
+		return ErrAlreadyClosed
+	}
+	wb := C.rocksdb_writebatch_create()
+	defer C.rocksdb_writebatch_destroy(wb)
+	for i, op := range ops {
+		cKey := (*C.char)(unsafePointerOf(op.key))
+		if op.delete {
+			C.rocksdb_writebatch_delete_cf(wb, cfs[i], cKey, C.size_t(len(op.key)))
+		} else {
+			cValue := (*C.char)(unsafePointerOf(op.value))
+			C.rocksdb_writebatch_put_cf(wb, cfs[i], cKey, C.size_t(len(op.key)),
+				cValue, C.size_t(len(op.value)))
+		}
+	}
+	var cErr *C.char
+	C.rocksdb_write(db.db, db.wo, wb, &cErr)
+	if cErr != nil {
+		defer C.rocksdb_free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+func (db *RocksDB) newIterator(ro *C.rocksdb_readoptions_t, cf *C.rocksdb_column_family_handle_t, r *Range) Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.db == nil {
+		return &errorIterator{ErrAlreadyClosed}
+	}
+	iter := C.rocksdb_create_iterator_cf(db.db, ro, cf)
+	if r != nil && r.Start != nil {
+		C.rocksdb_iter_seek(iter, (*C.char)(unsafePointerOf(r.Start)), C.size_t(len(r.Start)))
+	} else {
+		C.rocksdb_iter_seek_to_first(iter)
+	}
+	it := &rocksIterator{
+		db:   db,
+		iter: iter,
+	}
+	if r != nil {
+		it.limit = r.Limit
+	}
+	return it
+}
+
+func (db *RocksDB) Compact() error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.db == nil {
+		return ErrAlreadyClosed
+	}
+	C.rocksdb_compact_range(db.db, nil, 0, nil, 0)
+	for _, bk := range db.buckets {
+		C.rocksdb_compact_range_cf(db.db, bk.cf, nil, 0, nil, 0)
+	}
+	return nil
+}
+
+func (db *RocksDB) NewSnapshot() (Snapshot, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.db == nil {
+		return nil, ErrAlreadyClosed
+	}
+	snap := C.rocksdb_create_snapshot(db.db)
+	ro := C.rocksdb_readoptions_create()
+	C.rocksdb_readoptions_set_snapshot(ro, snap)
+	return &rocksSnapshot{
+		db:   db,
+		snap: snap,
+		ro:   ro,
+	}, nil
+}
+
 type RocksBucket struct {
 	cf *C.rocksdb_column_family_handle_t
 	db *RocksDB
 }
 
 func (b *RocksBucket) Get(key []byte) ([]byte, error) {
-	return b.db.getValue(b.cf, key)
+	return b.db.getValue(b.db.ro, b.cf, key)
 }
 
 func (b *RocksBucket) Has(key []byte) (bool, error) {
-	return b.db.hasValue(b.cf, key)
+	return b.db.hasValue(b.db.ro, b.cf, key)
 }
 
 func (b *RocksBucket) Set(key []byte, value []byte) error {
@@ -308,3 +404,124 @@ func (b *RocksBucket) Set(key []byte, value []byte) error {
 func (b *RocksBucket) Delete(key []byte) error {
 	return b.db.deleteValue(b.cf, key)
 }
+
+func (b *RocksBucket) NewIterator(r *Range) Iterator {
+	return b.db.newIterator(b.db.ro, b.cf, r)
+}
+
+type rocksIterator struct {
+	db      *RocksDB
+	iter    *C.rocksdb_iterator_t
+	limit   []byte
+	started bool
+	key     []byte
+	value   []byte
+	err     error
+}
+
+func (it *rocksIterator) Next() bool {
+	it.db.lock.RLock()
+	defer it.db.lock.RUnlock()
+
+	it.key, it.value = nil, nil
+	if it.iter == nil || it.err != nil {
+		return false
+	}
+	if it.db.db == nil {
+		it.err = ErrAlreadyClosed
+		return false
+	}
+	if it.started {
+		C.rocksdb_iter_next(it.iter)
+	}
+	it.started = true
+	if C.rocksdb_iter_valid(it.iter) == 0 {
+		var cErr *C.char
+		C.rocksdb_iter_get_error(it.iter, &cErr)
+		if cErr != nil {
+			defer C.rocksdb_free(unsafe.Pointer(cErr))
+			it.err = errors.New(C.GoString(cErr))
+		}
+		return false
+	}
+	var cLen C.size_t
+	cKey := C.rocksdb_iter_key(it.iter, &cLen)
+	key := C.GoBytes(unsafe.Pointer(cKey), C.int(cLen))
+	if it.limit != nil && bytes.Compare(key, it.limit) >= 0 {
+		return false
+	}
+	cValue := C.rocksdb_iter_value(it.iter, &cLen)
+	it.key, it.value = key, C.GoBytes(unsafe.Pointer(cValue), C.int(cLen))
+	return true
+}
+
+func (it *rocksIterator) Key() []byte {
+	return it.key
+}
+
+func (it *rocksIterator) Value() []byte {
+	return it.value
+}
+
+func (it *rocksIterator) Error() error {
+	return it.err
+}
+
+func (it *rocksIterator) Release() {
+	it.db.lock.RLock()
+	defer it.db.lock.RUnlock()
+
+	if it.iter != nil && it.db.db != nil {
+		C.rocksdb_iter_destroy(it.iter)
+	}
+	it.iter = nil
+	it.key, it.value = nil, nil
+}
+
+type rocksSnapshot struct {
+	db   *RocksDB
+	snap *C.rocksdb_snapshot_t
+	ro   *C.rocksdb_readoptions_t
+}
+
+func (s *rocksSnapshot) GetBucket(id BucketID) (ReadBucket, error) {
+	bk, err := s.db.GetBucket(id)
+	if err != nil {
+		return nil, err
+	}
+	return &rocksSnapshotBucket{
+		cf: bk.(*RocksBucket).cf,
+		s:  s,
+	}, nil
+}
+
+func (s *rocksSnapshot) Release() {
+	s.db.lock.RLock()
+	defer s.db.lock.RUnlock()
+
+	if s.snap == nil {
+		return
+	}
+	C.rocksdb_readoptions_destroy(s.ro)
+	if s.db.db != nil {
+		C.rocksdb_release_snapshot(s.db.db, s.snap)
+	}
+	s.snap, s.ro = nil, nil
+}
+
+type rocksSnapshotBucket struct {
+	cf *C.rocksdb_column_family_handle_t
+	s  *rocksSnapshot
+}
+
+func (b *rocksSnapshotBucket) Get(key []byte) ([]byte, error) {
+	return b.s.db.getValue(b.s.ro, b.cf, key)
+}
+
+func (b *rocksSnapshotBucket) Has(key []byte) (bool, error) {
+	return b.s.db.hasValue(b.s.ro, b.cf, key)
+}
+
+func (b *rocksSnapshotBucket) NewIterator(r *Range) Iterator {
+	return b.s.db.newIterator(b.s.ro, b.cf, r)
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/intconv"
+)
+
+const (
+	// SchemaBucket keeps the version of the layout of the database.
+	SchemaBucket BucketID = "V|"
+
+	schemaVersionKey = "Version"
+)
+
+// Migration converts the database from Version-1 to Version. Updates are
+// written to the batch, so that they are applied atomically with the version.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(database Database, batch Batch) error
+}
+
+type Schema struct {
+	Name       string
+	Migrations []*Migration
+}
+
+// Version returns the latest version of the schema.
+func (s *Schema) Version() int {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+var (
+	schemaLock sync.Mutex
+	schemas    = map[string]*Schema{}
+)
+
+// RegisterSchema registers migrations of the schema for the name (e.g. type
+// of the receiver). Migrations should have consecutive versions from 1.
+func RegisterSchema(name string, migrations ...*Migration) {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+
+	if _, ok := schemas[name]; ok {
+		panic(fmt.Sprintf("Duplicate schema registration for %s", name))
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			panic(fmt.Sprintf("Invalid migration version %d for %s", m.Version, name))
+		}
+	}
+	schemas[name] = &Schema{Name: name, Migrations: migrations}
+}
+
+func GetSchema(name string) (*Schema, bool) {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+
+	s, ok := schemas[name]
+	return s, ok
+}
+
+// GetSchemaVersion returns the version of the database, zero for the
+// database without version.
+func GetSchemaVersion(database Database) (int, error) {
+	bk, err := database.GetBucket(SchemaBucket)
+	if err != nil {
+		return 0, err
+	}
+	bs, err := bk.Get([]byte(schemaVersionKey))
+	if err != nil || bs == nil {
+		return 0, err
+	}
+	return int(intconv.BytesToInt64(bs)), nil
+}
+
+// Migrate applies the migrations of the schema from the version of the
+// database, and returns the version of the database and the migrations
+// to be applied. With dryRun, it doesn't update the database. It fails if
+// the database is newer than the schema.
+func Migrate(database Database, name string, dryRun bool) (int, []*Migration, error) {
+	s, ok := GetSchema(name)
+	if !ok {
+		return 0, nil, errors.NotFoundError.Errorf("no schema for %s", name)
+	}
+	version, err := GetSchemaVersion(database)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version > s.Version() {
+		return version, nil, errors.UnsupportedError.Errorf(
+			"unknown schema version %d for %s (latest:%d)", version, name, s.Version())
+	}
+	pending := s.Migrations[version:]
+	if dryRun {
+		return version, pending, nil
+	}
+	for _, m := range pending {
+		batch := database.NewBatch()
+		if err = m.Migrate(database, batch); err != nil {
+			return version, pending, errors.Wrapf(err, "fail to migrate %s to version %d", name, m.Version)
+		}
+		batch.Set(SchemaBucket, []byte(schemaVersionKey), intconv.Int64ToBytes(int64(m.Version)))
+		if err = batch.Write(); err != nil {
+			return version, pending, err
+		}
+	}
+	return version, pending, nil
+}
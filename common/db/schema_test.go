@@ -0,0 +1,92 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/errors"
+)
+
+func TestMigrate(t *testing.T) {
+	RegisterSchema("test-migrate",
+		&Migration{
+			Version:     1,
+			Description: "move key",
+			Migrate: func(database Database, batch Batch) error {
+				bs, err := BucketOf(database, "A|").Get([]byte("key"))
+				if err != nil || bs == nil {
+					return err
+				}
+				batch.Set("B|", []byte("key"), bs)
+				batch.Delete("A|", []byte("key"))
+				return nil
+			},
+		},
+		&Migration{
+			Version:     2,
+			Description: "fail",
+			Migrate: func(database Database, batch Batch) error {
+				batch.Set("B|", []byte("other"), []byte("value"))
+				return errors.InvalidStateError.New("fail")
+			},
+		},
+	)
+	s, ok := GetSchema("test-migrate")
+	assert.True(t, ok)
+	assert.Equal(t, 2, s.Version())
+
+	database := NewMapDB()
+	assert.NoError(t, BucketOf(database, "A|").Set([]byte("key"), []byte("value")))
+
+	// dry run doesn't change anything
+	version, pending, err := Migrate(database, "test-migrate", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Len(t, pending, 2)
+	version, err = GetSchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	// applies the first one, and stops at the failure
+	_, _, err = Migrate(database, "test-migrate", false)
+	assert.Error(t, err)
+	version, err = GetSchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	v, err := BucketOf(database, "B|").Get([]byte("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+	has, err := BucketOf(database, "A|").Has([]byte("key"))
+	assert.NoError(t, err)
+	assert.False(t, has)
+	has, err = BucketOf(database, "B|").Has([]byte("other"))
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestMigrate_NewerVersion(t *testing.T) {
+	RegisterSchema("test-newer", &Migration{
+		Version: 1,
+		Migrate: func(database Database, batch Batch) error { return nil },
+	})
+	database := NewMapDB()
+	_, _, err := Migrate(database, "test-newer", false)
+	assert.NoError(t, err)
+	version, err := GetSchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	assert.NoError(t, BucketOf(database, SchemaBucket).Set([]byte(schemaVersionKey), []byte{2}))
+	_, _, err = Migrate(database, "test-newer", false)
+	assert.True(t, errors.UnsupportedError.Equals(err))
+
+	_, _, err = Migrate(database, "test-unknown", false)
+	assert.True(t, errors.NotFoundError.Equals(err))
+}
+
+func TestRegisterSchema_InvalidVersion(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterSchema("test-invalid", &Migration{Version: 2})
+	})
+}
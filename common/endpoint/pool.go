@@ -0,0 +1,281 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package endpoint
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/log"
+)
+
+const (
+	DefaultCheckInterval = 30 * time.Second
+	DefaultMaxHeightLag  = 10
+
+	// weight of the last sample for moving averages
+	ewmaWeight = 0.2
+	// endpoints with higher error rate are unhealthy
+	maxErrorRate = 0.5
+)
+
+// CheckFunc returns the last block height of the endpoint.
+type CheckFunc func(url string) (int64, error)
+
+// IsFailureFunc returns whether the error is caused by the endpoint.
+// Errors returned by the chain, like JSON-RPC errors, are not.
+type IsFailureFunc func(err error) bool
+
+type Status struct {
+	URL       string        `json:"url"`
+	Latency   time.Duration `json:"latency"`
+	ErrorRate float64       `json:"error_rate"`
+	Height    int64         `json:"height"`
+	Healthy   bool          `json:"healthy"`
+}
+
+type endpoint struct {
+	url       string
+	latency   float64
+	errorRate float64
+	height    int64
+}
+
+func ewma(avg, v float64) float64 {
+	return avg*(1-ewmaWeight) + v*ewmaWeight
+}
+
+// Pool keeps the health of endpoints by latency, error rate and freshness
+// of the block height, and routes calls to the best one.
+type Pool struct {
+	mtx       sync.Mutex
+	eps       []*endpoint
+	check     CheckFunc
+	isFailure IsFailureFunc
+	l         log.Logger
+
+	CheckInterval time.Duration
+	MaxHeightLag  int64
+	lastCheck     time.Time
+	checking      bool
+}
+
+// NewPool returns the pool of endpoints. check and isFailure can be nil,
+// then health checks are disabled and all errors are failures.
+func NewPool(urls []string, check CheckFunc, isFailure IsFailureFunc, l log.Logger) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, errors.IllegalArgumentError.New("no endpoint")
+	}
+	p := &Pool{
+		check:         check,
+		isFailure:     isFailure,
+		l:             l,
+		CheckInterval: DefaultCheckInterval,
+		MaxHeightLag:  DefaultMaxHeightLag,
+	}
+	seen := make(map[string]bool)
+	for _, url := range urls {
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		p.eps = append(p.eps, &endpoint{url: url})
+	}
+	if len(p.eps) == 0 {
+		return nil, errors.IllegalArgumentError.New("no endpoint")
+	}
+	return p, nil
+}
+
+func (p *Pool) URLs() []string {
+	urls := make([]string, len(p.eps))
+	for i, ep := range p.eps {
+		urls[i] = ep.url
+	}
+	return urls
+}
+
+func (p *Pool) maxHeight() int64 {
+	var h int64
+	for _, ep := range p.eps {
+		if ep.height > h {
+			h = ep.height
+		}
+	}
+	return h
+}
+
+func (p *Pool) healthy(ep *endpoint, maxHeight int64) bool {
+	if ep.errorRate >= maxErrorRate {
+		return false
+	}
+	return ep.height == 0 || maxHeight-ep.height <= p.MaxHeightLag
+}
+
+// ranked returns endpoints from the best, healthy ones are ordered by
+// latency weighted with error rate, then by the configured order.
+func (p *Pool) ranked() []*endpoint {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	maxHeight := p.maxHeight()
+	eps := make([]*endpoint, len(p.eps))
+	copy(eps, p.eps)
+	healthy := make(map[*endpoint]bool)
+	for _, ep := range eps {
+		healthy[ep] = p.healthy(ep, maxHeight)
+	}
+	sort.SliceStable(eps, func(i, j int) bool {
+		if healthy[eps[i]] != healthy[eps[j]] {
+			return healthy[eps[i]]
+		}
+		return eps[i].latency*(1+eps[i].errorRate) < eps[j].latency*(1+eps[j].errorRate)
+	})
+	return eps
+}
+
+// Best returns the URL of the best endpoint.
+func (p *Pool) Best() string {
+	p.maybeCheck()
+	return p.ranked()[0].url
+}
+
+// Do calls f with endpoints from the best one, until it succeeds or fails
+// with the error which is not a failure of the endpoint.
+func (p *Pool) Do(f func(url string) error) error {
+	p.maybeCheck()
+	var err error
+	for i, ep := range p.ranked() {
+		if i > 0 {
+			p.l.Warnf("failover to %s err:%+v", ep.url, err)
+		}
+		start := time.Now()
+		err = f(ep.url)
+		p.Report(ep.url, time.Since(start), err)
+		if err == nil || !p.IsFailure(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (p *Pool) IsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if p.isFailure == nil {
+		return true
+	}
+	return p.isFailure(err)
+}
+
+func (p *Pool) find(url string) *endpoint {
+	for _, ep := range p.eps {
+		if ep.url == url {
+			return ep
+		}
+	}
+	return nil
+}
+
+// Report updates the health of the endpoint with the result of a call.
+// Latency is ignored if it's zero.
+func (p *Pool) Report(url string, latency time.Duration, err error) {
+	failed := p.IsFailure(err)
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	ep := p.find(url)
+	if ep == nil {
+		return
+	}
+	if failed {
+		ep.errorRate = ewma(ep.errorRate, 1)
+		return
+	}
+	ep.errorRate = ewma(ep.errorRate, 0)
+	if latency > 0 {
+		if ep.latency == 0 {
+			ep.latency = float64(latency)
+		} else {
+			ep.latency = ewma(ep.latency, float64(latency))
+		}
+	}
+}
+
+func (p *Pool) setHeight(url string, height int64) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if ep := p.find(url); ep != nil {
+		ep.height = height
+	}
+}
+
+// maybeCheck starts health checks in background, if there are multiple
+// endpoints and the last check is older than CheckInterval.
+func (p *Pool) maybeCheck() {
+	if p.check == nil || len(p.eps) < 2 {
+		return
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.checking || time.Since(p.lastCheck) < p.CheckInterval {
+		return
+	}
+	p.checking = true
+	go p.Check()
+}
+
+// Check gets the last block height of each endpoint, and updates the health.
+func (p *Pool) Check() {
+	defer func() {
+		p.mtx.Lock()
+		p.checking = false
+		p.lastCheck = time.Now()
+		p.mtx.Unlock()
+	}()
+	if p.check == nil {
+		return
+	}
+	for _, url := range p.URLs() {
+		start := time.Now()
+		height, err := p.check(url)
+		p.Report(url, time.Since(start), err)
+		if err != nil {
+			p.l.Debugf("fail to check endpoint %s err:%+v", url, err)
+			continue
+		}
+		p.setHeight(url, height)
+	}
+}
+
+func (p *Pool) Statuses() []*Status {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	maxHeight := p.maxHeight()
+	ss := make([]*Status, len(p.eps))
+	for i, ep := range p.eps {
+		ss[i] = &Status{
+			URL:       ep.url,
+			Latency:   time.Duration(ep.latency),
+			ErrorRate: ep.errorRate,
+			Height:    ep.height,
+			Healthy:   p.healthy(ep, maxHeight),
+		}
+	}
+	return ss
+}
@@ -0,0 +1,104 @@
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/log"
+)
+
+var errChain = errors.New("chain error")
+
+func isFailure(err error) bool {
+	return err != errChain
+}
+
+func TestNewPool(t *testing.T) {
+	_, err := NewPool(nil, nil, nil, log.New())
+	assert.True(t, errors.IllegalArgumentError.Equals(err))
+
+	p, err := NewPool([]string{"a", "", "b", "a"}, nil, nil, log.New())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, p.URLs())
+	assert.Equal(t, "a", p.Best())
+}
+
+func TestPool_Failover(t *testing.T) {
+	p, err := NewPool([]string{"a", "b"}, nil, isFailure, log.New())
+	assert.NoError(t, err)
+
+	var called []string
+	err = p.Do(func(url string) error {
+		called = append(called, url)
+		if url == "a" {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, called)
+
+	// errors from the chain are returned without failover
+	called = nil
+	err = p.Do(func(url string) error {
+		called = append(called, url)
+		return errChain
+	})
+	assert.Equal(t, errChain, err)
+	assert.Len(t, called, 1)
+
+	// all endpoints fail
+	err = p.Do(func(url string) error {
+		return errors.New("timeout")
+	})
+	assert.Error(t, err)
+}
+
+func TestPool_ErrorRate(t *testing.T) {
+	p, err := NewPool([]string{"a", "b"}, nil, isFailure, log.New())
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		p.Report("a", 0, errors.New("timeout"))
+	}
+	assert.Equal(t, "b", p.Best())
+	ss := p.Statuses()
+	assert.False(t, ss[0].Healthy)
+	assert.True(t, ss[1].Healthy)
+
+	for i := 0; i < 10; i++ {
+		p.Report("a", 0, nil)
+	}
+	assert.Equal(t, "a", p.Best())
+}
+
+func TestPool_Latency(t *testing.T) {
+	p, err := NewPool([]string{"a", "b"}, nil, isFailure, log.New())
+	assert.NoError(t, err)
+
+	p.Report("a", 100*time.Millisecond, nil)
+	p.Report("b", 10*time.Millisecond, nil)
+	assert.Equal(t, "b", p.Best())
+}
+
+func TestPool_Check(t *testing.T) {
+	heights := map[string]int64{"a": 100, "b": 200}
+	p, err := NewPool([]string{"a", "b"}, func(url string) (int64, error) {
+		return heights[url], nil
+	}, isFailure, log.New())
+	assert.NoError(t, err)
+
+	p.Check()
+	// a lags behind more than MaxHeightLag
+	assert.Equal(t, "b", p.Best())
+	ss := p.Statuses()
+	assert.Equal(t, int64(100), ss[0].Height)
+	assert.False(t, ss[0].Healthy)
+
+	heights["a"] = 200 - DefaultMaxHeightLag
+	p.Check()
+	assert.True(t, p.Statuses()[0].Healthy)
+}
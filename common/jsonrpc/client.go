@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -23,6 +24,64 @@ func NewJsonRpcClient(hc *http.Client, endpoint string) *Client {
 	return &Client{hc: hc, Endpoint: endpoint, CustomHeader: make(map[string]string)}
 }
 
+// NewJsonRpcClientWithOptions returns the client which retries and limits
+// the rate of requests by opt.
+func NewJsonRpcClientWithOptions(base http.RoundTripper, endpoint string, opt *Options) (*Client, error) {
+	tr, err := NewTransport(base, opt)
+	if err != nil {
+		return nil, err
+	}
+	return NewJsonRpcClient(&http.Client{Transport: tr}, endpoint), nil
+}
+
+// lastID is the ID of the last request, it starts with the timestamp in
+// milliseconds as before, and increases atomically for uniqueness.
+var lastID = time.Now().UnixNano() / int64(time.Millisecond)
+
+func nextID() int64 {
+	return atomic.AddInt64(&lastID, 1)
+}
+
+func newRequest(method string, reqPtr interface{}) (*Request, error) {
+	jrReq := &Request{
+		ID:      nextID(),
+		Version: Version,
+		Method:  method,
+	}
+	if reqPtr != nil {
+		b, err := json.Marshal(reqPtr)
+		if err != nil {
+			return nil, err
+		}
+		jrReq.Params = json.RawMessage(b)
+	}
+	return jrReq, nil
+}
+
+func (c *Client) newHttpRequest(body []byte) (*http.Request, error) {
+	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range c.CustomHeader {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+func decodeResult(result interface{}, respPtr interface{}) error {
+	if respPtr == nil {
+		return nil
+	}
+	rb, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(rb, respPtr)
+}
+
 func (c *Client) _do(req *http.Request) (resp *http.Response, err error) {
 	if c.Pre != nil {
 		if err = c.Pre(req); err != nil {
@@ -43,32 +102,18 @@ func (c *Client) _do(req *http.Request) (resp *http.Response, err error) {
 //Supported Parameter Structures only 'by-name through an Object'
 //refer https://www.jsonrpc.org/specification#parameter_structures
 func (c *Client) Do(method string, reqPtr, respPtr interface{}) (jrResp *Response, err error) {
-	jrReq := &Request{
-		ID:      time.Now().UnixNano() / int64(time.Millisecond),
-		Version: Version,
-		Method:  method,
-	}
-	if reqPtr != nil {
-		b, mErr := json.Marshal(reqPtr)
-		if mErr != nil {
-			err = mErr
-			return
-		}
-		jrReq.Params = json.RawMessage(b)
+	jrReq, err := newRequest(method, reqPtr)
+	if err != nil {
+		return nil, err
 	}
 	reqB, err := json.Marshal(jrReq)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewReader(reqB))
+	req, err := c.newHttpRequest(reqB)
 	if err != nil {
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	for k, v := range c.CustomHeader {
-		req.Header.Set(k, v)
-	}
 
 	var resp *http.Response
 	resp, err = c._do(req)
@@ -97,31 +142,79 @@ func (c *Client) Do(method string, reqPtr, respPtr interface{}) (jrResp *Respons
 		err = jrResp.Error
 		return
 	}
-	if respPtr != nil {
-		rb, mErr := json.Marshal(jrResp.Result)
-		if mErr != nil {
-			err = mErr
-			return
-		}
-		err = json.Unmarshal(rb, respPtr)
+	err = decodeResult(jrResp.Result, respPtr)
+	return
+}
+
+// BatchElem is a request in the batch. Result and Error are set
+// by the response.
+type BatchElem struct {
+	Method string
+	Params interface{}
+	Result interface{}
+	Error  error
+}
+
+// DoBatch sends requests in a batch. It returns an error if the batch
+// fails, while errors of each request are set to the element.
+func (c *Client) DoBatch(elems []*BatchElem) error {
+	if len(elems) == 0 {
+		return nil
+	}
+	reqs := make([]*Request, len(elems))
+	byID := make(map[int64]*BatchElem, len(elems))
+	for i, elem := range elems {
+		jrReq, err := newRequest(elem.Method, elem.Params)
 		if err != nil {
-			return
+			return err
 		}
+		reqs[i] = jrReq
+		byID[jrReq.ID.(int64)] = elem
 	}
-	return
+	reqB, err := json.Marshal(reqs)
+	if err != nil {
+		return err
+	}
+	req, err := c.newHttpRequest(reqB)
+	if err != nil {
+		return err
+	}
+	resp, err := c._do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var jrResps []*Response
+	if err = json.NewDecoder(resp.Body).Decode(&jrResps); err != nil {
+		return fmt.Errorf("fail to decode batch response body err:%+v", err)
+	}
+	for _, jrResp := range jrResps {
+		id, ok := jrResp.ID.(float64)
+		if !ok {
+			continue
+		}
+		elem, ok := byID[int64(id)]
+		if !ok {
+			continue
+		}
+		delete(byID, int64(id))
+		if jrResp.Error != nil {
+			elem.Error = jrResp.Error
+			continue
+		}
+		elem.Error = decodeResult(jrResp.Result, elem.Result)
+	}
+	for _, elem := range byID {
+		elem.Error = fmt.Errorf("no response for %s", elem.Method)
+	}
+	return nil
 }
 
 func (c *Client) Raw(reqB []byte) (resp *http.Response, err error) {
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewReader(reqB))
+	req, err := c.newHttpRequest(reqB)
 	if err != nil {
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	for k, v := range c.CustomHeader {
-		req.Header.Set(k, v)
-	}
-
 	return c._do(req)
 }
 
@@ -0,0 +1,178 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/capture"
+	"github.com/icon-project/btp2/common/log"
+)
+
+func writeResult(w http.ResponseWriter, id interface{}, result string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":%s}`, id, result)
+}
+
+func TestClient_Retry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &Request{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			writeResult(w, req.ID, `"0x1"`)
+		}
+	}))
+	defer srv.Close()
+
+	opt := &Options{MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	c, err := NewJsonRpcClientWithOptions(nil, srv.URL, opt)
+	assert.NoError(t, err)
+	var result string
+	_, err = c.Do("test", nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x1", result)
+	assert.Equal(t, int32(3), calls)
+
+	// without retries
+	atomic.StoreInt32(&calls, 0)
+	opt.MaxRetry = -1
+	c, err = NewJsonRpcClientWithOptions(nil, srv.URL, opt)
+	assert.NoError(t, err)
+	_, err = c.Do("test", nil, &result)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestClient_JSONErrorNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"fail"}}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewJsonRpcClientWithOptions(nil, srv.URL,
+		&Options{MinBackoff: time.Millisecond})
+	assert.NoError(t, err)
+	_, err = c.Do("test", nil, nil)
+	jErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorCodeServer, jErr.Code)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.True(t, r.Wait(nil))
+	}
+	// burst of 2, then 2 tokens by 100/s
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+
+	done := make(chan struct{})
+	close(done)
+	r = NewRateLimiter(0.001, 1)
+	assert.True(t, r.Wait(done))
+	assert.False(t, r.Wait(done))
+}
+
+func TestClient_UniqueID(t *testing.T) {
+	var mtx sync.Mutex
+	ids := make(map[float64]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &Request{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		mtx.Lock()
+		assert.False(t, ids[req.ID.(float64)])
+		ids[req.ID.(float64)] = true
+		mtx.Unlock()
+		writeResult(w, req.ID, `null`)
+	}))
+	defer srv.Close()
+
+	c := NewJsonRpcClient(&http.Client{}, srv.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Do("test", nil, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, ids, 50)
+}
+
+func TestClient_DoBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []*Request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+		w.Header().Set("Content-Type", "application/json")
+		// responses in reverse order, without the last request
+		fmt.Fprint(w, "[")
+		for i := len(reqs) - 2; i >= 0; i-- {
+			if reqs[i].Method == "fail" {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"error":{"code":-32601,"message":"no method"}}`, reqs[i].ID)
+			} else {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":%s}`, reqs[i].ID, reqs[i].Params)
+			}
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+		}
+		fmt.Fprint(w, "]")
+	}))
+	defer srv.Close()
+
+	c := NewJsonRpcClient(&http.Client{}, srv.URL)
+	var r1, r2 int
+	elems := []*BatchElem{
+		{Method: "echo", Params: 1, Result: &r1},
+		{Method: "echo", Params: 2, Result: &r2},
+		{Method: "fail", Params: 3},
+		{Method: "echo", Params: 4},
+	}
+	assert.NoError(t, c.DoBatch(elems))
+	assert.NoError(t, elems[0].Error)
+	assert.Equal(t, 1, r1)
+	assert.NoError(t, elems[1].Error)
+	assert.Equal(t, 2, r2)
+	jErr, ok := elems[2].Error.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorCodeMethodNotFound, jErr.Code)
+	assert.Error(t, elems[3].Error)
+}
+
+func TestNewTransport_Capture(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "capture.jsonl")
+	_, err := NewTransport(nil, &Options{Capture: &capture.Options{
+		WriterConfig: log.WriterConfig{Filename: file},
+	}})
+	assert.NoError(t, err)
+	_, err = os.Stat(file)
+	assert.NoError(t, err)
+
+	_, err = NewJsonRpcClientWithOptions(nil, "http://localhost", &Options{Capture: &capture.Options{
+		WriterConfig: log.WriterConfig{Filename: filepath.Join(file, "capture.jsonl")},
+	}})
+	assert.Error(t, err)
+}
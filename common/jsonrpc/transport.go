@@ -0,0 +1,194 @@
+package jsonrpc
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/icon-project/btp2/common"
+	"github.com/icon-project/btp2/common/capture"
+)
+
+const (
+	DefaultMaxRetry   = 3
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// Options configures retries and the rate limit of requests to an endpoint.
+type Options struct {
+	// MaxRetry is the number of retries for transport errors and
+	// 429/5xx responses, negative to disable retries.
+	MaxRetry   int           `json:"max_retry,omitempty"`
+	MinBackoff time.Duration `json:"-"`
+	MaxBackoff time.Duration `json:"-"`
+	// RateLimit is requests per second, zero for unlimited.
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	RateBurst int     `json:"rate_burst,omitempty"`
+	// Capture records requests and responses to the file if it's set.
+	Capture *capture.Options `json:"capture,omitempty"`
+}
+
+// RateLimiter is a token bucket.
+type RateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token, and returns the delay until the token is available.
+func (r *RateLimiter) reserve() time.Duration {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available or done is closed.
+func (r *RateLimiter) Wait(done <-chan struct{}) bool {
+	d := r.reserve()
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// Transport retries requests failed with transport errors or 429/5xx
+// responses with exponential backoff, and limits the rate of requests.
+type Transport struct {
+	Base    http.RoundTripper
+	opt     Options
+	limiter *RateLimiter
+}
+
+// NewTransport returns the transport by opt, it fails if the capture file
+// of opt can't be opened.
+func NewTransport(base http.RoundTripper, opt *Options) (*Transport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{Base: base}
+	if opt != nil {
+		t.opt = *opt
+	}
+	if t.opt.MaxRetry == 0 {
+		t.opt.MaxRetry = DefaultMaxRetry
+	}
+	if t.opt.MinBackoff <= 0 {
+		t.opt.MinBackoff = DefaultMinBackoff
+	}
+	if t.opt.MaxBackoff < t.opt.MinBackoff {
+		t.opt.MaxBackoff = DefaultMaxBackoff
+		if t.opt.MaxBackoff < t.opt.MinBackoff {
+			t.opt.MaxBackoff = t.opt.MinBackoff
+		}
+	}
+	if t.opt.RateLimit > 0 {
+		t.limiter = NewRateLimiter(t.opt.RateLimit, t.opt.RateBurst)
+	}
+	if t.opt.Capture != nil && t.opt.Capture.Filename != "" {
+		// capture each attempt
+		r, err := capture.OpenRecorder(t.opt.Capture)
+		if err != nil {
+			return nil, err
+		}
+		t.Base = capture.NewTransport(t.Base, r)
+	}
+	return t, nil
+}
+
+// retryable returns whether the response is throttled or failed by the
+// server. 500 with JSON is a JSON-RPC error, so it's not retried.
+func retryable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusInternalServerError:
+		return !common.HasContentType(resp.Header, echo.MIMEApplicationJSON)
+	}
+	return false
+}
+
+func (t *Transport) backoff(retry int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+			return time.Duration(s) * time.Second
+		}
+	}
+	d := t.opt.MinBackoff
+	for i := 0; i < retry && d < t.opt.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > t.opt.MaxBackoff {
+		d = t.opt.MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody == nil {
+		// body can't be sent again
+		return t.Base.RoundTrip(req)
+	}
+	done := req.Context().Done()
+	for retry := 0; ; retry++ {
+		if t.limiter != nil && !t.limiter.Wait(done) {
+			return nil, req.Context().Err()
+		}
+		r := req
+		if retry > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.Base.RoundTrip(r)
+		if retry >= t.opt.MaxRetry || (err == nil && !retryable(resp)) {
+			return resp, err
+		}
+		d := t.backoff(retry, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(d):
+		case <-done:
+			return nil, req.Context().Err()
+		}
+	}
+}
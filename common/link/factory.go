@@ -15,6 +15,7 @@ type Factory struct {
 	NewReceiver      func(srcCfg ChainConfig, dstAddr types.BtpAddress, baseDir string, l log.Logger) (Receiver, error)
 	NewLink          func(srcCfg ChainConfig, dstAddr types.BtpAddress, baseDir string, l log.Logger) (types.Link, error)
 	NewSender        func(srcAddr types.BtpAddress, dstCfg ChainConfig, baseDir string, l log.Logger) (types.Sender, error)
+	OpenStore        func(srcCfg ChainConfig, baseDir string, l log.Logger) (Store, error)
 }
 
 var factories = map[string]*Factory{}
@@ -29,7 +30,7 @@ func RegisterFactory(f *Factory) {
 	factories[f.Type] = f
 }
 
-func CreateLink(srcRaw, dstRaw json.RawMessage, baseDir string, l log.Logger) (types.Link, error) {
+func CreateLink(srcRaw, dstRaw json.RawMessage, baseDir string, epCfg *ErrorPolicyConfig, l log.Logger) (types.Link, error) {
 
 	var srcCfgCommon ChainConfigCommon
 	if err := json.Unmarshal(srcRaw, &srcCfgCommon); err != nil {
@@ -54,11 +55,15 @@ func CreateLink(srcRaw, dstRaw json.RawMessage, baseDir string, l log.Logger) (t
 		if f.NewLink != nil {
 			return f.NewLink(srcCfg, dstCfgCommon.GetAddress(), baseDir, l)
 		} else {
+			ep, err := NewErrorPolicy(epCfg)
+			if err != nil {
+				return nil, err
+			}
 			receiver, err := f.NewReceiver(srcCfg, dstCfgCommon.GetAddress(), baseDir, l)
 			if err != nil {
 				return nil, err
 			}
-			return NewLink(srcCfg, receiver, l), nil
+			return NewLink(srcCfg, receiver, ep, l), nil
 		}
 
 	} else {
@@ -1,9 +1,11 @@
 package link
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/icon-project/btp2/common/errors"
 	"github.com/icon-project/btp2/common/log"
@@ -16,8 +18,27 @@ const (
 	INIT = iota
 	RUNNING
 	PENDING
+	PAUSED
+	STOPPED
 )
 
+func (s RelayState) String() string {
+	switch s {
+	case INIT:
+		return "INIT"
+	case RUNNING:
+		return "RUNNING"
+	case PENDING:
+		return "PENDING"
+	case PAUSED:
+		return "PAUSED"
+	case STOPPED:
+		return "STOPPED"
+	default:
+		return fmt.Sprintf("RelayState(%d)", int(s))
+	}
+}
+
 type relayMessage struct {
 	id            string
 	bls           *types.BMCLinkStatus
@@ -55,10 +76,9 @@ type Link struct {
 	r          Receiver
 	s          types.Sender
 	l          log.Logger
-	mtx        sync.RWMutex
-	rmsMtx     sync.RWMutex
+	mtx        sync.RWMutex // guards states below against the receiver, the sender and timers
 	rms        []*relayMessage
-	rss        []ReceiveStatus
+	rss        *ReceiveStatusList
 	rmi        *relayMessageItem
 	limitSize  int64
 	srcCfg     ChainConfig
@@ -66,15 +86,21 @@ type Link struct {
 	blsChannel chan *types.BMCLinkStatus
 	relayState RelayState
 	p          types.Preference
+	ep         *ErrorPolicy
+	retries    map[string]int
+	errCh      chan error
+	windowFull bool // the receiver waits for finalization to continue
 }
 
-func NewLink(srcCfg ChainConfig, r Receiver, l log.Logger) types.Link {
+func NewLink(srcCfg ChainConfig, r Receiver, ep *ErrorPolicy, l log.Logger) types.Link {
 	link := &Link{
-		l:      l,
-		srcCfg: srcCfg,
-		r:      r,
-		rms:    make([]*relayMessage, 0),
-		rss:    make([]ReceiveStatus, 0),
+		l:       l,
+		srcCfg:  srcCfg,
+		r:       r,
+		ep:      ep,
+		retries: make(map[string]int),
+		rms:     make([]*relayMessage, 0),
+		rss:     NewReceiveStatusList(),
 		rmi: &relayMessageItem{
 			rmis: make([][]RelayMessageItem, 0),
 			size: 0,
@@ -89,6 +115,7 @@ func NewLink(srcCfg ChainConfig, r Receiver, l log.Logger) types.Link {
 func (l *Link) Start(sender types.Sender, errChan chan error) error {
 	l.s = sender
 	l.p = sender.GetPreference()
+	l.errCh = errChan
 
 	if err := l.startSenderChannel(errChan); err != nil {
 		return err
@@ -99,7 +126,9 @@ func (l *Link) Start(sender types.Sender, errChan chan error) error {
 		return err
 	}
 
+	l.mtx.Lock()
 	l.bls = bls
+	l.mtx.Unlock()
 
 	if err := l.startReceiverChannel(errChan); err != nil {
 		return err
@@ -116,7 +145,9 @@ func (l *Link) Stop() {
 
 func (l *Link) startReceiverChannel(errCh chan error) error {
 	once := new(sync.Once)
+	l.mtx.RLock()
 	rc, err := l.r.Start(l.bls)
+	l.mtx.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -126,24 +157,12 @@ func (l *Link) startReceiverChannel(errCh chan error) error {
 			case rsc := <-rc:
 				switch t := rsc.(type) {
 				case ReceiveStatus:
-					rs := t.(ReceiveStatus)
-					l.rss = append(l.rss, t)
-					l.l.Debugf("ReceiveStatus : height:%d, ReceiveStatus seq:%d, BMCLinkStatus height:%d, rxSeq:%d)",
-						rs.Height(), rs.Seq(), l.bls.Verifier.Height, l.bls.RxSeq)
-					once.Do(func() {
-						if err = l.handleUndeliveredRelayMessage(); err != nil {
-							errCh <- err
-						}
-
-						if err = l.handleRelayMessage(); err != nil {
-							errCh <- err
-						}
-					})
-
-					if l.bls.Verifier.Height < rs.Height() || l.bls.RxSeq < rs.Seq() {
-						if err = l.handleRelayMessage(); err != nil {
-							errCh <- err
-						}
+					if err := l.handleReceiveStatus(t, once); err != nil {
+						errCh <- err
+					}
+				case WindowFull:
+					if err := l.handleWindowFull(t); err != nil {
+						errCh <- err
 					}
 				case error:
 					l.l.Debugf("ReceiverChannel error : %+v", t)
@@ -157,6 +176,55 @@ func (l *Link) startReceiverChannel(errCh chan error) error {
 	return nil
 }
 
+// handleReceiveStatus relays messages up to the status from the receiver,
+// undelivered messages are handled once for the first status.
+func (l *Link) handleReceiveStatus(rs ReceiveStatus, once *sync.Once) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.rss.Append(rs)
+	l.l.Debugf("ReceiveStatus : height:%d, ReceiveStatus seq:%d, BMCLinkStatus height:%d, rxSeq:%d)",
+		rs.Height(), rs.Seq(), l.bls.Verifier.Height, l.bls.RxSeq)
+	var err error
+	once.Do(func() {
+		if err = l.handleUndeliveredRelayMessage(); err != nil {
+			return
+		}
+		err = l.handleRelayMessage()
+	})
+	if err != nil {
+		return err
+	}
+
+	if l.bls.Verifier.Height < rs.Height() || l.bls.RxSeq < rs.Seq() {
+		return l.handleRelayMessage()
+	}
+	return nil
+}
+
+// handleWindowFull relays pending block updates, which are kept for filling
+// the relay message, to get them finalized for the paused receiver.
+func (l *Link) handleWindowFull(wf WindowFull) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.l.Debugf("WindowFull : count:%d, bytes:%d, pending size:%d", wf.Count, wf.Bytes, l.rmi.size)
+	l.windowFull = true
+	if !l.isRelayable() || l.rmi.size == 0 {
+		return nil
+	}
+	if err := l.appendRelayMessage(); err != nil {
+		return err
+	}
+	if err := l.sendRelayMessage(); err != nil {
+		return err
+	}
+	if l.relayState == INIT {
+		l.relayState = PENDING
+	}
+	return nil
+}
+
 func (l *Link) startSenderChannel(errCh chan error) error {
 	l.limitSize = l.p.TxSizeLimit - l.p.MarginForLimit
 	rcc, err := l.s.Start()
@@ -190,39 +258,31 @@ func (l *Link) buildRelayMessage() error {
 		return err
 	}
 
-	if len(bus) != 0 {
-		for _, bu := range bus {
-			l.appendRelayMessageItem(bu)
-			if err := bu.UpdateBMCLinkStatus(l.bls); err != nil {
-				return err
-			}
+	var mpLen int64
+	for _, bu := range bus {
+		l.appendRelayMessageItem(bu)
+		if err := bu.UpdateBMCLinkStatus(l.bls); err != nil {
+			return err
+		}
 
-			mpLen, err := l.buildProof(bu)
-			if err != nil {
-				return err
-			}
+		n, err := l.buildProof(bu)
+		if err != nil {
+			return err
+		}
+		mpLen += n
 
-			if mpLen == 0 {
-				if l.p.FilledBlockUpdate == true {
-					if l.isOverLimit(l.rmi.size) {
-						if err = l.appendRelayMessage(); err != nil {
-							return err
-						}
-					}
-				} else {
-					if err = l.appendRelayMessage(); err != nil {
-						return err
-					}
-				}
-			} else {
-				if err = l.appendRelayMessage(); err != nil {
-					return err
-				}
+		if l.isOverLimit(l.rmi.size) {
+			if err = l.appendRelayMessage(); err != nil {
+				return err
 			}
-
 		}
 	}
 
+	if l.rmi.size > 0 && (mpLen > 0 || l.p.FilledBlockUpdate == false || l.windowFull) {
+		if err = l.appendRelayMessage(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -281,24 +341,23 @@ func (l *Link) appendRelayMessage() error {
 }
 
 func (l *Link) handleRelayMessage() error {
-	l.rmsMtx.Lock()
-	defer l.rmsMtx.Unlock()
 	l.l.Debugf("handleRelayMessage (relay status:%d)", l.relayState)
 
-	if l.relayState != PENDING {
+	if l.isRelayable() {
 		if err := l.sendRelayMessage(); err != nil {
 			return err
 		}
 		for {
-			if l.relayState != PENDING &&
-				len(l.rss) != 0 &&
-				(l.bls.Verifier.Height < l.rss[len(l.rss)-1].Height() ||
-					l.bls.RxSeq < l.rss[len(l.rss)-1].Seq()) {
-				if l.bls.Verifier.Height < l.rss[len(l.rss)-1].Height() {
+			last := l.rss.Last()
+			if l.isRelayable() &&
+				last != nil &&
+				(l.bls.Verifier.Height < last.Height() ||
+					l.bls.RxSeq < last.Seq()) {
+				if l.bls.Verifier.Height < last.Height() {
 					if err := l.buildRelayMessage(); err != nil {
 						return err
 					}
-				} else if l.bls.RxSeq < l.rss[len(l.rss)-1].Seq() {
+				} else if l.bls.RxSeq < last.Seq() {
 					_, err := l.buildProof(nil)
 					if err != nil {
 						return err
@@ -312,11 +371,12 @@ func (l *Link) handleRelayMessage() error {
 					return err
 				}
 
-				if l.relayState == INIT {
+				// waits for the result of the first relay message if it's sent
+				if l.relayState == INIT && len(l.rms) > 0 {
 					l.relayState = PENDING
 				}
 			} else {
-				l.l.Debugf("Relay status : %d, ReceiveStatus size: %d", l.relayState, len(l.rss))
+				l.l.Debugf("Relay status : %d, ReceiveStatus size: %d", l.relayState, l.rss.Len())
 				break
 			}
 		}
@@ -334,11 +394,19 @@ func (l *Link) buildBlockUpdates() ([]BlockUpdate, error) {
 		if len(bus) != 0 {
 			return bus, nil
 		}
+		if l.rmi.size == 0 {
+			return nil, errors.InvalidStateError.Errorf(
+				"BlockUpdate exceeds the limit (bls height:%d, limit:%d)", l.bls.Verifier.Height, l.limitSize)
+		}
+		// flush pending items to make room for the block update
+		if err = l.appendRelayMessage(); err != nil {
+			return nil, err
+		}
 	}
 }
 
 func (l *Link) handleUndeliveredRelayMessage() error {
-	rs := l.getReceiveStatusForHeight(l.bls.Verifier.Height)
+	rs := l.rss.ForHeight(l.bls.Verifier.Height)
 	if rs == nil {
 		return nil
 	}
@@ -363,18 +431,22 @@ func (l *Link) buildProof(bu BlockUpdate) (int64, error) {
 	l.l.Debugf("BuildProof (bls height:%d, bls rxSeq:%d)", l.bls.Verifier.Height, l.bls.RxSeq)
 	var mpLen int64
 	var seq int64
+	last := l.rss.Last()
+	if last == nil {
+		return 0, errors.InvalidStateError.New("no receive status")
+	}
 	if bu != nil {
-		rs := l.getReceiveStatusForHeight(l.bls.Verifier.Height)
+		rs := l.rss.ForHeight(l.bls.Verifier.Height)
 		if rs == nil {
-			seq = l.rss[len(l.rss)-1].Seq()
-			l.l.Debugf("[ReceiveStatusForHeight is null]BuildProof ReceiveStatus (height : %d, seq : %d)", l.rss[len(l.rss)-1].Height(), l.rss[len(l.rss)-1].Seq())
+			seq = last.Seq()
+			l.l.Debugf("[ReceiveStatusForHeight is null]BuildProof ReceiveStatus (height : %d, seq : %d)", last.Height(), last.Seq())
 		} else {
 			seq = rs.Seq()
 			l.l.Debugf("[ReceiveStatusForHeight is not null]BuildProof ReceiveStatus (height : %d, seq : %d)", rs.Height(), rs.Seq())
 		}
 	} else {
-		seq = l.rss[len(l.rss)-1].Seq()
-		l.l.Debugf("[BlockUpdate is null]BuildProof ReceiveStatus (height : %d, seq : %d)", l.rss[len(l.rss)-1].Height(), l.rss[len(l.rss)-1].Seq())
+		seq = last.Seq()
+		l.l.Debugf("[BlockUpdate is null]BuildProof ReceiveStatus (height : %d, seq : %d)", last.Height(), last.Seq())
 	}
 
 	for {
@@ -407,14 +479,18 @@ func (l *Link) buildProof(bu BlockUpdate) (int64, error) {
 			if err != nil {
 				return 0, err
 			}
-			l.appendRelayMessageItem(bp)
+			if bp != nil {
+				l.appendRelayMessageItem(bp)
+			}
 		} else {
 			if bu == nil || bu.ProofHeight() == -1 {
 				bp, err := l.buildBlockProof(l.bls)
 				if err != nil {
 					return 0, err
 				}
-				l.appendRelayMessageItem(bp)
+				if bp != nil {
+					l.appendRelayMessageItem(bp)
+				}
 			}
 		}
 		l.appendRelayMessageItem(mp)
@@ -455,22 +531,8 @@ func (l *Link) appendRelayMessageItem(rmi RelayMessageItem) {
 	l.rmi.size += rmi.Len()
 }
 
-func (l *Link) getReceiveStatusForHeight(height int64) ReceiveStatus {
-	for _, rs := range l.rss {
-		if rs.Height() == height {
-			return rs
-		}
-	}
-	return nil
-}
-
 func (l *Link) removeReceiveStatus(bls *types.BMCLinkStatus) {
-	for i, rs := range l.rss {
-		if rs.Height() <= bls.Verifier.Height && rs.Seq() <= bls.RxSeq {
-			l.rss = l.rss[i+1:]
-			break
-		}
-	}
+	l.rss.RemoveDelivered(bls.Verifier.Height, bls.RxSeq)
 }
 
 func (l *Link) getRelayMessage(bls *types.BMCLinkStatus) *relayMessage {
@@ -506,19 +568,56 @@ func (l *Link) removeAllRelayMessage() {
 	l.rms = l.rms[:0]
 }
 
+// updateBlockProof rebuilds block proofs of the relay message with the
+// current status of the destination, then resends the re-encoded message.
 func (l *Link) updateBlockProof(id string) error {
 	rm := l.getRelayMessageForId(id)
+	if rm == nil {
+		return nil
+	}
+
+	bls, err := l.s.GetStatus()
+	if err != nil {
+		return err
+	}
 
-	for _, rmi := range rm.RelayMessageItems() {
+	rmis := make([]RelayMessageItem, 0, len(rm.rmis))
+	for _, rmi := range rm.rmis {
 		if rmi.Type() == TypeBlockProof {
-			h := l.r.GetHeightForSeq(rm.bls.RxSeq)
-			bf, err := l.r.BuildBlockProof(rm.bls, h)
+			bp, err := l.r.BuildBlockProof(bls, rmi.(BlockProof).ProofHeight())
 			if err != nil {
 				return err
 			}
-			rmi = bf
+			if bp == nil {
+				// the destination could verify messages without the block proof
+				continue
+			}
+			rmi = bp
+		}
+		rmis = append(rmis, rmi)
+	}
+
+	m, err := l.r.BuildRelayMessage(rmis)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(m, rm.message) {
+		l.l.Debugf("UpdateBlockProof no change (id:%s, bmv height:%d)", id, bls.Verifier.Height)
+		return nil
+	}
+	rm.message = m
+	rm.rmis = rmis
+	rm.sendingStatus = false
+
+	l.l.Debugf("UpdateBlockProof resend (id:%s, bmv height:%d)", id, bls.Verifier.Height)
+	if _, err = l.s.Relay(rm); err != nil {
+		if errors.InvalidStateError.Equals(err) {
+			l.relayState = PENDING
+			return nil
 		}
+		return err
 	}
+	rm.sendingStatus = true
 	return nil
 }
 
@@ -538,8 +637,10 @@ func (l *Link) successRelayMessage(id string) error {
 	rm := l.getRelayMessageForId(id)
 	l.removeRelayMessage(rm.BMCLinkStatus())
 	l.removeReceiveStatus(rm.BMCLinkStatus())
+	l.windowFull = false
 
-	l.relayState = RUNNING
+	delete(l.retries, id)
+	l.setRunning()
 
 	if err := l.handleRelayMessage(); err != nil {
 		return err
@@ -558,6 +659,12 @@ func (l *Link) updateBMCLinkStatus() error {
 }
 
 func (l *Link) result(rr *types.RelayResult) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.relayState == STOPPED {
+		return nil
+	}
 	rm := l.getRelayMessageForId(rr.Id)
 	if rm != nil {
 		switch rr.Err {
@@ -573,8 +680,6 @@ func (l *Link) result(rr *types.RelayResult) error {
 					}
 				}
 			}
-		case errors.BMVUnknown:
-			l.l.Panicf("BMVUnknown Revert : ErrorCoder:%+v", rr.Err)
 		case errors.BMVNotVerifiable:
 			if rr.Finalized != true {
 				l.relayState = PENDING
@@ -583,7 +688,7 @@ func (l *Link) result(rr *types.RelayResult) error {
 					return err
 				}
 				l.removeAllRelayMessage()
-				l.relayState = RUNNING
+				l.setRunning()
 				if err := l.handleRelayMessage(); err != nil {
 					return err
 				}
@@ -595,7 +700,7 @@ func (l *Link) result(rr *types.RelayResult) error {
 				if err := l.updateBMCLinkStatus(); err != nil {
 					return err
 				}
-				l.relayState = RUNNING
+				l.setRunning()
 				index := l.removeRelayMessage(l.bls)
 				if index == 0 {
 					l.removeAllRelayMessage()
@@ -608,15 +713,153 @@ func (l *Link) result(rr *types.RelayResult) error {
 				}
 			}
 		case errors.BMVRevertInvalidBlockWitnessOld:
-			//TODO Error handling required on Finalized
 			if err := l.updateBlockProof(rr.Id); err != nil {
 				return err
 			}
 		default:
-			l.l.Panicf("fail to GetResult RelayMessage ID:%v ErrorCoder:%+v",
-				rr.Id, rr.Err)
+			return l.handleErrorPolicy(rr)
 		}
 	}
 
 	return nil
 }
+
+// Status is the snapshot of the link. State is PAUSED or STOPPED while
+// relaying is suspended by the error policy.
+type Status struct {
+	State           RelayState
+	Height          int64
+	RxSeq           int64
+	RelayMessages   int
+	ReceiveStatuses int
+}
+
+func (s *Status) String() string {
+	return fmt.Sprintf("Status{state:%s,height:%d,rxSeq:%d,relayMessages:%d,receiveStatuses:%d}",
+		s.State, s.Height, s.RxSeq, s.RelayMessages, s.ReceiveStatuses)
+}
+
+// Status returns the current status of the link.
+func (l *Link) Status() *Status {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	s := &Status{
+		State:           l.relayState,
+		RelayMessages:   len(l.rms),
+		ReceiveStatuses: l.rss.Len(),
+	}
+	if l.bls != nil {
+		s.Height = l.bls.Verifier.Height
+		s.RxSeq = l.bls.RxSeq
+	}
+	return s
+}
+
+func (l *Link) isRelayable() bool {
+	return l.relayState == INIT || l.relayState == RUNNING
+}
+
+// setRunning changes the state to RUNNING unless the link is paused or stopped
+// by the error policy.
+func (l *Link) setRunning() {
+	if l.relayState != PAUSED && l.relayState != STOPPED {
+		l.relayState = RUNNING
+	}
+}
+
+func (l *Link) handleErrorPolicy(rr *types.RelayResult) error {
+	action := l.ep.Action(rr.Err)
+	l.l.Warnf("relay result error (id:%s, err:%+v, finalized:%v, action:%s)",
+		rr.Id, rr.Err, rr.Finalized, action)
+	switch action {
+	case ActionRetry:
+		n := l.retries[rr.Id] + 1
+		if n > l.ep.RetryLimit() {
+			l.l.Warnf("retry limit exceeded (id:%s, limit:%d), resync", rr.Id, l.ep.RetryLimit())
+			return l.resync()
+		}
+		l.retries[rr.Id] = n
+		d := l.ep.RetryInterval(n)
+		l.l.Infof("retry relay message (id:%s, retry:%d, after:%s)", rr.Id, n, d)
+		time.AfterFunc(d, func() {
+			if err := l.retryRelayMessage(rr.Id); err != nil {
+				l.errCh <- err
+			}
+		})
+	case ActionResync:
+		return l.resync()
+	case ActionPause:
+		l.pause(rr)
+	case ActionStop:
+		// other links of the relay keep running
+		l.relayState = STOPPED
+		l.l.Errorf("link stopped by relay result (id:%s, err:%+v), restart is required", rr.Id, rr.Err)
+	}
+	return nil
+}
+
+// retryRelayMessage resends the relay message, it's called by the timer.
+func (l *Link) retryRelayMessage(id string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	rm := l.getRelayMessageForId(id)
+	// the link is PENDING for the result of the relay message
+	if rm == nil || l.relayState == PAUSED || l.relayState == STOPPED {
+		return nil
+	}
+	if _, err := l.s.Relay(rm); err != nil {
+		if errors.InvalidStateError.Equals(err) {
+			l.relayState = PENDING
+			return nil
+		}
+		return err
+	}
+	rm.sendingStatus = true
+	return nil
+}
+
+// resync drops all relay messages, and builds them again from the status of BMC.
+func (l *Link) resync() error {
+	if err := l.updateBMCLinkStatus(); err != nil {
+		return err
+	}
+	l.l.Infof("resync link (bls height:%d, bls rxSeq:%d)", l.bls.Verifier.Height, l.bls.RxSeq)
+	l.removeAllRelayMessage()
+	l.rmi.rmis = l.rmi.rmis[:0]
+	l.resetRelayMessageItem()
+	l.retries = make(map[string]int)
+	if l.relayState != STOPPED {
+		l.relayState = RUNNING
+	}
+	return l.handleRelayMessage()
+}
+
+func (l *Link) pause(rr *types.RelayResult) {
+	l.relayState = PAUSED
+	d := l.ep.PauseInterval()
+	if d <= 0 {
+		l.l.Warnf("link paused by relay result (id:%s, err:%+v)", rr.Id, rr.Err)
+		return
+	}
+	l.l.Warnf("link paused by relay result (id:%s, err:%+v), resume after %s", rr.Id, rr.Err, d)
+	time.AfterFunc(d, func() {
+		if err := l.resume(); err != nil {
+			l.errCh <- err
+		}
+	})
+}
+
+// resume resyncs the link paused by the error policy, it's called by the
+// timer.
+func (l *Link) resume() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.relayState != PAUSED {
+		return nil
+	}
+	l.l.Infof("resume paused link")
+	return l.resync()
+}
@@ -0,0 +1,533 @@
+package link
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+const (
+	testUpdateSize  = 100
+	testProofSize   = 50
+	testMessageSize = 10
+)
+
+type testItem struct {
+	it   MessageItemType
+	size int64
+}
+
+func (i *testItem) Type() MessageItemType {
+	return i.it
+}
+
+func (i *testItem) Len() int64 {
+	return i.size
+}
+
+type testBlockUpdate struct {
+	testItem
+	src, target int64
+}
+
+func (u *testBlockUpdate) UpdateBMCLinkStatus(bls *types.BMCLinkStatus) error {
+	bls.Verifier.Height = u.target
+	return nil
+}
+
+func (u *testBlockUpdate) ProofHeight() int64 {
+	return u.target
+}
+
+func (u *testBlockUpdate) SrcHeight() int64 {
+	return u.src
+}
+
+func (u *testBlockUpdate) TargetHeight() int64 {
+	return u.target
+}
+
+func (u *testBlockUpdate) String() string {
+	return fmt.Sprintf("BU(%d->%d)", u.src, u.target)
+}
+
+// testBlockProof proves the block at height with the verifier at the height of at.
+type testBlockProof struct {
+	testItem
+	height, at int64
+}
+
+func (p *testBlockProof) UpdateBMCLinkStatus(bls *types.BMCLinkStatus) error {
+	return nil
+}
+
+func (p *testBlockProof) ProofHeight() int64 {
+	return p.height
+}
+
+func (p *testBlockProof) String() string {
+	return fmt.Sprintf("BP(%d@%d)", p.height, p.at)
+}
+
+type testMessageProof struct {
+	testItem
+	height      int64
+	start, last int64
+}
+
+func (p *testMessageProof) UpdateBMCLinkStatus(bls *types.BMCLinkStatus) error {
+	bls.RxSeq = p.last
+	return nil
+}
+
+func (p *testMessageProof) StartSeqNum() int64 {
+	return p.start
+}
+
+func (p *testMessageProof) LastSeqNum() int64 {
+	return p.last
+}
+
+func (p *testMessageProof) String() string {
+	return fmt.Sprintf("MP(%d:%d-%d)", p.height, p.start, p.last)
+}
+
+// testReceiver is the receiver of blocks with messages, heights of blocks
+// are from 1 without gaps.
+type testReceiver struct {
+	mtx       sync.Mutex
+	rsc       chan interface{}
+	blocks    []*testReceiveStatus
+	finalized chan *types.BMCLinkStatus
+	// window pauses addBlock until finalization if it's not nil.
+	window *ReceiveStatusList
+	// blockProof is called for BuildBlockProof of the block lower than the
+	// verifier height, the default returns the block proof.
+	blockProof func(bls *types.BMCLinkStatus, height int64) (BlockProof, error)
+}
+
+func newTestReceiver() *testReceiver {
+	return &testReceiver{
+		rsc:       make(chan interface{}),
+		finalized: make(chan *types.BMCLinkStatus, 100),
+	}
+}
+
+// addBlock adds the block with the messages, and notifies it to the link.
+func (r *testReceiver) addBlock(messages int64) *testReceiveStatus {
+	r.mtx.Lock()
+	rs := &testReceiveStatus{height: int64(len(r.blocks)) + 1, seq: messages}
+	if len(r.blocks) > 0 {
+		rs.seq += r.blocks[len(r.blocks)-1].seq
+	}
+	r.blocks = append(r.blocks, rs)
+	r.mtx.Unlock()
+	if r.window != nil {
+		if r.window.Full(rs) {
+			r.rsc <- r.window.WindowFull()
+		}
+		r.window.Append(rs)
+	}
+	r.rsc <- rs
+	return rs
+}
+
+func (r *testReceiver) block(height int64) *testReceiveStatus {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if height < 1 || height > int64(len(r.blocks)) {
+		return nil
+	}
+	return r.blocks[height-1]
+}
+
+func (r *testReceiver) Start(bls *types.BMCLinkStatus) (<-chan interface{}, error) {
+	return r.rsc, nil
+}
+
+func (r *testReceiver) Stop() {}
+
+func (r *testReceiver) GetStatus() (ReceiveStatus, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.blocks) == 0 {
+		return nil, errors.NotFoundError.New("no receive status")
+	}
+	return r.blocks[len(r.blocks)-1], nil
+}
+
+func (r *testReceiver) BuildBlockUpdate(bls *types.BMCLinkStatus, limit int64) ([]BlockUpdate, error) {
+	bus := make([]BlockUpdate, 0)
+	var size int64
+	for h := bls.Verifier.Height + 1; r.block(h) != nil; h++ {
+		if size += testUpdateSize; size > limit {
+			break
+		}
+		bus = append(bus, &testBlockUpdate{
+			testItem: testItem{TypeBlockUpdate, testUpdateSize},
+			src:      h - 1,
+			target:   h,
+		})
+	}
+	return bus, nil
+}
+
+func (r *testReceiver) BuildBlockProof(bls *types.BMCLinkStatus, height int64) (BlockProof, error) {
+	if height < 1 || height >= bls.Verifier.Height {
+		return nil, nil
+	}
+	if r.blockProof != nil {
+		return r.blockProof(bls, height)
+	}
+	return &testBlockProof{
+		testItem: testItem{TypeBlockProof, testProofSize},
+		height:   height,
+		at:       bls.Verifier.Height,
+	}, nil
+}
+
+func (r *testReceiver) BuildMessageProof(bls *types.BMCLinkStatus, limit int64) (MessageProof, error) {
+	rs := r.block(bls.Verifier.Height)
+	if rs == nil || rs.seq <= bls.RxSeq {
+		return nil, nil
+	}
+	n := rs.seq - bls.RxSeq
+	if max := limit / testMessageSize; n > max {
+		n = max
+	}
+	if n < 1 {
+		return nil, nil
+	}
+	return &testMessageProof{
+		testItem: testItem{TypeMessageProof, n * testMessageSize},
+		height:   rs.height,
+		start:    bls.RxSeq,
+		last:     bls.RxSeq + n,
+	}, nil
+}
+
+func (r *testReceiver) GetHeightForSeq(seq int64) int64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, rs := range r.blocks {
+		if rs.seq == seq {
+			return rs.height
+		}
+	}
+	return 0
+}
+
+func (r *testReceiver) BuildRelayMessage(rmis []RelayMessageItem) ([]byte, error) {
+	items := make([]string, len(rmis))
+	for i, rmi := range rmis {
+		items[i] = fmt.Sprint(rmi)
+	}
+	return []byte(strings.Join(items, ",")), nil
+}
+
+func (r *testReceiver) FinalizedStatus(blsc <-chan *types.BMCLinkStatus) {
+	go func() {
+		for bls := range blsc {
+			if r.window != nil {
+				r.window.RemoveDelivered(bls.Verifier.Height, bls.RxSeq)
+			}
+			r.finalized <- bls
+		}
+	}()
+}
+
+// testSender applies relay messages to the status of the destination like
+// BMC, and returns results asynchronously.
+type testSender struct {
+	mtx     sync.Mutex
+	rr      chan *types.RelayResult
+	p       types.Preference
+	bls     *types.BMCLinkStatus
+	relayed []string
+	// result overrides the error code of the relay message if it's not nil.
+	result func(rm types.RelayMessage) errors.Code
+}
+
+func newTestSender() *testSender {
+	return &testSender{
+		rr:  make(chan *types.RelayResult),
+		p:   types.Preference{TxSizeLimit: 1000},
+		bls: &types.BMCLinkStatus{},
+	}
+}
+
+func (s *testSender) Start() (<-chan *types.RelayResult, error) {
+	return s.rr, nil
+}
+
+func (s *testSender) Stop() {}
+
+func (s *testSender) GetStatus() (*types.BMCLinkStatus, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	bls := *s.bls
+	return &bls, nil
+}
+
+// apply handles items of the relay message like BMC and BMV.
+func (s *testSender) apply(rmis []RelayMessageItem) errors.Code {
+	bls := *s.bls
+	for _, rmi := range rmis {
+		switch item := rmi.(type) {
+		case *testBlockUpdate:
+			if item.src != bls.Verifier.Height {
+				return errors.BMVNotVerifiable
+			}
+		case *testBlockProof:
+			if item.at != bls.Verifier.Height {
+				return errors.BMVRevertInvalidBlockWitnessOld
+			}
+		case *testMessageProof:
+			if item.start != bls.RxSeq {
+				return errors.BMCRevertInvalidSN
+			}
+		}
+		rmi.UpdateBMCLinkStatus(&bls)
+	}
+	*s.bls = bls
+	return errors.SUCCESS
+}
+
+func (s *testSender) Relay(rm types.RelayMessage) (string, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.relayed = append(s.relayed, string(rm.Bytes()))
+	code := errors.SUCCESS
+	if s.result != nil {
+		code = s.result(rm)
+	}
+	if code == errors.SUCCESS {
+		code = s.apply(rm.(*relayMessage).RelayMessageItems())
+	}
+	go func() {
+		s.rr <- &types.RelayResult{Id: rm.Id(), Err: code, Finalized: true}
+	}()
+	return rm.Id(), nil
+}
+
+func (s *testSender) GetPreference() types.Preference {
+	return s.p
+}
+
+func (s *testSender) Relayed() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string{}, s.relayed...)
+}
+
+func (s *testSender) Status() types.BMCLinkStatus {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return *s.bls
+}
+
+func startTestLink(t *testing.T, r *testReceiver, s *testSender, epCfg *ErrorPolicyConfig) (*Link, chan error) {
+	ep, err := NewErrorPolicy(epCfg)
+	assert.NoError(t, err)
+	cfg := &ChainConfigCommon{Address: "btp://0x1.icon/cx0000000000000000000000000000000000000001"}
+	l := NewLink(cfg, r, ep, log.New()).(*Link)
+	errCh := make(chan error, 10)
+	assert.NoError(t, l.Start(s, errCh))
+	return l, errCh
+}
+
+// waitFor waits until the destination receives all messages of the receiver.
+func waitFor(t *testing.T, s *testSender, height, seq int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if bls := s.Status(); bls.Verifier.Height == height && bls.RxSeq == seq {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	bls := s.Status()
+	assert.FailNow(t, "timeout", "status height:%d seq:%d, expected height:%d seq:%d",
+		bls.Verifier.Height, bls.RxSeq, height, seq)
+}
+
+func TestLink_Relay(t *testing.T) {
+	r, s := newTestReceiver(), newTestSender()
+	startTestLink(t, r, s, nil)
+	var last *testReceiveStatus
+	for i := int64(0); i < 5; i++ {
+		last = r.addBlock(i)
+	}
+	waitFor(t, s, last.height, last.seq)
+}
+
+func TestLink_ErrorPolicyStop(t *testing.T) {
+	r, s := newTestReceiver(), newTestSender()
+	s.result = func(rm types.RelayMessage) errors.Code {
+		return errors.BMCRevertUnauthorized
+	}
+	l, errCh := startTestLink(t, r, s, nil)
+	r.addBlock(1)
+	assert.Eventually(t, func() bool {
+		return l.Status().State == STOPPED
+	}, 5*time.Second, time.Millisecond, "link is not stopped with the error")
+	relayed := len(s.Relayed())
+
+	// the link doesn't fail the relay, and doesn't relay any more
+	r.addBlock(1)
+	select {
+	case err := <-errCh:
+		assert.FailNow(t, "unexpected error", "err:%+v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, RelayState(STOPPED), l.Status().State)
+	assert.Len(t, s.Relayed(), relayed)
+}
+
+func TestLink_ErrorPolicyPauseAndRetry(t *testing.T) {
+	for _, action := range []ErrorAction{ActionPause, ActionRetry} {
+		t.Run(string(action), func(t *testing.T) {
+			r, s := newTestReceiver(), newTestSender()
+			var mtx sync.Mutex
+			failures := 2
+			s.result = func(rm types.RelayMessage) errors.Code {
+				mtx.Lock()
+				defer mtx.Unlock()
+				if failures > 0 {
+					failures--
+					return errors.BMCRevertNotExistsBSH
+				}
+				return errors.SUCCESS
+			}
+			l, errCh := startTestLink(t, r, s, &ErrorPolicyConfig{
+				Default:       action,
+				RetryInterval: "1ms",
+				PauseInterval: "10ms",
+			})
+			var last *testReceiveStatus
+			for i := int64(0); i < 5; i++ {
+				last = r.addBlock(2)
+				// status is read while timers update the link
+				l.Status()
+			}
+			waitFor(t, s, last.height, last.seq)
+			assert.Len(t, errCh, 0)
+			assert.Equal(t, RelayState(RUNNING), l.Status().State)
+		})
+	}
+}
+
+func TestLink_WindowFullWithFilledBlockUpdate(t *testing.T) {
+	r, s := newTestReceiver(), newTestSender()
+	r.window = NewReceiveStatusListWithWindow(&WindowConfig{MaxCount: 3})
+	s.p.FilledBlockUpdate = true
+	startTestLink(t, r, s, nil)
+
+	// blocks without messages are kept for filling the block update, so
+	// the receiver waits for finalization on the full window
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			r.addBlock(0)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "receiver is blocked by the full window")
+	}
+	assert.NotEmpty(t, s.Relayed())
+
+	last := r.addBlock(1)
+	waitFor(t, s, last.height, last.seq)
+}
+
+func TestLink_PackBlockUpdates(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		limit   int64
+		blocks  int
+		relayed []string
+	}{
+		{"in the limit", 1000, 4, []string{
+			"BU(0->1),BU(1->2),BU(2->3),BU(3->4),MP(4:0-2)",
+		}},
+		{"over the limit", 350, 6, []string{
+			"BU(0->1),BU(1->2),BU(2->3)",
+			"BU(3->4),BU(4->5),BU(5->6),MP(6:0-2)",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, s := newTestReceiver(), newTestSender()
+			s.p.TxSizeLimit = tc.limit
+			s.p.FilledBlockUpdate = true
+			startTestLink(t, r, s, nil)
+
+			// block updates without messages are packed with the following ones
+			for i := 1; i < tc.blocks; i++ {
+				r.addBlock(0)
+			}
+			last := r.addBlock(2)
+			waitFor(t, s, last.height, last.seq)
+			assert.Equal(t, tc.relayed, s.Relayed())
+		})
+	}
+}
+
+// startTestLinkWithUndelivered starts the link for the destination having the
+// verifier at the block with undelivered messages, which are relayed with
+// the block proof of the block having the messages.
+func startTestLinkWithUndelivered(t *testing.T, r *testReceiver, s *testSender) chan error {
+	r.blocks = []*testReceiveStatus{{height: 1, seq: 0}, {height: 2, seq: 2}}
+	s.bls.Verifier.Height = 3
+	_, errCh := startTestLink(t, r, s, nil)
+	r.addBlock(0)
+	return errCh
+}
+
+func TestLink_UpdateBlockProof(t *testing.T) {
+	r, s := newTestReceiver(), newTestSender()
+	var once sync.Once
+	s.result = func(rm types.RelayMessage) errors.Code {
+		code := errors.SUCCESS
+		once.Do(func() {
+			// the verifier is updated by another relay
+			s.bls.Verifier.Height = 4
+			code = errors.BMVRevertInvalidBlockWitnessOld
+		})
+		return code
+	}
+	startTestLinkWithUndelivered(t, r, s)
+
+	waitFor(t, s, 4, 2)
+	assert.Equal(t, []string{
+		"BP(2@3),MP(3:0-2)",
+		"BP(2@4),MP(3:0-2)",
+	}, s.Relayed())
+}
+
+func TestLink_UpdateBlockProofWithoutChange(t *testing.T) {
+	r, s := newTestReceiver(), newTestSender()
+	s.result = func(rm types.RelayMessage) errors.Code {
+		return errors.BMVRevertInvalidBlockWitnessOld
+	}
+	errCh := startTestLinkWithUndelivered(t, r, s)
+
+	// the message is not resent for the same block proof
+	assert.Eventually(t, func() bool {
+		return len(s.Relayed()) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Never(t, func() bool {
+		return len(s.Relayed()) > 1
+	}, 100*time.Millisecond, 5*time.Millisecond)
+	assert.Len(t, errCh, 0)
+	assert.Equal(t, []string{"BP(2@3),MP(3:0-2)"}, s.Relayed())
+}
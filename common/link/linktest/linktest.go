@@ -0,0 +1,70 @@
+// Package linktest provides helpers for tests of receivers and their
+// stores.
+package linktest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/jsonrpc"
+)
+
+// NewDatabase opens the goleveldb database in dir, it's closed at the end
+// of the test unless it's closed already.
+func NewDatabase(t *testing.T, dir string) db.Database {
+	database, err := db.NewGoLevelDB("test", dir)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		database.Close()
+	})
+	return database
+}
+
+// BucketKeys returns keys of the bucket in order.
+func BucketKeys(t *testing.T, database db.Database, id db.BucketID) [][]byte {
+	bk, err := database.GetBucket(id)
+	assert.NoError(t, err)
+	it := bk.NewIterator(nil)
+	defer it.Release()
+	var keys [][]byte
+	for it.Next() {
+		keys = append(keys, append([]byte{}, it.Key()...))
+	}
+	assert.NoError(t, it.Error())
+	return keys
+}
+
+// HandleFunc returns the result of the JSON-RPC request.
+type HandleFunc func(method string, params json.RawMessage) (interface{}, *jsonrpc.Error)
+
+// JSONRPCHandler serves JSON-RPC requests with handle.
+func JSONRPCHandler(handle HandleFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &jsonrpc.Request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		result, jErr := handle(req.Method, req.Params)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&jsonrpc.Response{
+			Version: jsonrpc.Version,
+			Result:  result,
+			Error:   jErr,
+			ID:      req.ID,
+		})
+	})
+}
+
+// ServeJSONRPC starts the server of JSON-RPC requests with handle, it's
+// closed at the end of the test.
+func ServeJSONRPC(t *testing.T, handle HandleFunc) *httptest.Server {
+	srv := httptest.NewServer(JSONRPCHandler(handle))
+	t.Cleanup(srv.Close)
+	return srv
+}
@@ -0,0 +1,168 @@
+package link
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/icon-project/btp2/common/errors"
+)
+
+type ErrorAction string
+
+const (
+	// ActionRetry resends the relay message with backoff
+	ActionRetry ErrorAction = "retry"
+	// ActionResync drops pending relay messages and rebuilds them from the status of BMC
+	ActionResync ErrorAction = "resync"
+	// ActionPause stops relaying for a while, then resyncs
+	ActionPause ErrorAction = "pause"
+	// ActionStop stops relaying until restart
+	ActionStop ErrorAction = "stop"
+)
+
+func (a ErrorAction) Valid() bool {
+	switch a {
+	case ActionRetry, ActionResync, ActionPause, ActionStop:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	DefaultRetryLimit    = 3
+	DefaultRetryInterval = 5 * time.Second
+	DefaultPauseInterval = time.Minute
+)
+
+var defaultErrorActions = map[errors.Code]ErrorAction{
+	errors.BMCRevertUnauthorized:        ActionStop,
+	errors.BMCRevertInvalidSN:           ActionResync,
+	errors.BMCRevertNotExistsBMV:        ActionStop,
+	errors.BMCRevertNotExistsLink:       ActionStop,
+	errors.BMCRevertUnreachable:         ActionStop,
+	errors.BMCRevertNotExistsPermission: ActionStop,
+	errors.BMVUnknown:                   ActionResync,
+}
+
+// ErrorPolicyConfig is the configuration of ErrorPolicy, keys of Actions are
+// names of revert codes (e.g. "BMCRevertUnauthorized") or numbers.
+type ErrorPolicyConfig struct {
+	Default       ErrorAction            `json:"default,omitempty"`
+	Actions       map[string]ErrorAction `json:"actions,omitempty"`
+	RetryLimit    int                    `json:"retry_limit,omitempty"`
+	RetryInterval string                 `json:"retry_interval,omitempty"`
+	PauseInterval string                 `json:"pause_interval,omitempty"`
+}
+
+// ErrorPolicy decides the action for the error code of RelayResult
+type ErrorPolicy struct {
+	actions       map[errors.Code]ErrorAction
+	defaultAction ErrorAction
+	retryLimit    int
+	retryInterval time.Duration
+	pauseInterval time.Duration
+}
+
+func (p *ErrorPolicy) Action(c errors.Code) ErrorAction {
+	if a, ok := p.actions[c]; ok {
+		return a
+	}
+	return p.defaultAction
+}
+
+func (p *ErrorPolicy) RetryLimit() int {
+	return p.retryLimit
+}
+
+// RetryInterval returns the interval before n-th retry, which is doubled
+// for each retry.
+func (p *ErrorPolicy) RetryInterval(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	return p.retryInterval << uint(n-1)
+}
+
+// PauseInterval returns the interval to resume paused link, zero means
+// that the link is not resumed automatically.
+func (p *ErrorPolicy) PauseInterval() time.Duration {
+	return p.pauseInterval
+}
+
+func parseCode(s string) (errors.Code, error) {
+	for _, names := range []map[errors.Code]string{
+		errors.BMCRevertCodeNames, errors.BMVRevertCodeNames,
+	} {
+		for c, name := range names {
+			if name == s {
+				return c, nil
+			}
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return errors.Code(n), nil
+	}
+	return 0, errors.IllegalArgumentError.Errorf("unknown error code %s", s)
+}
+
+func parseInterval(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.IllegalArgumentError.Wrapf(err, "invalid interval %s", s)
+	}
+	return d, nil
+}
+
+func NewErrorPolicy(cfg *ErrorPolicyConfig) (*ErrorPolicy, error) {
+	p := &ErrorPolicy{
+		actions:       make(map[errors.Code]ErrorAction),
+		defaultAction: ActionPause,
+		retryLimit:    DefaultRetryLimit,
+		retryInterval: DefaultRetryInterval,
+		pauseInterval: DefaultPauseInterval,
+	}
+	for c, a := range defaultErrorActions {
+		p.actions[c] = a
+	}
+	if cfg == nil {
+		return p, nil
+	}
+
+	if cfg.Default != "" {
+		if !cfg.Default.Valid() {
+			return nil, errors.IllegalArgumentError.Errorf("invalid default action %s", cfg.Default)
+		}
+		p.defaultAction = cfg.Default
+	}
+	for k, a := range cfg.Actions {
+		c, err := parseCode(k)
+		if err != nil {
+			return nil, err
+		}
+		if !a.Valid() {
+			return nil, errors.IllegalArgumentError.Errorf("invalid action %s for %s", a, k)
+		}
+		p.actions[c] = a
+	}
+	if cfg.RetryLimit > 0 {
+		p.retryLimit = cfg.RetryLimit
+	}
+	var err error
+	if p.retryInterval, err = parseInterval(cfg.RetryInterval, DefaultRetryInterval); err != nil {
+		return nil, err
+	}
+	if p.pauseInterval, err = parseInterval(cfg.PauseInterval, DefaultPauseInterval); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *ErrorPolicy) String() string {
+	return fmt.Sprintf("ErrorPolicy{default:%s,actions:%v,retry:%d/%s,pause:%s}",
+		p.defaultAction, p.actions, p.retryLimit, p.retryInterval, p.pauseInterval)
+}
@@ -0,0 +1,42 @@
+package link
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/errors"
+)
+
+func TestNewErrorPolicy(t *testing.T) {
+	p, err := NewErrorPolicy(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ActionStop, p.Action(errors.BMCRevertUnauthorized))
+	assert.Equal(t, ActionResync, p.Action(errors.BMVUnknown))
+	assert.Equal(t, ActionPause, p.Action(errors.CodeBSH))
+
+	p, err = NewErrorPolicy(&ErrorPolicyConfig{
+		Default: ActionRetry,
+		Actions: map[string]ErrorAction{
+			"BMCRevertUnauthorized": ActionPause,
+			"40":                    ActionStop,
+		},
+		RetryInterval: "1s",
+		PauseInterval: "0s",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ActionPause, p.Action(errors.BMCRevertUnauthorized))
+	assert.Equal(t, ActionStop, p.Action(errors.CodeBSH))
+	assert.Equal(t, ActionRetry, p.Action(errors.BMCRevertNotExistsBSH))
+	assert.Equal(t, 4*time.Second, p.RetryInterval(3))
+	assert.Equal(t, time.Duration(0), p.PauseInterval())
+
+	_, err = NewErrorPolicy(&ErrorPolicyConfig{
+		Actions: map[string]ErrorAction{"Unknown": ActionStop},
+	})
+	assert.True(t, errors.IllegalArgumentError.Equals(err))
+
+	_, err = NewErrorPolicy(&ErrorPolicyConfig{Default: "ignore"})
+	assert.True(t, errors.IllegalArgumentError.Equals(err))
+}
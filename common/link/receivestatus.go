@@ -0,0 +1,241 @@
+package link
+
+import (
+	"sync"
+
+	"github.com/icon-project/btp2/common/errors"
+)
+
+const (
+	DefaultWindowCount = 1000
+	DefaultWindowBytes = 64 * 1024 * 1024
+)
+
+// WindowConfig bounds the receive statuses pending for finalization. Zero
+// is for the default, and negative to disable the limit.
+type WindowConfig struct {
+	MaxCount int   `json:"max_count,omitempty"`
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Sizer is implemented by the receive status holding payloads of messages,
+// whose size is counted for MaxBytes of the window.
+type Sizer interface {
+	Size() int64
+}
+
+func sizeOf(rs ReceiveStatus) int64 {
+	if s, ok := rs.(Sizer); ok {
+		return s.Size()
+	}
+	return 0
+}
+
+// WindowFull is sent through the channel of the receiver before it pauses
+// monitoring for the full window. Statuses are removed from the window by
+// finalization, so the link relays pending block updates even if the
+// sender prefers filled block updates.
+type WindowFull struct {
+	Count int
+	Bytes int64
+}
+
+// WindowFull returns the notification of the full window.
+func (l *ReceiveStatusList) WindowFull() WindowFull {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return WindowFull{Count: len(l.rss), Bytes: l.bytes}
+}
+
+// ReceiveStatusList is the list of receive statuses ordered by height. It's
+// appended by the monitor of the receiver, read to build relay messages and
+// truncated on finalization, so all methods are safe for concurrent use.
+type ReceiveStatusList struct {
+	mtx      sync.RWMutex
+	cond     *sync.Cond
+	rss      []ReceiveStatus
+	bytes    int64
+	maxCount int
+	maxBytes int64
+	closed   bool
+}
+
+// NewReceiveStatusList returns the list without the window.
+func NewReceiveStatusList() *ReceiveStatusList {
+	return NewReceiveStatusListWithWindow(&WindowConfig{MaxCount: -1, MaxBytes: -1})
+}
+
+// NewReceiveStatusListWithWindow returns the list whose Append blocks while
+// the window is full, so the monitor of the receiver pauses until
+// finalization catches up.
+func NewReceiveStatusListWithWindow(cfg *WindowConfig) *ReceiveStatusList {
+	l := &ReceiveStatusList{
+		rss:      make([]ReceiveStatus, 0),
+		maxCount: cfg.MaxCount,
+		maxBytes: cfg.MaxBytes,
+	}
+	if l.maxCount == 0 {
+		l.maxCount = DefaultWindowCount
+	}
+	if l.maxBytes == 0 {
+		l.maxBytes = DefaultWindowBytes
+	}
+	l.cond = sync.NewCond(&l.mtx)
+	return l
+}
+
+func (l *ReceiveStatusList) isFull(size int64) bool {
+	if len(l.rss) == 0 || l.closed {
+		return false
+	}
+	return (l.maxCount > 0 && len(l.rss) >= l.maxCount) ||
+		(l.maxBytes > 0 && l.bytes+size > l.maxBytes)
+}
+
+// Full returns whether Append blocks for the status.
+func (l *ReceiveStatusList) Full(rs ReceiveStatus) bool {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.isFull(sizeOf(rs))
+}
+
+// Append adds the status, it waits for removal of statuses while the
+// window is full. A status is always accepted by the empty list.
+func (l *ReceiveStatusList) Append(rs ReceiveStatus) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	size := sizeOf(rs)
+	for l.isFull(size) {
+		l.cond.Wait()
+	}
+	l.rss = append(l.rss, rs)
+	l.bytes += size
+}
+
+// Close releases Append waiting for the window, and disables the window.
+func (l *ReceiveStatusList) Close() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.closed = true
+	l.cond.Broadcast()
+}
+
+// Reset replaces the statuses with rss regardless of the window.
+func (l *ReceiveStatusList) Reset(rss []ReceiveStatus) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.rss = append(make([]ReceiveStatus, 0, len(rss)), rss...)
+	l.bytes = 0
+	for _, rs := range l.rss {
+		l.bytes += sizeOf(rs)
+	}
+	l.cond.Broadcast()
+}
+
+func (l *ReceiveStatusList) Len() int {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return len(l.rss)
+}
+
+// Bytes returns the sum of sizes of statuses implementing Sizer.
+func (l *ReceiveStatusList) Bytes() int64 {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.bytes
+}
+
+// First returns the oldest status, nil if it's empty.
+func (l *ReceiveStatusList) First() ReceiveStatus {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if len(l.rss) == 0 {
+		return nil
+	}
+	return l.rss[0]
+}
+
+// Last returns the latest status, nil if it's empty.
+func (l *ReceiveStatusList) Last() ReceiveStatus {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if len(l.rss) == 0 {
+		return nil
+	}
+	return l.rss[len(l.rss)-1]
+}
+
+// All returns all statuses.
+func (l *ReceiveStatusList) All() []ReceiveStatus {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return append([]ReceiveStatus{}, l.rss...)
+}
+
+// Status returns the latest status for Receiver.GetStatus.
+func (l *ReceiveStatusList) Status() (ReceiveStatus, error) {
+	if rs := l.Last(); rs != nil {
+		return rs, nil
+	}
+	return nil, errors.NotFoundError.New("no receive status")
+}
+
+// Find returns the first status matched with f, nil if there is no one.
+func (l *ReceiveStatusList) Find(f func(rs ReceiveStatus) bool) ReceiveStatus {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	for _, rs := range l.rss {
+		if f(rs) {
+			return rs
+		}
+	}
+	return nil
+}
+
+func (l *ReceiveStatusList) ForHeight(height int64) ReceiveStatus {
+	return l.Find(func(rs ReceiveStatus) bool {
+		return rs.Height() == height
+	})
+}
+
+// After returns the statuses higher than the height.
+func (l *ReceiveStatusList) After(height int64) []ReceiveStatus {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	for i, rs := range l.rss {
+		if height < rs.Height() {
+			return append([]ReceiveStatus{}, l.rss[i:]...)
+		}
+	}
+	return nil
+}
+
+// RemoveWhile removes the oldest statuses while f returns true, and
+// returns the removed ones.
+func (l *ReceiveStatusList) RemoveWhile(f func(rs ReceiveStatus) bool) []ReceiveStatus {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	i := 0
+	for ; i < len(l.rss) && f(l.rss[i]); i++ {
+	}
+	if i == 0 {
+		return nil
+	}
+	removed := append([]ReceiveStatus{}, l.rss[:i]...)
+	for j, rs := range removed {
+		l.bytes -= sizeOf(rs)
+		// release payloads held by the backing array
+		l.rss[j] = nil
+	}
+	l.rss = l.rss[i:]
+	l.cond.Broadcast()
+	return removed
+}
+
+// RemoveDelivered removes the statuses delivered to the destination of
+// the verifier height and rxSeq.
+func (l *ReceiveStatusList) RemoveDelivered(height, seq int64) []ReceiveStatus {
+	return l.RemoveWhile(func(rs ReceiveStatus) bool {
+		return rs.Height() <= height && rs.Seq() <= seq
+	})
+}
@@ -0,0 +1,168 @@
+package link
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/errors"
+)
+
+type testReceiveStatus struct {
+	height int64
+	seq    int64
+}
+
+func (r *testReceiveStatus) Height() int64 {
+	return r.height
+}
+
+func (r *testReceiveStatus) Seq() int64 {
+	return r.seq
+}
+
+func TestReceiveStatusList(t *testing.T) {
+	l := NewReceiveStatusList()
+	_, err := l.Status()
+	assert.True(t, errors.NotFoundError.Equals(err))
+	assert.Nil(t, l.First())
+	assert.Nil(t, l.Last())
+	assert.Nil(t, l.RemoveDelivered(10, 10))
+
+	for h := int64(1); h <= 5; h++ {
+		l.Append(&testReceiveStatus{height: h * 10, seq: h * 2})
+	}
+	rs, err := l.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), rs.Height())
+	assert.Equal(t, int64(30), l.ForHeight(30).Height())
+	assert.Nil(t, l.ForHeight(31))
+	assert.Len(t, l.After(30), 2)
+	assert.Len(t, l.After(50), 0)
+
+	// the status at height 30 has undelivered messages
+	removed := l.RemoveDelivered(30, 5)
+	assert.Len(t, removed, 2)
+	assert.Equal(t, 3, l.Len())
+	assert.Equal(t, int64(30), l.First().Height())
+
+	l.Reset(nil)
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestReceiveStatusList_Race(t *testing.T) {
+	const count = 1000
+	l := NewReceiveStatusList()
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	// monitor of the receiver
+	go func() {
+		defer wg.Done()
+		for h := int64(1); h <= count; h++ {
+			l.Append(&testReceiveStatus{height: h, seq: h})
+		}
+	}()
+
+	// link building relay messages
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			if rs, err := l.Status(); err == nil {
+				l.ForHeight(rs.Height())
+				for _, r := range l.After(rs.Height() - 10) {
+					assert.True(t, r.Height() > rs.Height()-10)
+				}
+			}
+		}
+	}()
+
+	// finalization
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			if rs := l.Last(); rs != nil {
+				l.RemoveDelivered(rs.Height()-5, rs.Seq()-5)
+			}
+		}
+	}()
+	wg.Wait()
+
+	all := l.All()
+	assert.Equal(t, int64(count), all[len(all)-1].Height())
+	for i := 1; i < len(all); i++ {
+		assert.Equal(t, all[i-1].Height()+1, all[i].Height())
+	}
+}
+
+type testSizedStatus struct {
+	testReceiveStatus
+	size int64
+}
+
+func (r *testSizedStatus) Size() int64 {
+	return r.size
+}
+
+func appendAsync(l *ReceiveStatusList, rs ReceiveStatus) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		l.Append(rs)
+		close(done)
+	}()
+	return done
+}
+
+func assertBlocked(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+		assert.Fail(t, "Append is not blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func assertReleased(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Append is not released")
+	}
+}
+
+func TestReceiveStatusList_Window(t *testing.T) {
+	l := NewReceiveStatusListWithWindow(&WindowConfig{MaxCount: 2, MaxBytes: -1})
+	l.Append(&testReceiveStatus{height: 1, seq: 1})
+	l.Append(&testReceiveStatus{height: 2, seq: 2})
+	rs := &testReceiveStatus{height: 3, seq: 3}
+	assert.True(t, l.Full(rs))
+
+	// resumed by finalization
+	done := appendAsync(l, rs)
+	assertBlocked(t, done)
+	l.RemoveDelivered(1, 1)
+	assertReleased(t, done)
+	assert.Equal(t, 2, l.Len())
+
+	// released by Close
+	done = appendAsync(l, &testReceiveStatus{height: 4, seq: 4})
+	assertBlocked(t, done)
+	l.Close()
+	assertReleased(t, done)
+
+	l = NewReceiveStatusListWithWindow(&WindowConfig{MaxCount: -1, MaxBytes: 100})
+	// the empty list accepts a status larger than the window
+	l.Append(&testSizedStatus{testReceiveStatus{1, 1}, 150})
+	done = appendAsync(l, &testSizedStatus{testReceiveStatus{2, 2}, 60})
+	assertBlocked(t, done)
+	l.RemoveDelivered(1, 1)
+	assertReleased(t, done)
+	assert.Equal(t, int64(60), l.Bytes())
+	l.Append(&testSizedStatus{testReceiveStatus{3, 3}, 40})
+	assert.True(t, l.Full(&testSizedStatus{testReceiveStatus{4, 4}, 1}))
+	assert.False(t, l.Full(&testReceiveStatus{4, 4}))
+
+	l.Reset([]ReceiveStatus{&testSizedStatus{testReceiveStatus{5, 5}, 10}})
+	assert.Equal(t, int64(10), l.Bytes())
+}
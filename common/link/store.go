@@ -0,0 +1,58 @@
+package link
+
+import (
+	"encoding/json"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+const (
+	StoreEntryHeader = "header"
+	StoreEntryStatus = "status"
+)
+
+// StoreEntry is the data stored by the receiver for the height.
+type StoreEntry struct {
+	Kind   string      `json:"kind"`
+	Height int64       `json:"height"`
+	Seq    int64       `json:"seq,omitempty"`
+	Value  interface{} `json:"value"`
+}
+
+// Store is the database of the receiver, which is opened without the link
+// for maintenance. The relay should be stopped while it's opened.
+type Store interface {
+	LastReceiveHeight() (int64, error)
+	// Entries returns the stored entries ordered by height.
+	Entries() ([]*StoreEntry, error)
+	// Reset removes the entries after the height, so that the receiver
+	// resumes from the height.
+	Reset(height int64) error
+	// Verify checks the stored data could be resumed with the status of
+	// the destination.
+	Verify(bls *types.BMCLinkStatus) error
+	Compact() error
+	Close() error
+}
+
+func OpenStore(srcRaw json.RawMessage, baseDir string, l log.Logger) (Store, error) {
+	var srcCfgCommon ChainConfigCommon
+	if err := json.Unmarshal(srcRaw, &srcCfgCommon); err != nil {
+		return nil, err
+	}
+	srcType := srcCfgCommon.GetType()
+	f, ok := factories[srcType]
+	if !ok {
+		return nil, errors.NotFoundError.Errorf("UnknownSourceType(type=%s)", srcType)
+	}
+	if f.OpenStore == nil {
+		return nil, errors.UnsupportedError.Errorf("NoStore(type=%s)", srcType)
+	}
+	srcCfg, err := f.ParseChainConfig(srcRaw)
+	if err != nil {
+		return nil, err
+	}
+	return f.OpenStore(srcCfg, baseDir, l)
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mbt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/icon-project/btp2/common/codec"
+)
+
+// ProofRange is the range of leaves from Begin to End, both are included
+// and start from 1.
+type ProofRange struct {
+	Begin int
+	End   int
+}
+
+func (r ProofRange) String() string {
+	return fmt.Sprintf("[%d,%d]", r.Begin, r.End)
+}
+
+// MerkleBinaryTreeMultiProof proves disjoint ranges of leaves. Nodes are
+// roots of subtrees without proven leaves from left to right, so siblings
+// shared by ranges appear once.
+type MerkleBinaryTreeMultiProof struct {
+	Ranges   []ProofRange
+	Contents [][]byte
+	Nodes    []ProofNode
+	hashFunc HashFunc
+}
+
+// normalizeRanges returns sorted ranges, and merges adjacent ranges.
+func normalizeRanges(ranges []ProofRange, total int) ([]ProofRange, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no range")
+	}
+	nr := make([]ProofRange, 0, len(ranges))
+	for i, r := range ranges {
+		if r.Begin < 1 || r.End < 1 || r.End > total {
+			return nil, OutOfRange
+		}
+		if r.Begin > r.End {
+			return nil, fmt.Errorf("begin should be less than end")
+		}
+		if i > 0 && r.Begin <= ranges[i-1].End {
+			return nil, fmt.Errorf("ranges should be sorted and disjoint %s %s", ranges[i-1], r)
+		}
+		if len(nr) > 0 && nr[len(nr)-1].End+1 == r.Begin {
+			nr[len(nr)-1].End = r.End
+		} else {
+			nr = append(nr, r)
+		}
+	}
+	return nr, nil
+}
+
+func intersects(ranges []ProofRange, begin, end int) bool {
+	for _, r := range ranges {
+		if r.Begin <= end && r.End >= begin {
+			return true
+		}
+	}
+	return false
+}
+
+// multiProof appends roots of subtrees without leaves in the ranges. begin
+// is the number of the first leaf of n.
+func (n *node) multiProof(begin int, ranges []ProofRange, nodes []ProofNode) []ProofNode {
+	if !intersects(ranges, begin, begin+n.numOfLeaf-1) {
+		return append(nodes, ProofNode{NumOfLeaf: n.numOfLeaf, Value: n.h})
+	}
+	if n.level < levelBranch {
+		return nodes
+	}
+	nodes = n.l.multiProof(begin, ranges, nodes)
+	return n.r.multiProof(begin+n.l.numOfLeaf, ranges, nodes)
+}
+
+// MultiProof returns the proof of the ranges, which should be sorted and
+// disjoint.
+func (m *MerkleBinaryTree) MultiProof(ranges ...ProofRange) (*MerkleBinaryTreeMultiProof, error) {
+	nr, err := normalizeRanges(ranges, m.Len())
+	if err != nil {
+		return nil, err
+	}
+	p := &MerkleBinaryTreeMultiProof{
+		Ranges:   nr,
+		Contents: make([][]byte, 0),
+		Nodes:    m.root.multiProof(1, nr, make([]ProofNode, 0)),
+		hashFunc: m.hashFunc,
+	}
+	for _, r := range nr {
+		p.Contents = append(p.Contents, m.contents[r.Begin-1:r.End]...)
+	}
+	return p, nil
+}
+
+func addProofNode(r *node, pn ProofNode) (*node, error) {
+	if pn.NumOfLeaf < 1 || len(pn.Value) == 0 {
+		return nil, fmt.Errorf("invalid proof node %s", pn)
+	}
+	return r.lazyAdd(pn.NumOfLeaf, pn.Value), nil
+}
+
+// Root returns the root hash and the number of leaves of the tree,
+// which are reconstructed from the proof.
+func (p *MerkleBinaryTreeMultiProof) Root() (h []byte, total int, err error) {
+	if p.hashFunc == nil {
+		return nil, 0, fmt.Errorf("hashFunc cannot be nil")
+	}
+	if len(p.Ranges) == 0 {
+		return nil, 0, fmt.Errorf("no range")
+	}
+	r := &node{hashFunc: p.hashFunc}
+	pos, j, k := 1, 0, 0
+	for i, pr := range p.Ranges {
+		if pr.Begin > pr.End || (i > 0 && pr.Begin <= p.Ranges[i-1].End) {
+			return nil, 0, fmt.Errorf("invalid range %s", pr)
+		}
+		for ; pos < pr.Begin && j < len(p.Nodes); j++ {
+			if r, err = addProofNode(r, p.Nodes[j]); err != nil {
+				return nil, 0, err
+			}
+			pos += p.Nodes[j].NumOfLeaf
+		}
+		if pos != pr.Begin {
+			return nil, 0, fmt.Errorf("nodes don't match range %s", pr)
+		}
+		for ; pos <= pr.End; pos++ {
+			if k >= len(p.Contents) {
+				return nil, 0, fmt.Errorf("not enough contents for range %s", pr)
+			}
+			r = r.lazyAdd(1, p.hashFunc(p.Contents[k]))
+			k++
+		}
+	}
+	if k != len(p.Contents) {
+		return nil, 0, fmt.Errorf("too many contents %d", len(p.Contents))
+	}
+	for ; j < len(p.Nodes); j++ {
+		if r, err = addProofNode(r, p.Nodes[j]); err != nil {
+			return nil, 0, err
+		}
+		pos += p.Nodes[j].NumOfLeaf
+	}
+	r.ensureHash(false)
+	total = pos - 1
+	if r.numOfLeaf != total {
+		return nil, 0, fmt.Errorf("total doesn't match sum: %d, node: %d", total, r.numOfLeaf)
+	}
+	if err = r.verify(); err != nil {
+		return nil, 0, err
+	}
+	return r.h, total, nil
+}
+
+// Verify returns an error if the root of the proof doesn't match.
+func (p *MerkleBinaryTreeMultiProof) Verify(root []byte) error {
+	h, _, err := p.Root()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h, root) {
+		return fmt.Errorf("root mismatch expected:%s, value:%s",
+			hex.EncodeToString(root), hex.EncodeToString(h))
+	}
+	return nil
+}
+
+// Get returns the content of the leaf, nil if it's not in the ranges.
+func (p *MerkleBinaryTreeMultiProof) Get(num int) []byte {
+	k := 0
+	for _, r := range p.Ranges {
+		if num >= r.Begin && num <= r.End {
+			if k+num-r.Begin < len(p.Contents) {
+				return p.Contents[k+num-r.Begin]
+			}
+			return nil
+		}
+		k += r.End - r.Begin + 1
+	}
+	return nil
+}
+
+func (p *MerkleBinaryTreeMultiProof) SetHashFunc(hashFunc HashFunc) {
+	p.hashFunc = hashFunc
+}
+
+func (p *MerkleBinaryTreeMultiProof) Bytes() []byte {
+	return codec.RLP.MustMarshalToBytes(p)
+}
+
+func NewMerkleBinaryTreeMultiProofFromBytes(hashFunc HashFunc, bs []byte) (*MerkleBinaryTreeMultiProof, error) {
+	p := &MerkleBinaryTreeMultiProof{hashFunc: hashFunc}
+	if _, err := codec.RLP.UnmarshalFromBytes(bs, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *MerkleBinaryTreeMultiProof) String() string {
+	s := "MerkleBinaryTreeMultiProof{"
+	for i, r := range p.Ranges {
+		s += fmt.Sprintf("Ranges[%d]:%s\n", i, r)
+	}
+	for i, c := range p.Contents {
+		s += fmt.Sprintf("Contents[%d]:%s\n", i, hex.EncodeToString(c))
+	}
+	for i, pn := range p.Nodes {
+		s += fmt.Sprintf("Nodes[%d]:%s\n", i, pn.String())
+	}
+	s += "}"
+	return s
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mbt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerkleBinaryTreeMultiProof_Basic(t *testing.T) {
+	for n := 1; n <= len(contents); n++ {
+		m, err := NewMerkleBinaryTree(Sha3Keccak256, contents[:n])
+		assert.NoError(t, err)
+		// every subset of leaves as ranges
+		for set := 1; set < 1<<n; set++ {
+			ranges := make([]ProofRange, 0)
+			for i := 0; i < n; i++ {
+				if set&(1<<i) != 0 {
+					ranges = append(ranges, ProofRange{Begin: i + 1, End: i + 1})
+				}
+			}
+			p, err := m.MultiProof(ranges...)
+			assert.NoError(t, err)
+			r, total, err := p.Root()
+			assert.NoError(t, err, "n:%d ranges:%v", n, ranges)
+			assert.Equal(t, m.Root(), r)
+			assert.Equal(t, n, total)
+			for _, pr := range ranges {
+				assert.Equal(t, contents[pr.Begin-1], p.Get(pr.Begin))
+			}
+
+			// RLP
+			p2, err := NewMerkleBinaryTreeMultiProofFromBytes(Sha3Keccak256, p.Bytes())
+			assert.NoError(t, err)
+			assert.NoError(t, p2.Verify(m.Root()))
+		}
+	}
+}
+
+func TestMerkleBinaryTreeMultiProof_Dedup(t *testing.T) {
+	m, err := NewMerkleBinaryTree(Sha3Keccak256, contents[:8])
+	assert.NoError(t, err)
+	p, err := m.MultiProof(ProofRange{1, 2}, ProofRange{3, 3}, ProofRange{7, 8})
+	assert.NoError(t, err)
+	// adjacent ranges are merged
+	assert.Equal(t, []ProofRange{{1, 3}, {7, 8}}, p.Ranges)
+	// leaf 4 and the subtree of 5-6
+	assert.Len(t, p.Nodes, 2)
+	assert.Equal(t, 1, p.Nodes[0].NumOfLeaf)
+	assert.Equal(t, 2, p.Nodes[1].NumOfLeaf)
+	assert.Nil(t, p.Get(4))
+
+	_, err = m.MultiProof()
+	assert.Error(t, err)
+	_, err = m.MultiProof(ProofRange{3, 4}, ProofRange{1, 2})
+	assert.Error(t, err)
+	_, err = m.MultiProof(ProofRange{1, 9})
+	assert.Error(t, err)
+}
+
+func TestMerkleBinaryTreeMultiProof_Fake(t *testing.T) {
+	m, err := NewMerkleBinaryTree(Sha3Keccak256, contents[:7])
+	assert.NoError(t, err)
+	p, err := m.MultiProof(ProofRange{2, 2}, ProofRange{6, 6})
+	assert.NoError(t, err)
+	assert.NoError(t, p.Verify(m.Root()))
+
+	fake := *p
+	fake.Contents = [][]byte{p.Contents[0], []byte("fake")}
+	assert.Error(t, fake.Verify(m.Root()))
+
+	fake = *p
+	fake.Ranges = []ProofRange{{2, 2}, {5, 5}}
+	assert.Error(t, fake.Verify(m.Root()))
+
+	fake = *p
+	fake.Contents = p.Contents[:1]
+	assert.Error(t, fake.Verify(m.Root()))
+
+	fake = *p
+	fake.Nodes = append([]ProofNode{{NumOfLeaf: 1}}, p.Nodes[1:]...)
+	assert.Error(t, fake.Verify(m.Root()))
+}
+
+func TestMerkleBinaryTreeProof_Bytes(t *testing.T) {
+	m, err := NewMerkleBinaryTree(Sha3Keccak256, contents[:7])
+	assert.NoError(t, err)
+	for _, r := range []ProofRange{{1, 7}, {1, 3}, {2, 5}, {4, 7}} {
+		p, err := m.Proof(r.Begin, r.End)
+		assert.NoError(t, err)
+		p2, err := NewMerkleBinaryTreeProofFromBytes(Sha3Keccak256, p.Bytes())
+		assert.NoError(t, err)
+		h, left, total, err := p2.Root()
+		assert.NoError(t, err)
+		assert.Equal(t, m.Root(), h)
+		assert.Equal(t, r.Begin-1, left)
+		assert.Equal(t, 7, total)
+	}
+}
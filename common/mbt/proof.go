@@ -1,8 +1,12 @@
 package mbt
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/intconv"
 )
 
 func NumberToLevel(n int) int {
@@ -86,9 +90,82 @@ func (m *MerkleBinaryTree) ProofOfAll() *MerkleBinaryTreeProof {
 	}
 }
 
+// rlpHeaderSize returns the size of RLP header for bytes or list of size.
+func rlpHeaderSize(size int) int {
+	if size <= 55 {
+		return 1
+	}
+	return 1 + len(intconv.SizeToBytes(uint64(size)))
+}
+
+func rlpBytesSize(b []byte) int {
+	if len(b) == 1 && b[0] < 0x80 {
+		return 1
+	}
+	return rlpHeaderSize(len(b)) + len(b)
+}
+
+func rlpListSize(size int) int {
+	return rlpHeaderSize(size) + size
+}
+
+// rlpNullSize is the size of RLP encoded nil slice.
+const rlpNullSize = 2
+
+func proofNodesSize(pns []ProofNode) int {
+	if pns == nil {
+		return rlpNullSize
+	}
+	size := 0
+	for _, pn := range pns {
+		size += rlpListSize(rlpBytesSize(intconv.Int64ToBytes(int64(pn.NumOfLeaf))) + rlpBytesSize(pn.Value))
+	}
+	return rlpListSize(size)
+}
+
+// contentsSize returns the size of RLP encoded contents from begin to end.
+func (m *MerkleBinaryTree) contentsSize(begin, end int) int {
+	for i := len(m.sizes); i < len(m.contents); i++ {
+		prev := 0
+		if i > 0 {
+			prev = m.sizes[i-1]
+		}
+		m.sizes = append(m.sizes, prev+rlpBytesSize(m.contents[i]))
+	}
+	size := m.sizes[end-1]
+	if begin > 1 {
+		size -= m.sizes[begin-2]
+	}
+	return rlpListSize(size)
+}
+
+// ProofLength returns the length of RLP encoded proof by Proof(begin, end),
+// without encoding. Sizes of contents are cached, so it takes O(log n)
+// except the first call.
 func (m *MerkleBinaryTree) ProofLength(begin, end int) (int, error) {
-	//TODO implement me
-	panic("implement me")
+	if begin < 1 || end < 1 || end > m.Len() {
+		return 0, OutOfRange
+	}
+	if begin > end {
+		return 0, fmt.Errorf("begin should be less than end")
+	}
+	var l, r []ProofNode
+	if begin != 1 || end != m.Len() {
+		if begin == 1 {
+			l = make([]ProofNode, 0)
+			_, r = m.root.proof(end)
+		} else {
+			l, r = m.root.proof(begin)
+			if begin != end {
+				if end == m.Len() {
+					r = r[:0]
+				} else {
+					_, r = m.root.proof(end)
+				}
+			}
+		}
+	}
+	return rlpListSize(proofNodesSize(l) + m.contentsSize(begin, end) + proofNodesSize(r)), nil
 }
 
 func (m *MerkleBinaryTree) Proof(begin, end int) (*MerkleBinaryTreeProof, error) {
@@ -161,10 +238,64 @@ func (p *MerkleBinaryTreeProof) Root() (h []byte, left int, total int, err error
 	return
 }
 
+// VerifyRange verifies the leaves with the proof against the root, without
+// the tree. Contents of the proof are ignored if leaves is not nil. It
+// returns the number of leaves on the left of the range, and the total.
+func VerifyRange(hashFunc HashFunc, root []byte, leaves [][]byte, proof *MerkleBinaryTreeProof) (left, total int, err error) {
+	if hashFunc == nil {
+		return 0, 0, fmt.Errorf("hashFunc cannot be nil")
+	}
+	if leaves == nil {
+		leaves = proof.Contents
+	}
+	if len(leaves) == 0 {
+		return 0, 0, fmt.Errorf("no leaves")
+	}
+	for _, pns := range [][]ProofNode{proof.ProofInLeft, proof.ProofInRight} {
+		for _, pn := range pns {
+			if pn.NumOfLeaf < 1 || len(pn.Value) == 0 {
+				return 0, 0, fmt.Errorf("invalid proof node %s", pn)
+			}
+		}
+	}
+	for _, leaf := range leaves {
+		if leaf == nil {
+			return 0, 0, InvalidContent
+		}
+	}
+	p := &MerkleBinaryTreeProof{
+		ProofInLeft:  proof.ProofInLeft,
+		Contents:     leaves,
+		ProofInRight: proof.ProofInRight,
+		hashFunc:     hashFunc,
+	}
+	var h []byte
+	if h, left, total, err = p.Root(); err != nil {
+		return 0, 0, err
+	}
+	if !bytes.Equal(h, root) {
+		return 0, 0, fmt.Errorf("root mismatch expected:%s, value:%s",
+			hex.EncodeToString(root), hex.EncodeToString(h))
+	}
+	return left, total, nil
+}
+
 func (p *MerkleBinaryTreeProof) SetHashFunc(hashFunc HashFunc) {
 	p.hashFunc = hashFunc
 }
 
+func (p *MerkleBinaryTreeProof) Bytes() []byte {
+	return codec.RLP.MustMarshalToBytes(p)
+}
+
+func NewMerkleBinaryTreeProofFromBytes(hashFunc HashFunc, bs []byte) (*MerkleBinaryTreeProof, error) {
+	p := &MerkleBinaryTreeProof{hashFunc: hashFunc}
+	if _, err := codec.RLP.UnmarshalFromBytes(bs, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
 func (p *MerkleBinaryTreeProof) String() string {
 	s := "MerkleBinaryTreeProof{"
 	for i, pn := range p.ProofInLeft {
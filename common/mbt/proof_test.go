@@ -1,11 +1,13 @@
 package mbt
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/icon-project/btp2/common/codec"
 	"github.com/stretchr/testify/assert"
+	"sort"
 	"testing"
 )
 
@@ -136,3 +138,108 @@ func merkleNode(hashFunc HashFunc, contents [][]byte) *node {
 	n.ensureHash(false)
 	return n
 }
+
+func TestMerkleBinaryTree_ProofLength(t *testing.T) {
+	msgs := make([][]byte, 0)
+	for i := 0; i < 70; i++ {
+		// various sizes for RLP headers
+		msgs = append(msgs, bytes.Repeat([]byte{byte(i)}, i*i%300))
+	}
+	for n := 1; n <= len(msgs); n += 7 {
+		m, err := NewMerkleBinaryTree(Sha3Keccak256, msgs[:n])
+		assert.NoError(t, err)
+		for begin := 1; begin <= n; begin++ {
+			for end := begin; end <= n; end++ {
+				p, err := m.Proof(begin, end)
+				assert.NoError(t, err)
+				l, err := m.ProofLength(begin, end)
+				assert.NoError(t, err)
+				assert.Equal(t, len(codec.RLP.MustMarshalToBytes(p)), l, "n:%d begin:%d end:%d", n, begin, end)
+			}
+		}
+	}
+
+	m, _ := NewMerkleBinaryTree(Sha3Keccak256, msgs[:3])
+	_, err := m.ProofLength(0, 1)
+	assert.Error(t, err)
+	_, err = m.ProofLength(1, 4)
+	assert.Error(t, err)
+	_, err = m.ProofLength(3, 2)
+	assert.Error(t, err)
+
+	// contents added after ProofLength
+	assert.NoError(t, m.Add([]byte("added")))
+	p, _ := m.Proof(2, 4)
+	l, err := m.ProofLength(2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, len(codec.RLP.MustMarshalToBytes(p)), l)
+}
+
+func benchmarkTree(b *testing.B, n int) *MerkleBinaryTree {
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = bytes.Repeat([]byte{byte(i)}, 200)
+	}
+	m, err := NewMerkleBinaryTree(Sha3Keccak256, msgs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+// largest end of the proof from the begin, which fits the limit
+func BenchmarkProofEnd_Encode(b *testing.B) {
+	m := benchmarkTree(b, 2000)
+	limit := 256 * 1024
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		end := 1
+		for ; end < m.Len(); end++ {
+			p, _ := m.Proof(1, end+1)
+			if len(codec.RLP.MustMarshalToBytes(p)) > limit {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkProofEnd_ProofLength(b *testing.B) {
+	m := benchmarkTree(b, 2000)
+	limit := 256 * 1024
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sort.Search(m.Len(), func(j int) bool {
+			l, _ := m.ProofLength(1, j+1)
+			return l > limit
+		})
+	}
+}
+
+func TestVerifyRange(t *testing.T) {
+	m, err := NewMerkleBinaryTree(Sha3Keccak256, contents[:7])
+	assert.NoError(t, err)
+	p, err := m.Proof(3, 5)
+	assert.NoError(t, err)
+	p2, err := NewMerkleBinaryTreeProofFromBytes(nil, p.Bytes())
+	assert.NoError(t, err)
+
+	left, total, err := VerifyRange(Sha3Keccak256, m.Root(), nil, p2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, left)
+	assert.Equal(t, 7, total)
+
+	left, total, err = VerifyRange(Sha3Keccak256, m.Root(), contents[2:5], p2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, left)
+	assert.Equal(t, 7, total)
+
+	_, _, err = VerifyRange(Sha3Keccak256, m.Root(), contents[1:4], p2)
+	assert.Error(t, err)
+	_, _, err = VerifyRange(Sha3FIPS256, m.Root(), nil, p2)
+	assert.Error(t, err)
+
+	fake := *p2
+	fake.ProofInLeft = []ProofNode{{NumOfLeaf: 2}}
+	_, _, err = VerifyRange(Sha3Keccak256, m.Root(), nil, &fake)
+	assert.Error(t, err)
+}
@@ -20,40 +20,25 @@ import (
 	"encoding/hex"
 	"fmt"
 
-	"golang.org/x/crypto/sha3"
+	"github.com/icon-project/btp2/common/ntm"
 )
 
-func Sha3FIPS256(l ...[]byte) []byte {
-	h := sha3.New256()
-	for _, b := range l {
-		h.Write(b)
-	}
-	var digest [32]byte
-	h.Sum(digest[:0])
-	return digest[:]
-}
-
-func Sha3Keccak256(data ...[]byte) []byte {
-	h := sha3.NewLegacyKeccak256()
-	for _, b := range data {
-		h.Write(b)
-	}
-	var digest [32]byte
-	h.Sum(digest[:0])
-	return digest[:]
-}
-
-type HashFunc func(l ...[]byte) []byte
+// HashFunc is the hash function of the tree, implementations are in ntm.
+type HashFunc = ntm.HashFunc
 
 var (
-	uidHashFuncs = map[string]HashFunc{
-		"icon": Sha3FIPS256,
-		"eth":  Sha3Keccak256,
-	}
+	Sha3FIPS256   = ntm.Sha3FIPS256
+	Sha3Keccak256 = ntm.Sha3Keccak256
 )
 
+// HashFuncByUID returns the hash function of the network type named uid,
+// nil for unknown network types.
 func HashFuncByUID(uid string) HashFunc {
-	return uidHashFuncs[uid]
+	nt, err := ntm.ByName(uid)
+	if err != nil {
+		return nil
+	}
+	return nt.HashFunc
 }
 
 const (
@@ -164,6 +149,7 @@ func (n *node) String() string {
 type MerkleBinaryTree struct {
 	root     *node
 	contents [][]byte //nullable
+	sizes    []int    // accumulated sizes of RLP encoded contents
 	hashFunc func(l ...[]byte) []byte
 }
 
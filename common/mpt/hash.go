@@ -0,0 +1,23 @@
+package mpt
+
+import (
+	"golang.org/x/crypto/sha3"
+
+	"github.com/icon-project/btp2/common/crypto"
+)
+
+// HashFunc returns the reference hash of an encoded node.
+type HashFunc func(b []byte) []byte
+
+var (
+	// ICON references nodes with SHA3-256 (FIPS 202)
+	ICON HashFunc = crypto.SHA3Sum256
+	// Ethereum references nodes with legacy Keccak-256
+	Ethereum HashFunc = keccak256
+)
+
+func keccak256(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/icon-project/btp2/common/codec"
-	"github.com/icon-project/btp2/common/crypto"
 )
 
 //refer service/txresult/receiptlist.go:59 receiptList.GetProof(n int) ([][]byte, error)
@@ -14,50 +13,229 @@ type MptNode struct {
 	Header *MptHeader
 	Link   []*MptLink
 	Data   []byte
+
+	serialized []byte
 }
+
+const (
+	prefixOdd  = 0x10
+	prefixLeaf = 0x20
+)
+
+// MptHeader is the hex-prefix encoded path of a leaf or an extension node.
 type MptHeader struct {
 	Prefix  byte
 	Nibbles []byte
 }
 
 func (mh *MptHeader) IsLeaf() bool {
-	return (mh.Prefix & 0x20) != 0
+	return (mh.Prefix & prefixLeaf) != 0
+}
+
+// Path returns the nibbles of the header, one nibble per byte.
+func (mh *MptHeader) Path() []byte {
+	path := make([]byte, 0, len(mh.Nibbles)*2+1)
+	if (mh.Prefix & prefixOdd) != 0 {
+		path = append(path, mh.Prefix&0x0f)
+	}
+	for _, b := range mh.Nibbles {
+		path = append(path, b>>4, b&0x0f)
+	}
+	return path
+}
+
+func (mh *MptHeader) Bytes() []byte {
+	return append([]byte{mh.Prefix}, mh.Nibbles...)
 }
 
+func NewMptHeader(path []byte, leaf bool) *MptHeader {
+	h := &MptHeader{}
+	if leaf {
+		h.Prefix = prefixLeaf
+	}
+	if len(path)%2 == 1 {
+		h.Prefix |= prefixOdd | path[0]
+		path = path[1:]
+	}
+	h.Nibbles = make([]byte, len(path)/2)
+	for i := range h.Nibbles {
+		h.Nibbles[i] = path[i*2]<<4 | path[i*2+1]
+	}
+	return h
+}
+
+// MptLink refers a child node by its hash. A child whose encoding is shorter
+// than a hash is embedded in its parent, then Hash is empty.
 type MptLink struct {
 	Hash []byte
 	ptr  *MptNode
 }
 
+func (ml *MptLink) IsEmpty() bool {
+	return ml == nil || (len(ml.Hash) == 0 && ml.ptr == nil)
+}
+
+// Node returns the resolved child node, nil if it's not resolved yet.
+func (ml *MptLink) Node() *MptNode {
+	if ml == nil {
+		return nil
+	}
+	return ml.ptr
+}
+
+type rawItem []byte
+
+func (r rawItem) MarshalRLP() ([]byte, error) {
+	return r, nil
+}
+
+func (r *rawItem) UnmarshalRLP(b []byte) error {
+	*r = append((*r)[:0], b...)
+	return nil
+}
+
+func (r rawItem) isList() bool {
+	return len(r) > 0 && r[0] >= 0xc0 && !r.isNull()
+}
+
+func (r rawItem) isNull() bool {
+	return len(r) == 2 && r[0] == 0xf8 && r[1] == 0x00
+}
+
+func (r rawItem) bytes() ([]byte, error) {
+	if r.isNull() {
+		return nil, nil
+	}
+	var b []byte
+	if _, err := codec.RLP.UnmarshalFromBytes(r, &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
+
+func (ml *MptLink) encode(e codec.Encoder) error {
+	if ml == nil {
+		return e.Encode([]byte{})
+	}
+	if len(ml.Hash) == 0 && ml.ptr != nil {
+		b, err := ml.ptr.Bytes()
+		if err != nil {
+			return err
+		}
+		raw := rawItem(b)
+		return e.Encode(&raw)
+	}
+	return e.Encode(nonNil(ml.Hash))
+}
+
+func decodeLink(r rawItem) (*MptLink, error) {
+	if r.isList() {
+		n := &MptNode{}
+		if _, err := codec.RLP.UnmarshalFromBytes(r, n); err != nil {
+			return nil, err
+		}
+		return &MptLink{ptr: n}, nil
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &MptLink{Hash: b}, nil
+}
+
 func (n *MptNode) RLPEncodeSelf(e codec.Encoder) error {
-	return fmt.Errorf("not implemented")
+	e2, err := e.EncodeList()
+	if err != nil {
+		return err
+	}
+	if n.Header != nil {
+		if err = e2.Encode(n.Header.Bytes()); err != nil {
+			return err
+		}
+		if n.Header.IsLeaf() {
+			return e2.Encode(nonNil(n.Data))
+		}
+		if len(n.Link) != 1 {
+			return fmt.Errorf("invalid extension link length %d", len(n.Link))
+		}
+		return n.Link[0].encode(e2)
+	}
+	if len(n.Link) != 16 {
+		return fmt.Errorf("invalid branch link length %d", len(n.Link))
+	}
+	for _, ml := range n.Link {
+		if err = ml.encode(e2); err != nil {
+			return err
+		}
+	}
+	return e2.Encode(nonNil(n.Data))
 }
+
 func (n *MptNode) RLPDecodeSelf(d codec.Decoder) error {
-	var bl [][]byte
-	if err := d.Decode(&bl); err != nil {
+	var rl []rawItem
+	if err := d.Decode(&rl); err != nil {
 		return err
 	}
-	switch len(bl) {
+	switch len(rl) {
 	case 2:
-		n.Header = &MptHeader{bl[0][0], bl[0][1:]}
+		hb, err := rl[0].bytes()
+		if err != nil {
+			return err
+		}
+		if len(hb) == 0 {
+			return fmt.Errorf("empty header")
+		}
+		n.Header = &MptHeader{hb[0], hb[1:]}
 		if n.Header.IsLeaf() {
-			n.Data = bl[1]
+			if n.Data, err = rl[1].bytes(); err != nil {
+				return err
+			}
 		} else {
-			n.Link = make([]*MptLink, 1)
-			n.Link[0] = &MptLink{Hash: bl[1]}
+			ml, err := decodeLink(rl[1])
+			if err != nil {
+				return err
+			}
+			n.Link = []*MptLink{ml}
 		}
 	case 17:
 		n.Link = make([]*MptLink, 16)
-		for i, b := range bl[:16] {
-			n.Link[i] = &MptLink{Hash: b}
+		for i, r := range rl[:16] {
+			ml, err := decodeLink(r)
+			if err != nil {
+				return err
+			}
+			n.Link[i] = ml
 		}
-		n.Data = bl[16]
+		b, err := rl[16].bytes()
+		if err != nil {
+			return err
+		}
+		n.Data = b
 	default:
-		return fmt.Errorf("invalid list length %d", len(bl))
+		return fmt.Errorf("invalid list length %d", len(rl))
 	}
 	return nil
 }
 
+// Bytes returns RLP encoded bytes of the node.
+func (n *MptNode) Bytes() ([]byte, error) {
+	if n.serialized == nil {
+		b, err := codec.RLP.MarshalToBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		n.serialized = b
+	}
+	return n.serialized, nil
+}
+
 type MptProof struct {
 	Nodes  []MptNode
 	Hashes [][]byte
@@ -67,7 +245,12 @@ func (mp *MptProof) Leaf() *MptNode {
 	return &mp.Nodes[len(mp.Nodes)-1]
 }
 
+// NewMptProof parses the proof of ICON
 func NewMptProof(bl [][]byte) (*MptProof, error) {
+	return NewMptProofWithHash(ICON, bl)
+}
+
+func NewMptProofWithHash(hf HashFunc, bl [][]byte) (*MptProof, error) {
 	mp := &MptProof{
 		Nodes:  make([]MptNode, len(bl)),
 		Hashes: make([][]byte, len(bl)),
@@ -77,19 +260,20 @@ func NewMptProof(bl [][]byte) (*MptProof, error) {
 		if err != nil {
 			return nil, err
 		}
-		mp.Hashes[i] = crypto.SHA3Sum256(b)
+		mp.Hashes[i] = hf(b)
 	}
 Loop:
-	for i := 0; i < len(bl); i++ {
+	for i := 0; i < len(bl)-1; i++ {
 		mn := mp.Nodes[i]
 		if mn.Header != nil {
-			if !mn.Header.IsLeaf() {
-				ml := mn.Link[0]
-				if !bytes.Equal(ml.Hash, mp.Hashes[i+1]) {
-					return nil, fmt.Errorf("invalid link[%d] hash[%d]", i, i+1)
-				}
-				ml.ptr = &mp.Nodes[i+1]
+			if mn.Header.IsLeaf() {
+				return nil, fmt.Errorf("invalid leaf[%d], not last", i)
+			}
+			ml := mn.Link[0]
+			if !bytes.Equal(ml.Hash, mp.Hashes[i+1]) {
+				return nil, fmt.Errorf("invalid link[%d] hash[%d]", i, i+1)
 			}
+			ml.ptr = &mp.Nodes[i+1]
 		} else {
 			for _, ml := range mn.Link {
 				if len(ml.Hash) > 0 && bytes.Equal(ml.Hash, mp.Hashes[i+1]) {
@@ -0,0 +1,131 @@
+package mpt
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+)
+
+func TestMptHeader_Path(t *testing.T) {
+	cases := [][]byte{
+		{},
+		{1},
+		{1, 2},
+		{0, 15, 1},
+		{15, 1, 12, 11, 8, 10},
+	}
+	for _, path := range cases {
+		for _, leaf := range []bool{true, false} {
+			h := NewMptHeader(path, leaf)
+			assert.Equal(t, leaf, h.IsLeaf())
+			assert.Equal(t, path, h.Path())
+		}
+	}
+}
+
+func TestTrie_Ethereum(t *testing.T) {
+	tr := NewTrie(Ethereum, nil, nil)
+	data := map[string]string{
+		"doe":          "reindeer",
+		"dog":          "puppy",
+		"dogglesworth": "cat",
+	}
+	for k, v := range data {
+		assert.NoError(t, tr.Set([]byte(k), []byte(v)))
+	}
+	root := tr.Hash()
+	assert.Equal(t, "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3",
+		hex.EncodeToString(root))
+
+	for k, v := range data {
+		proof, err := tr.Prove([]byte(k))
+		assert.NoError(t, err)
+		value, err := Verify(Ethereum, root, []byte(k), proof)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(v), value)
+	}
+
+	proof, err := tr.Prove([]byte("do"))
+	assert.NoError(t, err)
+	_, err = Verify(Ethereum, root, []byte("do"), proof)
+	assert.True(t, errors.NotFoundError.Equals(err))
+}
+
+func TestTrie_ProveAndVerify(t *testing.T) {
+	mdb := db.NewMapDB()
+	bk, _ := mdb.GetBucket("")
+
+	for _, hf := range []HashFunc{ICON, Ethereum} {
+		tr := NewTrie(hf, bk, nil)
+		for i := 0; i < 100; i++ {
+			k, _ := codec.RLP.MarshalToBytes(i)
+			assert.NoError(t, tr.Set(k, []byte(fmt.Sprintf("value%d", i))))
+		}
+		assert.NoError(t, tr.Flush())
+		root := tr.Hash()
+
+		for i := 0; i < 100; i++ {
+			k, _ := codec.RLP.MarshalToBytes(i)
+			proof, err := Prove(hf, bk, root, k)
+			assert.NoError(t, err)
+
+			value, err := Verify(hf, root, k, proof)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(fmt.Sprintf("value%d", i)), value)
+
+			// round trip of node encoding
+			for _, b := range proof {
+				var n MptNode
+				_, err = codec.RLP.UnmarshalFromBytes(b, &n)
+				assert.NoError(t, err)
+				b2, err := codec.RLP.MarshalToBytes(&n)
+				assert.NoError(t, err)
+				assert.Equal(t, b, b2)
+			}
+
+			// tampered proof
+			tampered := make([][]byte, len(proof))
+			copy(tampered, proof)
+			last := append([]byte{}, proof[len(proof)-1]...)
+			last[len(last)-1] ^= 0x01
+			tampered[len(tampered)-1] = last
+			_, err = Verify(hf, root, k, tampered)
+			assert.True(t, errors.IllegalArgumentError.Equals(err))
+
+			// insufficient proof
+			_, err = Verify(hf, root, k, proof[:len(proof)-1])
+			assert.True(t, errors.IllegalArgumentError.Equals(err))
+		}
+
+		// proof of absence
+		k, _ := codec.RLP.MarshalToBytes(1000)
+		proof, err := Prove(hf, bk, root, k)
+		assert.NoError(t, err)
+		_, err = Verify(hf, root, k, proof)
+		assert.True(t, errors.NotFoundError.Equals(err))
+		_, err = Verify(hf, root, k, nil)
+		assert.True(t, errors.IllegalArgumentError.Equals(err))
+	}
+}
+
+func TestNewMptProof(t *testing.T) {
+	tr := NewTrie(ICON, nil, nil)
+	for i := 0; i < 20; i++ {
+		k, _ := codec.RLP.MarshalToBytes(i)
+		v := []byte(fmt.Sprintf("long enough value to be referred by hash %d", i))
+		assert.NoError(t, tr.Set(k, v))
+	}
+	k, _ := codec.RLP.MarshalToBytes(7)
+	proof, err := tr.Prove(k)
+	assert.NoError(t, err)
+
+	mp, err := NewMptProof(proof)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("long enough value to be referred by hash 7"), mp.Leaf().Data)
+}
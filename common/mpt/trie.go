@@ -0,0 +1,364 @@
+package mpt
+
+import (
+	"bytes"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/db"
+	"github.com/icon-project/btp2/common/errors"
+)
+
+// Trie is a minimal Merkle Patricia Trie for building and proving
+// key/value lists. Nodes are kept in memory, and Flush stores the
+// hashed nodes to the bucket keyed by their hashes.
+type Trie struct {
+	hashFunc HashFunc
+	bucket   db.Bucket
+	root     *MptLink
+}
+
+func keyToPath(k []byte) []byte {
+	path := make([]byte, len(k)*2)
+	for i, b := range k {
+		path[i*2] = b >> 4
+		path[i*2+1] = b & 0x0f
+	}
+	return path
+}
+
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func (t *Trie) resolve(ml *MptLink) (*MptNode, error) {
+	if ml.IsEmpty() {
+		return nil, nil
+	}
+	if ml.ptr != nil {
+		return ml.ptr, nil
+	}
+	if t.bucket == nil {
+		return nil, errors.NotFoundError.Errorf("node not found hash:%x", ml.Hash)
+	}
+	b, err := db.DoGet(t.bucket, ml.Hash)
+	if err != nil {
+		return nil, err
+	}
+	n := &MptNode{}
+	if _, err = codec.RLP.UnmarshalFromBytes(b, n); err != nil {
+		return nil, err
+	}
+	n.serialized = b
+	ml.ptr = n
+	return n, nil
+}
+
+// link returns the reference of the node, it embeds the node if the
+// encoding is shorter than its hash.
+func (t *Trie) link(n *MptNode) (*MptLink, error) {
+	b, err := n.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 32 {
+		return &MptLink{ptr: n}, nil
+	}
+	return &MptLink{Hash: t.hashFunc(b), ptr: n}, nil
+}
+
+func newLeaf(path, value []byte) *MptNode {
+	return &MptNode{Header: NewMptHeader(path, true), Data: value}
+}
+
+func (t *Trie) newExtension(path []byte, child *MptNode) (*MptNode, error) {
+	if len(path) == 0 {
+		return child, nil
+	}
+	ml, err := t.link(child)
+	if err != nil {
+		return nil, err
+	}
+	return &MptNode{Header: NewMptHeader(path, false), Link: []*MptLink{ml}}, nil
+}
+
+func (t *Trie) set(n *MptNode, path, value []byte) (*MptNode, error) {
+	if n == nil {
+		return newLeaf(path, value), nil
+	}
+	if n.Header == nil {
+		nn := &MptNode{Link: make([]*MptLink, 16), Data: n.Data}
+		copy(nn.Link, n.Link)
+		if len(path) == 0 {
+			nn.Data = value
+			return nn, nil
+		}
+		child, err := t.resolve(n.Link[path[0]])
+		if err != nil {
+			return nil, err
+		}
+		if child, err = t.set(child, path[1:], value); err != nil {
+			return nil, err
+		}
+		if nn.Link[path[0]], err = t.link(child); err != nil {
+			return nil, err
+		}
+		return nn, nil
+	}
+
+	hp := n.Header.Path()
+	c := commonPrefix(hp, path)
+	if n.Header.IsLeaf() && c == len(hp) && c == len(path) {
+		return newLeaf(path, value), nil
+	}
+	if !n.Header.IsLeaf() && c == len(hp) {
+		child, err := t.resolve(n.Link[0])
+		if err != nil {
+			return nil, err
+		}
+		if child, err = t.set(child, path[c:], value); err != nil {
+			return nil, err
+		}
+		return t.newExtension(hp, child)
+	}
+
+	br := &MptNode{Link: make([]*MptLink, 16)}
+	if n.Header.IsLeaf() {
+		if c == len(hp) {
+			br.Data = n.Data
+		} else {
+			ml, err := t.link(newLeaf(hp[c+1:], n.Data))
+			if err != nil {
+				return nil, err
+			}
+			br.Link[hp[c]] = ml
+		}
+	} else {
+		child, err := t.resolve(n.Link[0])
+		if err != nil {
+			return nil, err
+		}
+		if child, err = t.newExtension(hp[c+1:], child); err != nil {
+			return nil, err
+		}
+		if br.Link[hp[c]], err = t.link(child); err != nil {
+			return nil, err
+		}
+	}
+	if c == len(path) {
+		br.Data = value
+	} else {
+		ml, err := t.link(newLeaf(path[c+1:], value))
+		if err != nil {
+			return nil, err
+		}
+		br.Link[path[c]] = ml
+	}
+	return t.newExtension(path[:c], br)
+}
+
+// Set stores the value for the key. Deletion is not supported, so the
+// value must not be empty.
+func (t *Trie) Set(k, v []byte) error {
+	if len(v) == 0 {
+		return errors.IllegalArgumentError.New("empty value")
+	}
+	n, err := t.resolve(t.root)
+	if err != nil {
+		return err
+	}
+	if n, err = t.set(n, keyToPath(k), v); err != nil {
+		return err
+	}
+	b, err := n.Bytes()
+	if err != nil {
+		return err
+	}
+	t.root = &MptLink{Hash: t.hashFunc(b), ptr: n}
+	return nil
+}
+
+// walk follows the path from the root, and calls onNode for every
+// node referred by hash.
+func (t *Trie) walk(k []byte, onNode func(b []byte)) ([]byte, error) {
+	path := keyToPath(k)
+	ml := t.root
+	for {
+		n, err := t.resolve(ml)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, errors.NotFoundError.Errorf("not found key:%x", k)
+		}
+		if len(ml.Hash) > 0 && onNode != nil {
+			b, err := n.Bytes()
+			if err != nil {
+				return nil, err
+			}
+			onNode(b)
+		}
+		if n.Header == nil {
+			if len(path) == 0 {
+				if len(n.Data) == 0 {
+					return nil, errors.NotFoundError.Errorf("not found key:%x", k)
+				}
+				return n.Data, nil
+			}
+			ml = n.Link[path[0]]
+			path = path[1:]
+			continue
+		}
+		hp := n.Header.Path()
+		if n.Header.IsLeaf() {
+			if !bytes.Equal(hp, path) {
+				return nil, errors.NotFoundError.Errorf("not found key:%x", k)
+			}
+			return n.Data, nil
+		}
+		if !bytes.HasPrefix(path, hp) {
+			return nil, errors.NotFoundError.Errorf("not found key:%x", k)
+		}
+		ml = n.Link[0]
+		path = path[len(hp):]
+	}
+}
+
+func (t *Trie) Get(k []byte) ([]byte, error) {
+	return t.walk(k, nil)
+}
+
+// Hash returns the root hash, nil for the empty trie.
+func (t *Trie) Hash() []byte {
+	if t.root.IsEmpty() {
+		return nil
+	}
+	return t.root.Hash
+}
+
+// Prove returns encoded nodes from the root to the node holding the key.
+// Embedded nodes are not included since they are a part of their parent.
+// For the absent key, it returns the proof of absence.
+func (t *Trie) Prove(k []byte) ([][]byte, error) {
+	var proof [][]byte
+	if _, err := t.walk(k, func(b []byte) {
+		proof = append(proof, b)
+	}); err != nil && !errors.NotFoundError.Equals(err) {
+		return nil, err
+	}
+	return proof, nil
+}
+
+func (t *Trie) flush(ml *MptLink) error {
+	n := ml.ptr
+	if n == nil {
+		return nil
+	}
+	for _, cl := range n.Link {
+		if !cl.IsEmpty() {
+			if err := t.flush(cl); err != nil {
+				return err
+			}
+		}
+	}
+	if len(ml.Hash) > 0 {
+		b, err := n.Bytes()
+		if err != nil {
+			return err
+		}
+		return t.bucket.Set(ml.Hash, b)
+	}
+	return nil
+}
+
+// Flush stores the nodes referred by hash to the bucket.
+func (t *Trie) Flush() error {
+	if t.bucket == nil {
+		return errors.InvalidStateError.New("no bucket")
+	}
+	if t.root.IsEmpty() {
+		return nil
+	}
+	return t.flush(t.root)
+}
+
+// NewTrie returns the trie of the root. Nodes of the root are read from
+// the bucket on demand, and bk could be nil for a new trie kept in memory.
+func NewTrie(hf HashFunc, bk db.Bucket, root []byte) *Trie {
+	t := &Trie{
+		hashFunc: hf,
+		bucket:   bk,
+	}
+	if len(root) > 0 {
+		t.root = &MptLink{Hash: root}
+	}
+	return t
+}
+
+// Prove returns the proof of the key from the nodes stored in the bucket.
+func Prove(hf HashFunc, bk db.Bucket, root, key []byte) ([][]byte, error) {
+	return NewTrie(hf, bk, root).Prove(key)
+}
+
+// Verify checks the proof of the key against the root, and returns the value.
+// It returns NotFoundError if the proof shows that the key is absent, and
+// IllegalArgumentError if the proof is invalid.
+func Verify(hf HashFunc, root, key []byte, proof [][]byte) ([]byte, error) {
+	path := keyToPath(key)
+	expected := root
+	for i, b := range proof {
+		if !bytes.Equal(hf(b), expected) {
+			return nil, errors.IllegalArgumentError.Errorf("invalid proof[%d] hash mismatch", i)
+		}
+		n := &MptNode{}
+		if _, err := codec.RLP.UnmarshalFromBytes(b, n); err != nil {
+			return nil, errors.IllegalArgumentError.Wrapf(err, "invalid proof[%d]", i)
+		}
+		for {
+			var ml *MptLink
+			if n.Header == nil {
+				if len(path) == 0 {
+					return proofResult(i, len(proof), n.Data, key)
+				}
+				ml = n.Link[path[0]]
+				path = path[1:]
+			} else {
+				hp := n.Header.Path()
+				if n.Header.IsLeaf() {
+					if !bytes.Equal(hp, path) {
+						return proofResult(i, len(proof), nil, key)
+					}
+					return proofResult(i, len(proof), n.Data, key)
+				}
+				if !bytes.HasPrefix(path, hp) {
+					return proofResult(i, len(proof), nil, key)
+				}
+				ml = n.Link[0]
+				path = path[len(hp):]
+			}
+			if ml.IsEmpty() {
+				return proofResult(i, len(proof), nil, key)
+			}
+			if len(ml.Hash) == 0 {
+				n = ml.ptr
+				continue
+			}
+			expected = ml.Hash
+			break
+		}
+	}
+	return nil, errors.IllegalArgumentError.Errorf("insufficient proof length %d", len(proof))
+}
+
+func proofResult(idx, size int, value, key []byte) ([]byte, error) {
+	if idx != size-1 {
+		return nil, errors.IllegalArgumentError.Errorf("unused proof from %d", idx+1)
+	}
+	if len(value) == 0 {
+		return nil, errors.NotFoundError.Errorf("not found key:%x", key)
+	}
+	return value, nil
+}
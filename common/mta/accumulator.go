@@ -6,9 +6,9 @@ import (
 	"fmt"
 
 	"github.com/icon-project/btp2/common"
-	"github.com/icon-project/btp2/common/crypto"
 	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/ntm"
 )
 
 const (
@@ -134,7 +134,7 @@ func (n *branchNode) Hash() []byte {
 		copy(bs, n.left.Hash())
 		copy(bs[HashSize:], n.right.Hash())
 		n.serialized = bs
-		n.hashValue = crypto.SHA3Sum256(bs)
+		n.hashValue = ntm.Sha3FIPS256(bs)
 		n.state = stateHashed
 	}
 	return n.hashValue
@@ -204,7 +204,7 @@ func (n *dataNode) Hash() []byte {
 		return nil
 	}
 	if n.state < stateHashed {
-		n.hashValue = crypto.SHA3Sum256(n.data)
+		n.hashValue = ntm.Sha3FIPS256(n.data)
 		n.state = stateHashed
 	}
 	return n.hashValue
@@ -379,19 +379,8 @@ func (a *Accumulator) WitnessForWithAccLength(idx, accLength int64) (w []Witness
 }
 
 func (a *Accumulator) Verify(ws []Witness, h []byte) error {
-	buf := make([]byte, HashSize*2)
-	height := 0
-	for _, w := range ws {
-		if w.Direction == Left {
-			copy(buf, w.HashValue)
-			copy(buf[HashSize:], h)
-		} else {
-			copy(buf, h)
-			copy(buf[HashSize:], w.HashValue)
-		}
-		h = crypto.SHA3Sum256(buf)
-		height += 1
-	}
+	h = rootOfWitness(ws, h)
+	height := len(ws)
 	if height >= len(a.roots) {
 		return errors.Wrap(errors.ErrIllegalArgument, "InvalidWitness newer witness")
 	}
@@ -1,8 +1,6 @@
 package mta
 
 import (
-	"fmt"
-
 	"github.com/icon-project/btp2/common/codec"
 	"github.com/icon-project/btp2/common/db"
 	"github.com/icon-project/btp2/common/errors"
@@ -152,7 +150,6 @@ func (a *ExtAccumulator) WitnessForAt(height, at, offset int64) (int64, []Witnes
 	}
 
 	w, err := a.Accumulator.WitnessForWithAccLength(idx, accLength)
-	fmt.Printf("at:%d, idx:%d, accLength:%d, offset:%d\n", at, idx, accLength, offset)
 	return at, w, err
 }
 
@@ -184,3 +181,25 @@ func NewExtAccumulator(keyForState []byte, bk db.Bucket, offset int64) *ExtAccum
 		},
 	}
 }
+
+// RecoverExtAccumulator returns the accumulator recovered with the offset of
+// the state in the bucket, or the empty one with the offset if there is no
+// state.
+func RecoverExtAccumulator(keyForState []byte, bk db.Bucket, offset int64) (*ExtAccumulator, error) {
+	b, err := bk.Get(keyForState)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 {
+		var s serializedExtAccumulator
+		if _, err = codec.RLP.UnmarshalFromBytes(b, &s); err != nil {
+			return nil, err
+		}
+		offset = s.Offset
+	}
+	a := NewExtAccumulator(keyForState, bk, offset)
+	if err = a.Recover(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
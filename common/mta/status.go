@@ -0,0 +1,94 @@
+package mta
+
+import (
+	"bytes"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/ntm"
+)
+
+// Status is the state of the accumulator as carried in the status of BMV,
+// which is enough to verify witnesses without the database. Roots[i] is
+// the root of the tree with 2^i leaves, nil if there is no such tree.
+type Status struct {
+	Height int64
+	Roots  [][]byte
+	Offset int64
+}
+
+func (a *ExtAccumulator) Status() *Status {
+	roots := make([][]byte, len(a.roots))
+	for i, rn := range a.roots {
+		if rn != nil {
+			roots[i] = rn.Hash()
+		}
+	}
+	return &Status{
+		Height: a.Height(),
+		Roots:  roots,
+		Offset: a.offset,
+	}
+}
+
+// rootOfWitness returns the root from the hash of the leaf by the witness.
+func rootOfWitness(ws []Witness, h []byte) []byte {
+	buf := make([]byte, HashSize*2)
+	for _, w := range ws {
+		if w.Direction == Left {
+			copy(buf, w.HashValue)
+			copy(buf[HashSize:], h)
+		} else {
+			copy(buf, h)
+			copy(buf[HashSize:], w.HashValue)
+		}
+		h = ntm.Sha3FIPS256(buf)
+	}
+	return h
+}
+
+// VerifyWitness verifies the witness of the leaf hash against the root of
+// the tree whose depth is the length of the witness.
+func VerifyWitness(roots [][]byte, ws []Witness, h []byte) error {
+	if len(ws) >= len(roots) {
+		return errors.IllegalArgumentError.New("InvalidWitness newer witness")
+	}
+	root := roots[len(ws)]
+	if root == nil {
+		return errors.IllegalArgumentError.New("InvalidWitness not exists root")
+	}
+	if !bytes.Equal(root, rootOfWitness(ws, h)) {
+		return errors.IllegalArgumentError.New("InvalidWitness not matched root")
+	}
+	return nil
+}
+
+// VerifyAt verifies the witness of the leaf at the height, which is made
+// by the accumulator at the height at. The witness from the accumulator
+// newer than the status is truncated for the root of the status, while
+// the older one requires the accumulator.
+func (s *Status) VerifyAt(height, at int64, witness [][]byte, h []byte) error {
+	if height <= s.Offset || height > s.Height {
+		return errors.IllegalArgumentError.Errorf("InvalidHeight height=%d status=%d", height, s.Height)
+	}
+	if at < s.Height {
+		return errors.IllegalArgumentError.Errorf("InvalidWitness older witness at=%d status=%d", at, s.Height)
+	}
+	idx := height - 1 - s.Offset
+	length := s.Height - s.Offset
+	for depth := len(s.Roots) - 1; depth >= 0; depth-- {
+		size := int64(1) << uint(depth)
+		if length&size == 0 {
+			continue
+		}
+		if idx >= size {
+			idx -= size
+			continue
+		}
+		if len(witness) < depth {
+			return errors.IllegalArgumentError.Errorf("InvalidWitness short witness len=%d depth=%d",
+				len(witness), depth)
+		}
+		return VerifyWitness(s.Roots, HashesToWitness(witness[:depth], idx), h)
+	}
+	return errors.IllegalArgumentError.Errorf("InvalidStatus no root for height=%d", height)
+}
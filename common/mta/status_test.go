@@ -0,0 +1,54 @@
+package mta
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/crypto"
+	"github.com/icon-project/btp2/common/db"
+)
+
+func TestStatus_VerifyAt(t *testing.T) {
+	for _, offset := range []int64{0, 10} {
+		mdb := db.NewMapDB()
+		bk, _ := mdb.GetBucket("")
+		a := NewExtAccumulator([]byte("a"), bk, offset)
+		data := make([][]byte, 0)
+		statuses := make([]*Status, 0)
+		for i := 0; i < 37; i++ {
+			d := []byte(fmt.Sprintf("data%d", i))
+			data = append(data, d)
+			a.AddData(d)
+			assert.NoError(t, a.Flush())
+			statuses = append(statuses, a.Status())
+		}
+		for _, s := range statuses {
+			for height := offset + 1; height <= s.Height; height++ {
+				h := crypto.SHA3Sum256(data[height-1-offset])
+				// witness at the status and newer ones
+				for _, at := range []int64{s.Height, a.Height()} {
+					at, w, err := a.WitnessForAt(height, at, offset)
+					assert.NoError(t, err)
+					hs := WitnessesToHashes(w)
+					assert.NoError(t, s.VerifyAt(height, at, hs, h),
+						"status:%d height:%d at:%d", s.Height, height, at)
+				}
+				_, w, _ := a.WitnessForAt(height, s.Height, offset)
+				hs := WitnessesToHashes(w)
+				assert.Error(t, s.VerifyAt(height, s.Height, hs, crypto.SHA3Sum256([]byte("fake"))))
+				if height > offset+1 {
+					assert.Error(t, s.VerifyAt(height-1, s.Height, hs, h))
+				}
+			}
+			assert.Error(t, s.VerifyAt(s.Height+1, s.Height+1, nil, nil))
+			assert.Error(t, s.VerifyAt(offset, s.Height, nil, nil))
+		}
+
+		// older witness
+		_, w, _ := a.WitnessForAt(offset+1, offset+2, offset)
+		assert.Error(t, a.Status().VerifyAt(offset+1, offset+2, WitnessesToHashes(w),
+			crypto.SHA3Sum256(data[0])))
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ntm
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/icon-project/btp2/common/errors"
+)
+
+// HashFunc returns the digest of the concatenated data.
+type HashFunc func(l ...[]byte) []byte
+
+const (
+	HashSha3FIPS256   = "sha3-256"
+	HashSha3Keccak256 = "keccak-256"
+)
+
+func Sha3FIPS256(l ...[]byte) []byte {
+	h := sha3.New256()
+	for _, b := range l {
+		h.Write(b)
+	}
+	var digest [32]byte
+	h.Sum(digest[:0])
+	return digest[:]
+}
+
+func Sha3Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+	var digest [32]byte
+	h.Sum(digest[:0])
+	return digest[:]
+}
+
+var hashFuncs = map[string]HashFunc{
+	HashSha3FIPS256:   Sha3FIPS256,
+	HashSha3Keccak256: Sha3Keccak256,
+}
+
+// RegisterHashFunc adds the hash function, it panics on duplicated names.
+func RegisterHashFunc(name string, f HashFunc) {
+	if name == "" || f == nil {
+		panic(fmt.Sprintf("invalid hash function %s", name))
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if _, ok := hashFuncs[name]; ok {
+		panic(fmt.Sprintf("Duplicate hash function registration for %s", name))
+	}
+	hashFuncs[name] = f
+}
+
+// HashFuncByName returns the hash function registered with the name.
+func HashFuncByName(name string) (HashFunc, error) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	if f, ok := hashFuncs[name]; ok {
+		return f, nil
+	}
+	return nil, errors.NotFoundError.Errorf("UnknownHashFunc(name=%s)", name)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ntm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/errors"
+)
+
+const (
+	// ProofFormatSecp256k1 is the proof with secp256k1 signatures of
+	// validators, whose addresses are the last 20 bytes of the hash of
+	// the public key.
+	ProofFormatSecp256k1 = "secp256k1"
+)
+
+// NetworkType is how data of BTP networks of the type is hashed, proven
+// and encoded. Name is the network type name of BTPNetworkInfo.
+type NetworkType struct {
+	Name        string
+	HashFunc    HashFunc
+	ProofFormat string
+	HeaderCodec codec.Codec
+}
+
+var (
+	mtx          sync.RWMutex
+	networkTypes = map[string]*NetworkType{}
+)
+
+// Register adds the network type, it panics on duplicated names.
+func Register(nt *NetworkType) {
+	if nt.Name == "" || nt.HashFunc == nil || nt.HeaderCodec == nil {
+		panic(fmt.Sprintf("invalid network type %+v", nt))
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if _, ok := networkTypes[nt.Name]; ok {
+		panic(fmt.Sprintf("Duplicate network type registration for %s", nt.Name))
+	}
+	networkTypes[nt.Name] = nt
+}
+
+// ByName returns the network type registered with the name.
+func ByName(name string) (*NetworkType, error) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	if nt, ok := networkTypes[name]; ok {
+		return nt, nil
+	}
+	return nil, errors.NotFoundError.Errorf("UnknownNetworkType(name=%s)", name)
+}
+
+// Names returns the sorted names of registered network types.
+func Names() []string {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	names := make([]string, 0, len(networkTypes))
+	for name := range networkTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(&NetworkType{
+		Name:        "eth",
+		HashFunc:    Sha3Keccak256,
+		ProofFormat: ProofFormatSecp256k1,
+		HeaderCodec: codec.RLP,
+	})
+	Register(&NetworkType{
+		Name:        "icon",
+		HashFunc:    Sha3FIPS256,
+		ProofFormat: ProofFormatSecp256k1,
+		HeaderCodec: codec.RLP,
+	})
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2023 ICON Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ntm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/codec"
+	"github.com/icon-project/btp2/common/errors"
+)
+
+func TestByName(t *testing.T) {
+	nt, err := ByName("eth")
+	assert.NoError(t, err)
+	assert.Equal(t, Sha3Keccak256([]byte("a")), nt.HashFunc([]byte("a")))
+	assert.Equal(t, ProofFormatSecp256k1, nt.ProofFormat)
+
+	nt, err = ByName("icon")
+	assert.NoError(t, err)
+	assert.Equal(t, Sha3FIPS256([]byte("a")), nt.HashFunc([]byte("a")))
+
+	_, err = ByName("unknown")
+	assert.True(t, errors.NotFoundError.Equals(err))
+}
+
+func TestRegister(t *testing.T) {
+	nt := &NetworkType{
+		Name:        "test-register",
+		HashFunc:    Sha3FIPS256,
+		ProofFormat: "test",
+		HeaderCodec: codec.RLP,
+	}
+	Register(nt)
+	r, err := ByName(nt.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, nt, r)
+	assert.Contains(t, Names(), nt.Name)
+
+	assert.Panics(t, func() { Register(nt) })
+	assert.Panics(t, func() { Register(&NetworkType{Name: "test-invalid"}) })
+}
+
+func TestHashFuncByName(t *testing.T) {
+	f, err := HashFuncByName(HashSha3FIPS256)
+	assert.NoError(t, err)
+	assert.Equal(t, Sha3FIPS256([]byte("a")), f([]byte("a")))
+
+	f, err = HashFuncByName(HashSha3Keccak256)
+	assert.NoError(t, err)
+	assert.Equal(t, Sha3Keccak256([]byte("a"), []byte("b")), f([]byte("ab")))
+
+	_, err = HashFuncByName("unknown")
+	assert.True(t, errors.NotFoundError.Equals(err))
+
+	RegisterHashFunc("test-hash", Sha3FIPS256)
+	f, err = HashFuncByName("test-hash")
+	assert.NoError(t, err)
+	assert.Equal(t, Sha3FIPS256([]byte("a")), f([]byte("a")))
+	assert.Panics(t, func() { RegisterHashFunc("test-hash", Sha3FIPS256) })
+	assert.Panics(t, func() { RegisterHashFunc("test-invalid", nil) })
+}
@@ -7,12 +7,13 @@ import (
 )
 
 type RelayConfig struct {
-	Direction         string               `json:"direction"`
-	config.FileConfig `json:",squash"`     //instead of `mapstructure:",squash"`
-	LogLevel          string               `json:"log_level"`
-	ConsoleLevel      string               `json:"console_level"`
-	LogForwarder      *log.ForwarderConfig `json:"log_forwarder,omitempty"`
-	LogWriter         *log.WriterConfig    `json:"log_writer,omitempty"`
+	Direction         string                  `json:"direction"`
+	config.FileConfig `json:",squash"`        //instead of `mapstructure:",squash"`
+	LogLevel          string                  `json:"log_level"`
+	ConsoleLevel      string                  `json:"console_level"`
+	LogForwarder      *log.ForwarderConfig    `json:"log_forwarder,omitempty"`
+	LogWriter         *log.WriterConfig       `json:"log_writer,omitempty"`
+	ErrorPolicy       *link.ErrorPolicyConfig `json:"error_policy,omitempty"`
 }
 
 type Config struct {
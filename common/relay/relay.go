@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	stdlog "log"
+	"time"
 
 	"github.com/icon-project/btp2/common/link"
 	"github.com/icon-project/btp2/common/log"
@@ -16,9 +17,19 @@ const (
 	ReverseDirection = "reverse"
 )
 
+const (
+	StatusInterval = time.Minute
+)
+
+// statusReporter is implemented by links reporting the status.
+type statusReporter interface {
+	Status() *link.Status
+}
+
 type linkFactory struct {
 	link   types.Link
 	sender types.Sender
+	l      log.Logger
 }
 type Relay struct {
 	lfs []*linkFactory
@@ -70,7 +81,7 @@ func newLinkFactory(srcRaw, dstRaw json.RawMessage, relayCfg RelayConfig, modLev
 		return nil, err
 	}
 
-	l, err := link.CreateLink(srcRaw, dstRaw, relayCfg.BaseDir, logger)
+	l, err := link.CreateLink(srcRaw, dstRaw, relayCfg.BaseDir, relayCfg.ErrorPolicy, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +91,7 @@ func newLinkFactory(srcRaw, dstRaw json.RawMessage, relayCfg RelayConfig, modLev
 		return nil, err
 	}
 
-	return &linkFactory{link: l, sender: s}, nil
+	return &linkFactory{link: l, sender: s, l: logger}, nil
 }
 
 func (r *Relay) Start() error {
@@ -91,6 +102,8 @@ func (r *Relay) Start() error {
 		}
 	}
 
+	ticker := time.NewTicker(StatusInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case err := <-linkErrCh:
@@ -98,6 +111,26 @@ func (r *Relay) Start() error {
 				log.GlobalLogger().Debugln("Relay error :", err)
 				return err
 			}
+		case <-ticker.C:
+			r.logStatus()
+		}
+	}
+}
+
+// logStatus logs statuses of links, links suspended by the error policy
+// are warned.
+func (r *Relay) logStatus() {
+	for _, lf := range r.lfs {
+		sr, ok := lf.link.(statusReporter)
+		if !ok {
+			continue
+		}
+		s := sr.Status()
+		switch s.State {
+		case link.PAUSED, link.STOPPED:
+			lf.l.Warnf("link status %s", s)
+		default:
+			lf.l.Infof("link status %s", s)
 		}
 	}
 }
@@ -0,0 +1,84 @@
+package relay
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+// testLink reports the state, and sends errors to the relay by fail.
+type testLink struct {
+	mtx   sync.Mutex
+	state link.RelayState
+	errCh chan error
+}
+
+func (l *testLink) Start(sender types.Sender, errCh chan error) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.errCh = errCh
+	l.state = link.RUNNING
+	return nil
+}
+
+func (l *testLink) Stop() {}
+
+func (l *testLink) Status() *link.Status {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return &link.Status{State: l.state}
+}
+
+// stop changes the state like the link stopped by the error policy, which
+// doesn't send the error.
+func (l *testLink) stop() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.state = link.STOPPED
+}
+
+func (l *testLink) fail(err error) {
+	l.mtx.Lock()
+	errCh := l.errCh
+	l.mtx.Unlock()
+	errCh <- err
+}
+
+func TestRelay_StoppedLink(t *testing.T) {
+	stopped, running := &testLink{}, &testLink{}
+	r := &Relay{lfs: []*linkFactory{
+		{link: stopped, l: log.New()},
+		{link: running, l: log.New()},
+	}}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.Start()
+	}()
+	assert.Eventually(t, func() bool {
+		return running.Status().State == link.RUNNING
+	}, time.Second, time.Millisecond)
+
+	stopped.stop()
+	r.logStatus()
+	select {
+	case err := <-errc:
+		assert.FailNow(t, "relay is stopped by the stopped link", "err:%+v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// errors of other links still stop the relay
+	running.fail(errors.InvalidStateError.New("failure"))
+	select {
+	case err := <-errc:
+		assert.True(t, errors.InvalidStateError.Equals(err), "err:%+v", err)
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "relay is not stopped by the error")
+	}
+}